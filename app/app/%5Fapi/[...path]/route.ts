@@ -0,0 +1,47 @@
+import { NextRequest } from "next/server";
+
+// The frontend reaches the backend only through this route, so the API token never leaves the server
+// The folder is named %5Fapi because Next.js doesn't route folders starting with an underscore
+const API_URL = process.env.API_URL ?? "http://localhost:8080";
+const API_TOKEN = process.env.API_TOKEN;
+
+// fetch already decoded the body, these headers would describe the encoded one
+const DROPPED_HEADERS = ["content-encoding", "content-length", "transfer-encoding"];
+
+async function proxy(
+  request: NextRequest,
+  { params }: { params: Promise<{ path: string[] }> },
+): Promise<Response> {
+  const { path } = await params;
+  const url = `${API_URL}/${path.map(encodeURIComponent).join("/")}${request.nextUrl.search}`;
+
+  const headers = new Headers();
+  const contentType = request.headers.get("content-type");
+  if (contentType) headers.set("Content-Type", contentType);
+  if (API_TOKEN) headers.set("Authorization", `Bearer ${API_TOKEN}`);
+
+  const hasBody = request.method !== "GET" && request.method !== "HEAD";
+  const response = await fetch(url, {
+    method: request.method,
+    headers,
+    body: hasBody ? await request.arrayBuffer() : undefined,
+    cache: "no-store",
+    signal: request.signal,
+  });
+
+  const responseHeaders = new Headers(response.headers);
+  DROPPED_HEADERS.forEach((name) => responseHeaders.delete(name));
+  // Run events are streamed, the body is passed through as it arrives
+  return new Response(response.body, {
+    status: response.status,
+    headers: responseHeaders,
+  });
+}
+
+export {
+  proxy as GET,
+  proxy as POST,
+  proxy as PUT,
+  proxy as PATCH,
+  proxy as DELETE,
+};
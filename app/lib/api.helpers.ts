@@ -1,5 +1,6 @@
 import { ErrorReturn } from "./api";
 
+// Requests go through the API route of the app, it adds the API token on the server
 export const API_BASE = "/_api";
 
 export async function throwServerError(response: Response): Promise<void> {
@@ -44,11 +45,11 @@ export async function fetchPost<TArgs, TReturn>(
 ): Promise<TReturn> {
   const response = await fetch(`${API_BASE}/${path}`, {
     method: "POST",
+    ...options,
     headers: {
       "Content-Type": "application/json",
       ...options?.headers,
     },
-    ...options,
     body: JSON.stringify(args),
   });
   if (!response.ok) await throwServerError(response);
@@ -45,11 +45,16 @@ export type ConfigData = {
   productSpecification: string;
 };
 
-export type ConfigReturn = ConfigData;
+// Secrets are returned masked, the set flags tell whether they are configured
+export type ConfigReturn = ConfigData & {
+  anthropicApiKeySet: boolean;
+  sentryApiKeySet: boolean;
+  umamiAPIKeySet: boolean;
+};
 
 export type ConfigUpdateReturn = {
   success: boolean;
-  config: ConfigData;
+  config: ConfigReturn;
 };
 
 export type AnalyzeArgs = {
@@ -1,28 +1,56 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-	"time"
+	"os"
 
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/httprate"
 	"github.com/webscopeio/ai-hackathon/internal/config"
 	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/router"
+	"github.com/webscopeio/ai-hackathon/internal/store"
+	"github.com/webscopeio/ai-hackathon/internal/telemetry"
 )
 
 func main() {
-	cfg := config.Load()
+	provider := config.NewProvider(config.Load)
+	cfg := provider.Get()
+	logger.Configure(cfg.LogLevel, cfg.LogFormat)
+	log := logger.For(context.Background(), "api")
+
+	// Tracing stays off unless OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdown, err := telemetry.Setup(context.Background(), cfg.OTLPEndpoint)
+	if err != nil {
+		log.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	defer shutdown(context.Background())
 
 	r := router.New()
-	r.Use(middleware.Logger)
-	r.Use(httprate.LimitByIP(100, time.Minute))
+	r.Use(middleware.RequestID)
+	r.Use(logger.Middleware)
+	r.Use(telemetry.Middleware)
+
+	repo, err := store.New(cfg)
+	if err != nil {
+		log.Error("failed to open store", "error", err)
+		os.Exit(1)
+	}
 
 	llm := llm.New(cfg)
-	router.RegisterRoutes(r, cfg, llm)
+	// Keys saved through the API replace the key of the running client
+	provider.OnChange(func(cfg *config.Config) {
+		llm.UpdateAPIKey(cfg.APIKey)
+	})
+	router.RegisterRoutes(r, provider, llm, repo)
 
 	addr := fmt.Sprintf(":%s", cfg.Port)
-	fmt.Printf("Server starting on localhost%s in %s mode\n", addr, cfg.Environment)
-	http.ListenAndServe(addr, r)
+	log.Info("server starting", "addr", "localhost"+addr, "environment", cfg.Environment)
+	if err := http.ListenAndServe(addr, r); err != nil {
+		log.Error("server stopped", "error", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/store"
+)
+
+// runRecorder records a run in the store
+// Store failures only print a warning, they never stop the generation
+type runRecorder struct {
+	repo store.Repository
+	run  *models.Run
+}
+
+// startRun records the start of a run, the log records of the command carry the run ID from then on
+func startRun(cmd *cobra.Command, cfg *config.Config, siteURL, kind string) *runRecorder {
+	repo, err := store.New(cfg)
+	if err != nil {
+		fmt.Printf("[HISTORY] Not recording the run: %v\n", err)
+		return nil
+	}
+
+	run, err := store.StartRun(cmd.Context(), repo, cfg.Project, siteURL, kind)
+	if err != nil {
+		fmt.Printf("[HISTORY] Not recording the run: %v\n", err)
+		return nil
+	}
+	cmd.SetContext(logger.WithCorrelation(cmd.Context(), logger.RunIDKey, run.ID))
+
+	return &runRecorder{repo: repo, run: run}
+}
+
+// analysis records the tech spec and analyzed pages and stores the criteria
+func (r *runRecorder) analysis(ctx context.Context, techSpec string, pages []string, criteria []models.TestCriterion) []models.RunCriterion {
+	if r == nil {
+		return nil
+	}
+
+	r.run.TechSpec = techSpec
+	r.run.Pages = pages
+
+	if err := r.repo.SaveRun(ctx, r.run); err != nil {
+		fmt.Printf("[HISTORY] Couldn't record the analysis: %v\n", err)
+	}
+
+	stored, err := store.RecordCriteria(ctx, r.repo, r.run, criteria)
+	if err != nil {
+		fmt.Printf("[HISTORY] Couldn't record the criteria: %v\n", err)
+		return nil
+	}
+	return stored
+}
+
+// genEval stores the test versions and results of a gen-eval loop
+func (r *runRecorder) genEval(ctx context.Context, criteria []models.RunCriterion, index int, file string, result *models.GenEvalResult) {
+	if r == nil {
+		return
+	}
+
+	if err := store.RecordGenEval(ctx, r.repo, r.run, criterionID(criteria, index), file, result); err != nil {
+		fmt.Printf("[HISTORY] Couldn't record the generated test: %v\n", err)
+	}
+}
+
+// criterionID returns the ID of a stored criterion, or an empty string if the criteria weren't stored
+func criterionID(criteria []models.RunCriterion, index int) string {
+	if index < len(criteria) {
+		return criteria[index].ID
+	}
+	return ""
+}
+
+func (r *runRecorder) finish(ctx context.Context, runErr error) {
+	if r == nil {
+		return
+	}
+
+	if err := store.FinishRun(ctx, r.repo, r.run, runErr); err != nil {
+		fmt.Printf("[HISTORY] Couldn't record the end of the run: %v\n", err)
+		return
+	}
+	fmt.Printf("\n[HISTORY] Recorded run %s, see `testbuddy history %s`\n", r.run.ID, r.run.ID)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history [runId]",
+	Short: "List previous runs, or show the criteria and test results of a run",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := store.New(loadConfig())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		if len(args) == 1 {
+			details, err := store.Details(cmd.Context(), repo, args[0])
+			if err != nil {
+				fmt.Printf("Error: run %s: %v\n", args[0], err)
+				return
+			}
+			printRunDetails(details)
+			return
+		}
+
+		runs, err := repo.ListRuns(cmd.Context(), historyProject)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if len(runs) == 0 {
+			fmt.Println("No runs recorded yet")
+			return
+		}
+
+		for _, run := range runs {
+			fmt.Printf("%s  %s  %-12s %-8s %-12s %s\n", run.ID, run.StartedAt.Format("2006-01-02 15:04"), run.Kind, run.Status, run.Project, run.URL)
+		}
+	},
+}
+
+var historyCompareCmd = &cobra.Command{
+	Use:   "compare <baseRunId> <headRunId>",
+	Short: "Compare the criteria and test results of two runs",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := store.New(loadConfig())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		base, err := store.Details(cmd.Context(), repo, args[0])
+		if err != nil {
+			fmt.Printf("Error: run %s: %v\n", args[0], err)
+			return
+		}
+		head, err := store.Details(cmd.Context(), repo, args[1])
+		if err != nil {
+			fmt.Printf("Error: run %s: %v\n", args[1], err)
+			return
+		}
+
+		comparison := store.Compare(base, head)
+		printTitles("Added criteria", comparison.AddedCriteria)
+		printTitles("Removed criteria", comparison.RemovedCriteria)
+		printTitles("Fixed", comparison.Fixed)
+		printTitles("Broken", comparison.Broken)
+		printTitles("Unchanged", comparison.Unchanged)
+	},
+}
+
+// pageList returns the sorted pages of a content map
+func pageList(contents map[string]string) []string {
+	pages := make([]string, 0, len(contents))
+	for page := range contents {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+func printRunDetails(details *models.RunDetails) {
+	run := details.Run
+	fmt.Printf("Run %s (%s, %s) started %s\n", run.ID, run.Kind, run.Status, run.StartedAt.Format("2006-01-02 15:04"))
+	if run.Error != "" {
+		fmt.Printf("Error: %s\n", run.Error)
+	}
+	if len(run.Pages) > 0 {
+		fmt.Printf("Analyzed pages: %s\n", strings.Join(run.Pages, ", "))
+	}
+
+	results := map[string]models.TestResult{}
+	for _, result := range details.Results {
+		results[result.TestVersionID] = result
+	}
+
+	for _, criterion := range details.Criteria {
+		fmt.Printf("\n#%d %s\n", criterion.Index+1, criterion.Criterion.Title)
+		for _, version := range details.Tests {
+			if version.CriterionID != criterion.ID {
+				continue
+			}
+			result := results[version.ID]
+			status := "failed"
+			if result.Passed {
+				status = "passed"
+			}
+			accepted := ""
+			if result.Accepted {
+				accepted = ", accepted"
+			}
+			fmt.Printf("  v%d %s: %s%s in %dms\n", version.Version, version.File, status, accepted, result.DurationMs)
+		}
+	}
+}
+
+func printTitles(heading string, titles []string) {
+	fmt.Printf("%s (%d)\n", heading, len(titles))
+	for _, title := range titles {
+		fmt.Printf("  %s\n", title)
+	}
+}
@@ -1,38 +1,87 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/webscopeio/ai-hackathon/internal/analytics"
 	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/events"
 	"github.com/webscopeio/ai-hackathon/internal/llm"
 	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
 	"github.com/webscopeio/ai-hackathon/internal/repository/analyzer"
+	"github.com/webscopeio/ai-hackathon/internal/repository/coverage"
 	"github.com/webscopeio/ai-hackathon/internal/repository/gen_eval_loop"
+	"github.com/webscopeio/ai-hackathon/internal/repository/reproducer"
+	"github.com/webscopeio/ai-hackathon/internal/repository/session_replay"
+	"github.com/webscopeio/ai-hackathon/internal/repository/snapshot"
+	"github.com/webscopeio/ai-hackathon/internal/runner"
 )
 
+const generatedDir = "./__generated__"
+
 var url string
+var snapshotPath string
+var sentryOrg string
+var sentryProject string
+var gapsPath string
+var coverageDir string
+var historyProject string
+var configOverrides models.ConfigOverrides
+var allowLocalTargets bool
+var streamOutput bool
+var stabilityRuns int
+var cpuThrottle int
+var testMatrix models.GenerationDefaults
+var evaluatorScreenshots bool
+var axeTests bool
+var visualTests bool
 
 var rootCmd = &cobra.Command{
 	Use:   "testbuddy",
 	Short: "TestBuddy CLI",
+	// Every command is a job, its log records carry the job ID
+	// Everything it fetches is checked against the outbound URL policy, and tests run in the sandbox
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		cfg := loadConfig()
+		logger.Configure(cfg.LogLevel, cfg.LogFormat)
+		ctx := logger.WithCorrelation(cmd.Context(), logger.JobIDKey, logger.NewID())
+		ctx = runner.NewContext(ctx, runner.ForConfig(cfg))
+		ctx = gen_eval_loop.WithConfig(ctx, cfg)
+		if streamOutput {
+			ctx = events.NewContext(ctx, printSink{})
+		}
+		cmd.SetContext(netguard.NewContext(ctx, netguard.ForConfig(cfg)))
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
 }
 
+// printSink prints the output lines of the Playwright commands
+type printSink struct{}
+
+func (printSink) Emit(event models.RunEvent) {
+	if event.Stream != events.StreamStatus {
+		fmt.Printf("  [%s] %s\n", event.Step, event.Line)
+	}
+}
+
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate Playwright tests for a website",
 	Run: func(cmd *cobra.Command, args []string) {
 
 		// Initialize config and LLM client
-		cfg := config.Load()
+		cfg := loadConfig()
 		client := llm.New(cfg)
+		project := applyProject(cmd)
 
 		basePrompt := `You are a test planning expert. Your task is to analyze the provided website and generate EXACTLY 4 specific test criteria that can be used by another agent to generate E2E tests.
 
@@ -67,54 +116,123 @@ var generateCmd = &cobra.Command{
 		SCENARIO: Verify users can search for products and get relevant results
 		EXPECTED: Search results page should display matching products with correct information`
 
-		websiteDescription := "Check out the website, wonder how is it structured?. I am interested in the content of the most valuable pages to create the criteria to generate an E2E tests. My orgSlug := \"webscopeio-pb\" and projectSlug := \"ai-hackathon-demo\" for Sentry, please check the errors in the last 14 days and include them in the analysis."
+		websiteDescription := fmt.Sprintf("Check out the website, wonder how is it structured?. I am interested in the content of the most valuable pages to create the criteria to generate an E2E tests. My orgSlug := %q and projectSlug := %q for Sentry, please check the errors in the last 14 days and include them in the analysis.", sentryOrg, sentryProject)
 		basePrompt += `\n\IMPORTANT: You are analyzing the following website:` + websiteDescription
 
-		analysis, err := analyzer.Analyze(cmd.Context(), cfg, client, "https://ai-hackathon-demo-delta.vercel.app/", basePrompt)
+		if project != nil && project.TechSpecification != "" {
+			basePrompt += "\n\nTECHNICAL SPECIFICATION: " + project.TechSpecification
+		}
+		if project != nil && project.ProductSpecification != "" {
+			basePrompt += "\n\nPRODUCT SPECIFICATION: " + project.ProductSpecification
+		}
+
+		if gapsPath != "" {
+			report, err := coverage.Load(gapsPath)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			fmt.Printf("\n[MAIN FLOW] Generating criteria for %d coverage gaps\n", len(report.Gaps))
+			basePrompt += coverage.GapsPrompt(report)
+		}
+
+		var runErr error
+		rec := startRun(cmd, cfg, url, models.RunKindGenerate)
+		defer func() { rec.finish(cmd.Context(), runErr) }()
+
+		analysis, err := analyzer.Analyze(cmd.Context(), cfg, client, url, basePrompt)
 		if err != nil {
+			runErr = err
 			fmt.Printf("Error: %v\n", err)
 			return
 		}
 
 		if len(analysis.Criteria) == 0 {
+			runErr = fmt.Errorf("no test criteria were generated from the analysis")
 			fmt.Println("Error: No test criteria were generated from the analysis")
 			return
 		}
 
-		// Split criteria by double newlines
-		criteria := strings.Split(analysis.Criteria, "\n\n")
+		// The parsed criteria are generated, recorded and snapshotted, so their indexes match
+		criteria := models.ParseCriteria(analysis.Criteria)
+		if len(criteria) == 0 {
+			runErr = fmt.Errorf("no test criteria were generated from the analysis")
+			fmt.Println("Error: No test criteria were generated from the analysis")
+			return
+		}
 
 		fmt.Printf("\n[MAIN FLOW] Analyzer generated %d scenarios\n", len(criteria))
-		// print the criteria line by line
-		logger.Debug("CRITERIA LENGTH: %d", len(criteria))
 		for _, c := range criteria {
-			logger.Debug("CRITERIA: %s", c)
+			logger.For(cmd.Context(), "cli").Debug("generated criterion", "criterion", c.String())
 		}
 
-		noOfLoops := 6
+		noOfLoops := generationLoops(project)
+
+		// Snapshot the analyzed pages so `testbuddy update` can diff against them later
+		snap := snapshot.New(url, analysis.TechSpec)
+		sitemap, err := analyzer.GetSitemap(cmd.Context(), url)
+		if err != nil {
+			logger.For(cmd.Context(), "cli").Debug("no sitemap for the snapshot", "error", err)
+			sitemap = nil
+		}
+		snapshot.RecordPages(snap, sitemap, analysis.ContentMap)
+		snap.Criteria = criteria
+		storedCriteria := rec.analysis(cmd.Context(), analysis.TechSpec, pageList(analysis.ContentMap), snap.Criteria)
 
 		for i, c := range criteria {
-			fmt.Printf("\n[MAIN FLOW] Generating test for scenario %d: %s\n", i, c)
-			filename, err := gen_eval_loop.GenEvalLoop(cmd.Context(), client, &models.AnalyzerReturn{
-				TechSpec:   analysis.TechSpec,
-				ContentMap: analysis.ContentMap,
-				Criteria:   analysis.Criteria,
+			fmt.Printf("\n[MAIN FLOW] Generating test for scenario %d: %s\n", i, c.String())
+			ctx := logger.WithCorrelation(cmd.Context(), logger.CriterionIDKey, criterionID(storedCriteria, i))
+			result, err := gen_eval_loop.GenEvalLoop(ctx, client, &models.AnalyzerReturn{
+				TechSpec:      analysis.TechSpec,
+				ContentMap:    analysis.ContentMap,
+				Criteria:      c.String(),
+				BaseURL:       url,
+				Accessibility: analysis.Accessibility,
 			}, i+1, noOfLoops)
 			if err != nil {
+				runErr = err
 				fmt.Printf("Error: %v\n", err)
 				return
 			}
 
-			logger.Debug("[MAIN FLOW] Writing test file: %s\n", filepath.Base(filename))
-
-			// copy the file to the current directory
-			destPath := filepath.Join("./__generated__", filepath.Base(filename))
-			fmt.Printf("\n[MAIN FLOW] Writing generated test file to %s\n", destPath)
-			err = os.Rename(filename, destPath)
+			destPath, err := writeGeneratedTest(result.Filename)
 			if err != nil {
+				runErr = err
 				fmt.Printf("Error copying file: %v\n", err)
 				return
 			}
+			rec.genEval(cmd.Context(), storedCriteria, i, destPath, result)
+			printStability(result)
+
+			recordGeneratedTest(snap, destPath, c)
+		}
+
+		if err := snapshot.Save(snapshotPath, snap); err != nil {
+			runErr = err
+			fmt.Printf("Error saving snapshot: %v\n", err)
+			return
+		}
+		fmt.Printf("\n[MAIN FLOW] Saved site snapshot to %s\n", snapshotPath)
+
+		if visualTests {
+			fmt.Printf("\n[MAIN FLOW] Generating visual regression tests\n")
+			visual, err := gen_eval_loop.GenerateVisualTests(cmd.Context(), client, &models.AnalyzerReturn{
+				TechSpec:   analysis.TechSpec,
+				ContentMap: analysis.ContentMap,
+				BaseURL:    url,
+			}, generatedDir)
+			if err != nil {
+				runErr = err
+				fmt.Printf("Error generating visual tests: %v\n", err)
+				return
+			}
+			fmt.Printf("[MAIN FLOW] Wrote %s with %d baselines\n", visual.SpecFile, len(visual.Baselines))
+			if !visual.Stable {
+				fmt.Printf("[MAIN FLOW] Warning: the visual tests fail against their own baselines, mask the regions that change:\n")
+				for _, failure := range visual.Failures {
+					fmt.Printf("  - %s\n", failure)
+				}
+			}
 		}
 
 		return
@@ -122,9 +240,317 @@ var generateCmd = &cobra.Command{
 	},
 }
 
+var updateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Re-analyze only the pages that changed since the last snapshot",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := loadConfig()
+		client := llm.New(cfg)
+		project := applyProject(cmd)
+
+		snap, err := snapshot.Load(snapshotPath)
+		if err != nil {
+			fmt.Printf("Error: %v, run `testbuddy generate` first\n", err)
+			return
+		}
+
+		var runErr error
+		rec := startRun(cmd, cfg, snap.BaseURL, models.RunKindUpdate)
+		defer func() { rec.finish(cmd.Context(), runErr) }()
+
+		update, err := snapshot.Update(cmd.Context(), client, snap)
+		if err != nil {
+			runErr = err
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		fmt.Printf("\n[UPDATE] %d added, %d changed, %d removed, %d unchanged pages\n",
+			len(update.Added), len(update.Changed), len(update.Removed), len(update.Unchanged))
+		for _, test := range update.StaleTests {
+			fmt.Printf("[UPDATE] Likely stale test %s (%s)\n", test.File, test.StaleReason)
+		}
+		fmt.Printf("[UPDATE] Analyzer proposed %d new or changed criteria\n", len(update.NewCriteria))
+
+		noOfLoops := generationLoops(project)
+		offset := len(snap.Tests)
+		storedCriteria := rec.analysis(cmd.Context(), snap.TechSpec, pageList(update.Contents), update.NewCriteria)
+
+		for i, c := range update.NewCriteria {
+			fmt.Printf("\n[UPDATE] Generating test for scenario %d: %s\n", i, c.Title)
+			ctx := logger.WithCorrelation(cmd.Context(), logger.CriterionIDKey, criterionID(storedCriteria, i))
+			result, err := gen_eval_loop.GenEvalLoop(ctx, client, &models.AnalyzerReturn{
+				TechSpec:   snap.TechSpec,
+				ContentMap: update.Contents,
+				Criteria:   c.String(),
+				BaseURL:    snap.BaseURL,
+			}, offset+i+1, noOfLoops)
+			if err != nil {
+				runErr = err
+				fmt.Printf("Error: %v\n", err)
+				break
+			}
+
+			destPath, err := writeGeneratedTest(result.Filename)
+			if err != nil {
+				runErr = err
+				fmt.Printf("Error copying file: %v\n", err)
+				break
+			}
+			recordGeneratedTest(snap, destPath, c)
+			rec.genEval(cmd.Context(), storedCriteria, i, destPath, result)
+			printStability(result)
+		}
+
+		if err := snapshot.Save(snapshotPath, snap); err != nil {
+			runErr = err
+			fmt.Printf("Error saving snapshot: %v\n", err)
+			return
+		}
+		fmt.Printf("\n[UPDATE] Saved site snapshot to %s\n", snapshotPath)
+	},
+}
+
+var reproduceCmd = &cobra.Command{
+	Use:   "reproduce <issueId>",
+	Short: "Generate a Playwright regression test that reproduces a Sentry issue",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := loadConfig()
+		client := llm.New(cfg)
+		noOfLoops := generationLoops(applyProject(cmd))
+
+		var runErr error
+		rec := startRun(cmd, cfg, url, models.RunKindReproduce)
+		defer func() { rec.finish(cmd.Context(), runErr) }()
+
+		fmt.Printf("\n[REPRODUCE] Generating regression test for Sentry issue %s\n", args[0])
+		generated, err := reproducer.Reproduce(cmd.Context(), cfg, client, url, sentryOrg, args[0], noOfLoops)
+		if err != nil {
+			runErr = err
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		runErr = recordGenerated(cmd.Context(), rec, generated)
+	},
+}
+
+var fromSessionCmd = &cobra.Command{
+	Use:   "from-session <sessionId>",
+	Short: "Generate a Playwright test that follows the path of a recorded Umami session",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := loadConfig()
+		client := llm.New(cfg)
+		noOfLoops := generationLoops(applyProject(cmd))
+
+		var runErr error
+		rec := startRun(cmd, cfg, url, models.RunKindFromSession)
+		defer func() { rec.finish(cmd.Context(), runErr) }()
+
+		fmt.Printf("\n[FROM SESSION] Generating test for Umami session %s\n", args[0])
+		generated, err := session_replay.Replay(cmd.Context(), cfg, client, url, args[0], noOfLoops)
+		if err != nil {
+			runErr = err
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		runErr = recordGenerated(cmd.Context(), rec, generated)
+	},
+}
+
+var coverageCmd = &cobra.Command{
+	Use:   "coverage",
+	Short: "Report which pages, user flows and Sentry-affected paths the generated tests exercise",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := loadConfig()
+		ctx := cmd.Context()
+		applyProject(cmd)
+
+		testFiles, err := coverage.TestFiles(generatedDir)
+		if err != nil || len(testFiles) == 0 {
+			fmt.Printf("Error: no generated tests in %s, run `testbuddy generate` first\n", generatedDir)
+			return
+		}
+
+		fmt.Printf("\n[COVERAGE] Running %d generated test files with tracing\n", len(testFiles))
+		tests, err := coverage.Run(ctx, url, testFiles)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		// The sitemap, user flows and Sentry paths are optional, a missing source leaves its section empty
+		sitemap, err := analyzer.GetSitemap(ctx, url)
+		if err != nil {
+			fmt.Printf("[COVERAGE] Skipping sitemap: %v\n", err)
+		}
+
+		var flows []models.UmamiSignificantFlow
+		if source, err := analytics.New(cfg); err != nil {
+			fmt.Printf("[COVERAGE] Skipping user flows: %v\n", err)
+		} else if flows, err = analyzer.GetSignificantUserFlows(ctx, source, 7, 2, 2); err != nil {
+			fmt.Printf("[COVERAGE] Skipping user flows: %v\n", err)
+		}
+
+		sentryPaths, err := analyzer.GetAffectedSentryPaths(ctx, cfg, models.SentryTool{OrgSlug: sentryOrg, ProjectSlug: sentryProject})
+		if err != nil {
+			fmt.Printf("[COVERAGE] Skipping Sentry paths: %v\n", err)
+		}
+
+		report := coverage.Build(url, tests, sitemap, flows, sentryPaths)
+
+		jsonPath := filepath.Join(coverageDir, "coverage.json")
+		if err := coverage.Save(jsonPath, report); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		htmlPath := filepath.Join(coverageDir, "coverage.html")
+		if err := coverage.WriteHTML(htmlPath, report); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		fmt.Printf("\n[COVERAGE] Found %d gaps, wrote %s and %s\n", len(report.Gaps), jsonPath, htmlPath)
+		if len(report.Gaps) > 0 {
+			fmt.Printf("[COVERAGE] Run `testbuddy generate --gaps %s` to generate tests for them\n", jsonPath)
+		}
+	},
+}
+
+// writeGeneratedTest moves a generated test file into the generated tests directory
+func writeGeneratedTest(filename string) (string, error) {
+	destPath := filepath.Join(generatedDir, filepath.Base(filename))
+	fmt.Printf("\n[MAIN FLOW] Writing generated test file to %s\n", destPath)
+	if err := os.Rename(filename, destPath); err != nil {
+		return "", err
+	}
+
+	return destPath, nil
+}
+
+// recordGenerated moves a test generated for a single criterion into place and records it in the run
+func recordGenerated(ctx context.Context, rec *runRecorder, generated *models.GeneratedTestReturn) error {
+	destPath, err := writeGeneratedTest(generated.Filename)
+	if err != nil {
+		fmt.Printf("Error copying file: %v\n", err)
+		return err
+	}
+
+	storedCriteria := rec.analysis(ctx, generated.TechSpec, generated.Pages, []models.TestCriterion{generated.Criterion})
+	rec.genEval(ctx, storedCriteria, 0, destPath, generated.Result)
+	printStability(generated.Result)
+	return nil
+}
+
+// printStability prints the stability score of the last iteration, if the stability runs were on
+func printStability(result *models.GenEvalResult) {
+	if result == nil || len(result.Iterations) == 0 {
+		return
+	}
+	report := result.Iterations[len(result.Iterations)-1].Stability
+	if report == nil {
+		return
+	}
+
+	fmt.Printf("[STABILITY] Score %.2f over %d runs, stable: %v\n", report.Score, report.Runs, report.Stable)
+	for _, test := range report.Tests {
+		fmt.Printf("  %s: %d/%d passed, %.0f ± %.0f ms\n", test.Title, test.Passed, test.Runs, test.MeanMs, test.StdDevMs)
+	}
+}
+
+// recordGeneratedTest adds a generated test file to the snapshot
+func recordGeneratedTest(snap *models.SiteSnapshot, destPath string, criterion models.TestCriterion) {
+	content, err := os.ReadFile(destPath)
+	if err != nil {
+		logger.Default().Debug("couldn't read the test file for the snapshot", "subsystem", "cli", "file", destPath, "error", err)
+		return
+	}
+	snapshot.RecordTest(snap, destPath, criterion, string(content))
+}
+
 func init() {
-	generateCmd.Flags()
+	rootCmd.PersistentFlags().StringVar(&snapshotPath, "snapshot", filepath.Join(generatedDir, "snapshot.json"), "Path to the site snapshot file")
+	rootCmd.PersistentFlags().StringVar(&configOverrides.AnthropicApiKey, "anthropic-api-key", "", "Anthropic API key, overrides API_KEY and the config file")
+	rootCmd.PersistentFlags().StringVar(&configOverrides.SentryApiKey, "sentry-token", "", "Sentry auth token, overrides SENTRY_AUTH_TOKEN and the config file")
+	rootCmd.PersistentFlags().StringVar(&configOverrides.SentryURL, "sentry-url", "", "Sentry API URL, overrides SENTRY_URL")
+	rootCmd.PersistentFlags().StringVar(&configOverrides.UmamiAPIKey, "umami-api-key", "", "Umami API key, overrides UMAMI_API_KEY and the config file")
+	rootCmd.PersistentFlags().StringVar(&configOverrides.UmamiURL, "umami-url", "", "Umami API URL, overrides UMAMI_URL")
+	rootCmd.PersistentFlags().StringVar(&configOverrides.UmamiWebsiteId, "umami-website-id", "", "Umami website ID, overrides UMAMI_WEBSITE_ID and the current project")
+	rootCmd.PersistentFlags().BoolVar(&allowLocalTargets, "allow-local", false, "Allow localhost and private network targets, same as ALLOW_LOCAL_TARGETS=true")
+	rootCmd.PersistentFlags().BoolVar(&streamOutput, "stream-output", false, "Print the output of Playwright installs and test runs as it arrives")
+	rootCmd.PersistentFlags().IntVar(&stabilityRuns, "stability-runs", 0, "Run accepted tests this many times and regenerate flaky ones, same as STABILITY_RUNS")
+	rootCmd.PersistentFlags().StringSliceVar(&testMatrix.Projects, "projects", nil, "Browser and device projects generated tests run on ("+strings.Join(gen_eval_loop.SupportedProjects(), ", ")+"), same as TEST_PROJECTS")
+	rootCmd.PersistentFlags().StringVar(&testMatrix.Locale, "locale", "", "Browser locale of generated tests, e.g. de-DE, same as TEST_LOCALE")
+	rootCmd.PersistentFlags().StringVar(&testMatrix.Timezone, "timezone", "", "Browser timezone of generated tests, e.g. Europe/Berlin, same as TEST_TIMEZONE")
+	rootCmd.PersistentFlags().StringVar(&testMatrix.ColorScheme, "color-scheme", "", "Color scheme of generated tests (light, dark or no-preference), same as TEST_COLOR_SCHEME")
+	rootCmd.PersistentFlags().BoolVar(&testMatrix.RequireAllProjects, "require-all-projects", false, "Only accept tests passing on every project, same as REQUIRE_ALL_PROJECTS=true")
+	rootCmd.PersistentFlags().BoolVar(&evaluatorScreenshots, "evaluator-screenshots", false, "Show the evaluator the screenshot of the last test failure, same as EVALUATOR_SCREENSHOTS=true")
+	rootCmd.PersistentFlags().BoolVar(&axeTests, "axe-tests", false, "Let generated tests check accessibility with @axe-core/playwright, same as AXE_TESTS=true")
+	rootCmd.PersistentFlags().IntVar(&cpuThrottle, "cpu-throttle", 0, "Slow down the browser CPU by this factor during the stability runs, same as STABILITY_CPU_THROTTLE")
+
+	generateCmd.Flags().StringVar(&url, "url", "https://ai-hackathon-demo-delta.vercel.app/", "URL of the website to analyze")
+	generateCmd.Flags().StringVar(&gapsPath, "gaps", "", "Coverage report whose gaps the criteria should target")
+	generateCmd.Flags().StringVar(&sentryOrg, "org", "webscopeio-pb", "Sentry organization slug")
+	generateCmd.Flags().StringVar(&sentryProject, "sentry-project", "ai-hackathon-demo", "Sentry project slug")
+	generateCmd.Flags().BoolVar(&visualTests, "visual", false, "Also generate visual regression tests of the key pages and capture their baselines")
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(updateCmd)
+
+	reproduceCmd.Flags().StringVar(&sentryOrg, "org", "webscopeio-pb", "Sentry organization slug")
+	reproduceCmd.Flags().StringVar(&url, "url", "https://ai-hackathon-demo-delta.vercel.app/", "URL of the website the issue was reported on")
+	rootCmd.AddCommand(reproduceCmd)
+
+	fromSessionCmd.Flags().StringVar(&url, "url", "https://ai-hackathon-demo-delta.vercel.app/", "URL of the website the session was recorded on")
+	rootCmd.AddCommand(fromSessionCmd)
+
+	coverageCmd.Flags().StringVar(&url, "url", "https://ai-hackathon-demo-delta.vercel.app/", "URL of the website the tests run against")
+	coverageCmd.Flags().StringVar(&coverageDir, "out", filepath.Join(generatedDir, "coverage"), "Directory for the coverage report")
+	coverageCmd.Flags().StringVar(&sentryOrg, "org", "webscopeio-pb", "Sentry organization slug")
+	coverageCmd.Flags().StringVar(&sentryProject, "sentry-project", "ai-hackathon-demo", "Sentry project slug")
+	rootCmd.AddCommand(coverageCmd)
+
+	historyCmd.Flags().StringVar(&historyProject, "project", "", "Only list the runs of the named project")
+	historyCmd.AddCommand(historyCompareCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// loadConfig loads the layered config with the CLI flags on top
+func loadConfig() *config.Config {
+	cfg := config.Load().With(&configOverrides)
+	if allowLocalTargets {
+		cfg.AllowLocalTargets = true
+	}
+	if stabilityRuns > 0 {
+		cfg.StabilityRuns = stabilityRuns
+	}
+	if cpuThrottle > 0 {
+		cfg.StabilityCPUThrottle = cpuThrottle
+	}
+	if len(testMatrix.Projects) > 0 {
+		cfg.TestProjects = testMatrix.Projects
+	}
+	if testMatrix.Locale != "" {
+		cfg.TestLocale = testMatrix.Locale
+	}
+	if testMatrix.Timezone != "" {
+		cfg.TestTimezone = testMatrix.Timezone
+	}
+	if testMatrix.ColorScheme != "" {
+		cfg.TestColorScheme = testMatrix.ColorScheme
+	}
+	if testMatrix.RequireAllProjects {
+		cfg.RequireAllProjects = true
+	}
+	if evaluatorScreenshots {
+		cfg.EvaluatorScreenshots = true
+	}
+	if axeTests {
+		cfg.AxeTests = true
+	}
+	return cfg
 }
 
 func main() {
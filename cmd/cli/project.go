@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	configManager "github.com/webscopeio/ai-hackathon/internal/repository/config"
+)
+
+// defaultLoops is the number of gen-eval iterations when the project doesn't set it
+const defaultLoops = 6
+
+var newProject models.ProjectConfig
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage the target apps tests are generated for",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var projectAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a project, the first project added becomes the current one",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, err := configManager.NewManager()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		newProject.Name = args[0]
+		// Jobs run from other directories, so the storage state is stored with its absolute path
+		if newProject.Auth.StorageState != "" {
+			if newProject.Auth.StorageState, err = filepath.Abs(newProject.Auth.StorageState); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+		}
+		if err := manager.AddProject(newProject); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Added project %s (%s)\n", newProject.Name, newProject.URL)
+	},
+}
+
+var projectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the projects, the current one is marked with *",
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, err := configManager.NewManager()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		userConfig, err := manager.GetConfig()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if len(userConfig.Projects) == 0 {
+			fmt.Println("No projects yet, add one with `testbuddy project add <name> --url <url>`")
+			return
+		}
+
+		for _, project := range userConfig.Projects {
+			marker := " "
+			if project.Name == userConfig.CurrentProject {
+				marker = "*"
+			}
+			fmt.Printf("%s %-20s %s\n", marker, project.Name, project.URL)
+		}
+	},
+}
+
+var projectUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make a project the current one",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		manager, err := configManager.NewManager()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		if err := manager.UseProject(args[0]); err != nil {
+			if errors.Is(err, configManager.ErrProjectNotFound) {
+				fmt.Printf("Error: no project named %s, see `testbuddy project list`\n", args[0])
+				return
+			}
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Using project %s\n", args[0])
+	},
+}
+
+// applyProject fills the flags that weren't set explicitly from the current project
+// It returns the current project, or nil if there is none
+// Its Umami website is part of the config file layer loaded by loadConfig
+func applyProject(cmd *cobra.Command) *models.ProjectConfig {
+	manager, err := configManager.NewManager()
+	if err != nil {
+		return nil
+	}
+
+	project, err := manager.CurrentProject()
+	if err != nil || project == nil {
+		return nil
+	}
+
+	setDefault := func(flag string, target *string, value string) {
+		if value == "" || cmd.Flags().Lookup(flag) == nil || cmd.Flags().Changed(flag) {
+			return
+		}
+		*target = value
+	}
+	setDefault("url", &url, project.URL)
+	setDefault("org", &sentryOrg, project.SentryOrg)
+	setDefault("sentry-project", &sentryProject, project.SentryProject)
+
+	fmt.Printf("[PROJECT] Using project %s\n", project.Name)
+	return project
+}
+
+// generationLoops returns the number of gen-eval iterations for a project
+func generationLoops(project *models.ProjectConfig) int {
+	if project != nil && project.Generation.Loops > 0 {
+		return project.Generation.Loops
+	}
+	return defaultLoops
+}
+
+func init() {
+	projectAddCmd.Flags().StringVar(&newProject.URL, "url", "", "URL of the target app")
+	projectAddCmd.Flags().StringVar(&newProject.TechSpecification, "tech-spec", "", "Technical specification of the app")
+	projectAddCmd.Flags().StringVar(&newProject.ProductSpecification, "product-spec", "", "Product specification of the app")
+	projectAddCmd.Flags().StringVar(&newProject.SentryOrg, "sentry-org", "", "Sentry organization slug")
+	projectAddCmd.Flags().StringVar(&newProject.SentryProject, "sentry-project", "", "Sentry project slug")
+	projectAddCmd.Flags().StringVar(&newProject.UmamiWebsiteId, "umami-website", "", "Umami website ID")
+	projectAddCmd.Flags().StringSliceVar(&newProject.AllowedDomains, "allowed-domain", nil, "Domain jobs of the project may reach besides the project URL, repeatable")
+	projectAddCmd.Flags().StringVar(&newProject.Auth.StorageState, "storage-state", "", "Playwright storage state file of a signed-in session")
+	projectAddCmd.Flags().IntVar(&newProject.Generation.Loops, "loops", 0, "Maximum gen-eval iterations per test")
+	projectAddCmd.Flags().StringSliceVar(&newProject.Generation.Projects, "projects", nil, "Browser and device projects generated tests run on")
+	projectAddCmd.Flags().StringVar(&newProject.Generation.Locale, "locale", "", "Browser locale of generated tests")
+	projectAddCmd.Flags().StringVar(&newProject.Generation.Timezone, "timezone", "", "Browser timezone of generated tests")
+	projectAddCmd.Flags().StringVar(&newProject.Generation.ColorScheme, "color-scheme", "", "Color scheme of generated tests")
+	projectAddCmd.Flags().BoolVar(&newProject.Generation.RequireAllProjects, "require-all-projects", false, "Only accept tests passing on every project")
+	projectAddCmd.MarkFlagRequired("url")
+
+	projectCmd.AddCommand(projectAddCmd)
+	projectCmd.AddCommand(projectListCmd)
+	projectCmd.AddCommand(projectUseCmd)
+	rootCmd.AddCommand(projectCmd)
+}
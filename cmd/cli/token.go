@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/webscopeio/ai-hackathon/internal/auth"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/store"
+)
+
+var (
+	tokenRole      string
+	tokenRateLimit int
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the bearer tokens of the API server",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var tokenIssueCmd = &cobra.Command{
+	Use:   "issue <name>",
+	Short: "Issue a token, it is printed once and only its hash is stored",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := store.New(loadConfig())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		plaintext, token, err := auth.Issue(cmd.Context(), repo, args[0], tokenRole, tokenRateLimit)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Issued %s token %s (%s) for %s\n", token.Role, token.ID, token.Prefix, token.Name)
+		fmt.Printf("\n%s\n\nStore it now, it can't be shown again. Send it as `Authorization: Bearer <token>`\n", plaintext)
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <id|prefix>",
+	Short: "Revoke a token by its ID or prefix",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := store.New(loadConfig())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		token, err := auth.Revoke(cmd.Context(), repo, args[0])
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				fmt.Printf("Error: no token %s, see `testbuddy token list`\n", args[0])
+				return
+			}
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Printf("Revoked token %s (%s) of %s\n", token.ID, token.Prefix, token.Name)
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the issued tokens",
+	Run: func(cmd *cobra.Command, args []string) {
+		repo, err := store.New(loadConfig())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		tokens, err := repo.ListTokens(cmd.Context())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No tokens yet, issue one with `testbuddy token issue <name> --role admin`")
+			return
+		}
+
+		for _, token := range tokens {
+			status := "active"
+			if !token.RevokedAt.IsZero() {
+				status = "revoked " + token.RevokedAt.Format("2006-01-02 15:04")
+			}
+			rateLimit := "default"
+			if token.RateLimit > 0 {
+				rateLimit = fmt.Sprintf("%d/min", token.RateLimit)
+			}
+			fmt.Printf("%s  %s  %-6s  %-20s %-8s %s\n", token.ID, token.Prefix, token.Role, token.Name, rateLimit, status)
+		}
+	},
+}
+
+func init() {
+	tokenIssueCmd.Flags().StringVar(&tokenRole, "role", models.RoleMember, "Role of the token, admin can change the config and projects, member can run jobs")
+	tokenIssueCmd.Flags().IntVar(&tokenRateLimit, "rate-limit", 0, "Requests per minute, 0 uses the server default")
+
+	tokenCmd.AddCommand(tokenIssueCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
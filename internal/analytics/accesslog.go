@@ -0,0 +1,197 @@
+package analytics
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+const accessLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// combinedLogRegex matches the nginx/Apache combined log format:
+// remote - user [time] "METHOD path PROTOCOL" status bytes "referrer" "user agent"
+var combinedLogRegex = regexp.MustCompile(`^(\S+) \S+ \S+ \[([^\]]+)\] "(\S+) (\S+)[^"]*" (\d{3}) \S+ "[^"]*" "([^"]*)"`)
+
+// staticAssetExtensions are requests that are not page views
+var staticAssetExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".css": true, ".map": true, ".json": true, ".xml": true, ".txt": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".avif": true, ".ico": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".eot": true, ".mp4": true, ".webm": true, ".pdf": true,
+}
+
+// AccessLogSource reads sessions from nginx/Apache combined access logs on disk
+// Requests are grouped into sessions by client IP and user agent, and a new
+// session starts after the inactivity timeout.
+type AccessLogSource struct {
+	// Pattern is a file path or glob, rotated .gz files are supported
+	Pattern string
+	Timeout time.Duration
+}
+
+// NewAccessLogSource creates a source reading the access logs matching the pattern
+func NewAccessLogSource(pattern string, timeout time.Duration) *AccessLogSource {
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+	return &AccessLogSource{Pattern: pattern, Timeout: timeout}
+}
+
+type accessLogEntry struct {
+	visitor   string
+	path      string
+	timestamp time.Time
+}
+
+// Sessions parses the page views in the date range and groups them into sessions
+func (s *AccessLogSource) Sessions(ctx context.Context, startDate, endDate time.Time) ([]models.AnalyticsSession, error) {
+	files, err := filepath.Glob(s.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access log path: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no access log files found at %s", s.Pattern)
+	}
+
+	var entries []accessLogEntry
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fileEntries, err := readAccessLog(file, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	logger.For(ctx, "analytics").Debug("parsed access logs", "page_views", len(entries), "files", len(files))
+	return groupSessions(entries, s.Timeout), nil
+}
+
+// readAccessLog parses the page views of a single access log file
+func readAccessLog(path string, startDate, endDate time.Time) ([]accessLogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzipped access log: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var entries []accessLogEntry
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry, ok := parseAccessLogLine(scanner.Text())
+		if !ok || entry.timestamp.Before(startDate) || entry.timestamp.After(endDate) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read access log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// parseAccessLogLine parses a combined log line, returning false for anything but a page view
+func parseAccessLogLine(line string) (accessLogEntry, bool) {
+	match := combinedLogRegex.FindStringSubmatch(line)
+	if match == nil {
+		return accessLogEntry{}, false
+	}
+
+	ip, rawTime, method, target, rawStatus, userAgent := match[1], match[2], match[3], match[4], match[5], match[6]
+
+	if method != "GET" {
+		return accessLogEntry{}, false
+	}
+
+	status, _ := strconv.Atoi(rawStatus)
+	if status >= 400 {
+		return accessLogEntry{}, false
+	}
+
+	parsed, err := url.ParseRequestURI(target)
+	if err != nil || staticAssetExtensions[strings.ToLower(filepath.Ext(parsed.Path))] {
+		return accessLogEntry{}, false
+	}
+
+	timestamp, err := time.Parse(accessLogTimeLayout, rawTime)
+	if err != nil {
+		return accessLogEntry{}, false
+	}
+
+	return accessLogEntry{
+		visitor:   ip + "|" + userAgent,
+		path:      parsed.Path,
+		timestamp: timestamp,
+	}, true
+}
+
+// groupSessions splits the page views of each visitor into sessions on inactivity
+func groupSessions(entries []accessLogEntry, timeout time.Duration) []models.AnalyticsSession {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].timestamp.Before(entries[j].timestamp)
+	})
+
+	type visitorState struct {
+		session  int
+		count    int
+		lastSeen time.Time
+	}
+
+	visitors := make(map[string]*visitorState)
+	var sessions []models.AnalyticsSession
+
+	for _, entry := range entries {
+		state, ok := visitors[entry.visitor]
+		if !ok || entry.timestamp.Sub(state.lastSeen) > timeout {
+			count := 0
+			if ok {
+				count = state.count
+			}
+			sum := sha256.Sum256([]byte(entry.visitor))
+			sessions = append(sessions, models.AnalyticsSession{
+				ID:    fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:8]), count+1),
+				Steps: []models.AnalyticsStep{},
+			})
+			state = &visitorState{session: len(sessions) - 1, count: count + 1}
+			visitors[entry.visitor] = state
+		}
+
+		state.lastSeen = entry.timestamp
+		sessions[state.session].Steps = append(sessions[state.session].Steps, models.AnalyticsStep{
+			Path:      entry.path,
+			Timestamp: entry.timestamp,
+		})
+	}
+
+	return sessions
+}
@@ -0,0 +1,69 @@
+package analytics
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const accessLog = `10.0.0.1 - - [01/Apr/2025:10:00:00 +0000] "GET / HTTP/1.1" 200 512 "-" "Firefox"
+10.0.0.1 - - [01/Apr/2025:10:00:01 +0000] "GET /static/app.js HTTP/1.1" 200 512 "https://example.com/" "Firefox"
+10.0.0.2 - - [01/Apr/2025:10:00:02 +0000] "GET /blog HTTP/1.1" 200 512 "-" "Chrome"
+10.0.0.1 - - [01/Apr/2025:10:01:00 +0000] "GET /pricing?plan=pro HTTP/1.1" 200 512 "https://example.com/" "Firefox"
+10.0.0.1 - - [01/Apr/2025:10:01:30 +0000] "POST /signup HTTP/1.1" 302 0 "https://example.com/pricing" "Firefox"
+10.0.0.1 - - [01/Apr/2025:10:01:40 +0000] "GET /missing HTTP/1.1" 404 0 "-" "Firefox"
+not a log line
+`
+
+func TestAccessLogSessions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "access.log"), []byte(accessLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rotated log with a visit after the inactivity timeout
+	file, err := os.Create(filepath.Join(dir, "access.log.1.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte(`10.0.0.1 - - [01/Apr/2025:11:00:00 +0000] "GET /docs HTTP/1.1" 200 512 "-" "Firefox"` + "\n"))
+	gz.Close()
+	file.Close()
+
+	source := NewAccessLogSource(filepath.Join(dir, "access.log*"), 30*time.Minute)
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	sessions, err := source.Sessions(context.Background(), start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Failed to get sessions: %v", err)
+	}
+
+	if len(sessions) != 3 {
+		t.Fatalf("Expected 3 sessions, got %d: %+v", len(sessions), sessions)
+	}
+
+	paths := func(index int) []string {
+		result := []string{}
+		for _, step := range sessions[index].Steps {
+			result = append(result, step.Path)
+		}
+		return result
+	}
+
+	if expected := []string{"/", "/pricing"}; !reflect.DeepEqual(paths(0), expected) {
+		t.Errorf("Expected %v, got %v", expected, paths(0))
+	}
+	if expected := []string{"/blog"}; !reflect.DeepEqual(paths(1), expected) {
+		t.Errorf("Expected %v, got %v", expected, paths(1))
+	}
+	if expected := []string{"/docs"}; !reflect.DeepEqual(paths(2), expected) {
+		t.Errorf("Expected %v, got %v", expected, paths(2))
+	}
+	if sessions[0].ID == sessions[2].ID {
+		t.Errorf("Expected a new session ID after the timeout, got %s twice", sessions[0].ID)
+	}
+}
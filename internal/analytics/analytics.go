@@ -0,0 +1,31 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/umami"
+)
+
+// Source provides visitor sessions as ordered sequences of page views and events
+type Source interface {
+	Sessions(ctx context.Context, startDate, endDate time.Time) ([]models.AnalyticsSession, error)
+}
+
+// New creates the analytics source selected in config
+func New(cfg *config.Config) (Source, error) {
+	switch cfg.AnalyticsSource {
+	case "", "umami":
+		return NewUmamiSource(umami.New(cfg)), nil
+	case "accesslog":
+		if cfg.AccessLogPath == "" {
+			return nil, fmt.Errorf("access log path not configured")
+		}
+		return NewAccessLogSource(cfg.AccessLogPath, cfg.AccessLogSessionTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown analytics source: %s", cfg.AnalyticsSource)
+	}
+}
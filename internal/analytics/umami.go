@@ -0,0 +1,77 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/umami"
+)
+
+// umamiEventTypeCustom is the Umami event type of custom events, page views are 1
+const umamiEventTypeCustom = 2
+
+// UmamiSource reads sessions and their activity from the Umami API
+type UmamiSource struct {
+	client *umami.Client
+}
+
+// NewUmamiSource creates a source backed by an Umami client
+func NewUmamiSource(client *umami.Client) *UmamiSource {
+	return &UmamiSource{client: client}
+}
+
+// Sessions retrieves the sessions in the date range together with their activity
+func (s *UmamiSource) Sessions(ctx context.Context, startDate, endDate time.Time) ([]models.AnalyticsSession, error) {
+	sessions, err := s.client.Sessions(ctx, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Umami sessions: %w", err)
+	}
+
+	if len(sessions) == 0 {
+		logger.For(ctx, "analytics").Debug("no sessions found in the date range")
+		return []models.AnalyticsSession{}, nil
+	}
+
+	activities := s.client.SessionActivities(ctx, sessions, startDate, endDate)
+
+	result := make([]models.AnalyticsSession, 0, len(sessions))
+	for _, session := range sessions {
+		sessionActivities, ok := activities[session.ID]
+		if !ok || len(sessionActivities) == 0 {
+			continue // Skip sessions with no activities
+		}
+		result = append(result, ConvertUmamiActivities(session.ID, sessionActivities))
+	}
+
+	return result, nil
+}
+
+// ConvertUmamiActivities converts the activity of an Umami session into ordered steps
+func ConvertUmamiActivities(sessionID string, activities []models.UmamiSessionActivity) models.AnalyticsSession {
+	steps := make([]models.AnalyticsStep, 0, len(activities))
+	for _, activity := range activities {
+		timestamp, err := time.Parse(time.RFC3339, activity.CreatedAt)
+		if err != nil {
+			logger.Default().Debug("invalid activity timestamp", "subsystem", "analytics", "timestamp", activity.CreatedAt, "session_id", sessionID)
+		}
+
+		step := models.AnalyticsStep{
+			Path:      activity.URLPath,
+			Timestamp: timestamp,
+		}
+		if activity.EventType == umamiEventTypeCustom {
+			step.Event = activity.EventName
+		}
+		steps = append(steps, step)
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Timestamp.Before(steps[j].Timestamp)
+	})
+
+	return models.AnalyticsSession{ID: sessionID, Steps: steps}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/store"
+)
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	repo, err := store.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	admin, _, err := Issue(ctx, repo, "ci", models.RoleAdmin, 0)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	member, memberToken, err := Issue(ctx, repo, "frontend", models.RoleMember, 2)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+
+	tokens, _ := repo.ListTokens(ctx)
+	for _, token := range tokens {
+		if token.Hash == admin || token.Hash == member {
+			t.Fatalf("Expected only token hashes in the store, got %+v", token)
+		}
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	r := chi.NewRouter()
+	r.Use(Middleware(repo))
+	r.Use(RateLimit(100, time.Minute))
+	r.Post("/analyze", ok)
+	r.With(RequireRole(models.RoleAdmin)).Post("/config", ok)
+
+	status := func(path, token string) int {
+		req := httptest.NewRequest("POST", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := status("/analyze", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", code)
+	}
+	if code := status("/analyze", "tb_unknown"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown token, got %d", code)
+	}
+	if code := status("/config", member); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a member changing the config, got %d", code)
+	}
+	if code := status("/config", admin); code != http.StatusOK {
+		t.Errorf("Expected an admin to change the config, got %d", code)
+	}
+	if code := status("/analyze", member); code != http.StatusOK {
+		t.Errorf("Expected a member to run jobs, got %d", code)
+	}
+	// The member token allows 2 requests per minute, the 403 above counted too
+	if code := status("/analyze", member); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the member token to be rate limited, got %d", code)
+	}
+	if code := status("/analyze", admin); code != http.StatusOK {
+		t.Errorf("Expected other tokens to keep their own limit, got %d", code)
+	}
+
+	if _, err := Revoke(ctx, repo, memberToken.Prefix); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+	if _, err := Authenticate(ctx, repo, member); err != ErrInvalidToken {
+		t.Errorf("Expected a revoked token to be rejected, got %v", err)
+	}
+}
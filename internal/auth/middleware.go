@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/httprate"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/store"
+)
+
+// Middleware rejects requests without a valid bearer token and puts the token in the request context
+func Middleware(repo store.Repository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plaintext, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="testbuddy"`)
+				writeError(w, http.StatusUnauthorized, "missing bearer token")
+				return
+			}
+
+			token, err := Authenticate(r.Context(), repo, plaintext)
+			if err != nil && !errors.Is(err, ErrInvalidToken) {
+				logger.For(r.Context(), "auth").Error("failed to look up token", "error", err)
+				writeError(w, http.StatusInternalServerError, "failed to authenticate")
+				return
+			}
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="testbuddy", error="invalid_token"`)
+				writeError(w, http.StatusUnauthorized, ErrInvalidToken.Error())
+				return
+			}
+
+			ctx := logger.WithCorrelation(NewContext(r.Context(), token), logger.TokenIDKey, token.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole rejects requests whose token doesn't grant role, it runs after Middleware
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasRole(FromContext(r.Context()), role) {
+				writeError(w, http.StatusForbidden, "this endpoint requires the "+role+" role")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimit limits the requests of each token per window, it runs after Middleware
+// Tokens issued with their own rate limit use it instead of requestLimit
+func RateLimit(requestLimit int, window time.Duration) func(http.Handler) http.Handler {
+	limiter := httprate.Limit(requestLimit, window, httprate.WithKeyFuncs(func(r *http.Request) (string, error) {
+		if token := FromContext(r.Context()); token != nil {
+			return token.ID, nil
+		}
+		return httprate.KeyByIP(r)
+	}))
+
+	return func(next http.Handler) http.Handler {
+		limited := limiter(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token := FromContext(r.Context()); token != nil && token.RateLimit > 0 {
+				r = r.WithContext(httprate.WithRequestLimit(r.Context(), token.RateLimit))
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(models.ErrorReturn{Error: message})
+}
@@ -1,83 +1,383 @@
 package config
 
 import (
-	"log"
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	userconfig "github.com/webscopeio/ai-hackathon/internal/repository/config"
 )
 
+// mobileWidth is the narrowest viewport that isn't emulated as a phone
+const mobileWidth = 768
+
 type Config struct {
-	Port            string
-	Environment     string
-	APIKey          string
-	SentryAuthToken string
-	UmamiURL        string
-	UmamiAPIKey     string
-	UmamiWebsiteId  string
+	Port                    string
+	Environment             string
+	APIKey                  string
+	SentryAuthToken         string
+	SentryURL               string
+	UmamiURL                string
+	UmamiAPIKey             string
+	UmamiWebsiteId          string
+	UmamiCacheDir           string
+	AnalyticsSource         string
+	AccessLogPath           string
+	AccessLogSessionTimeout time.Duration
+	DataDir                 string
+	LogLevel                string
+	LogFormat               string
+	OTLPEndpoint            string
+	// AllowLocalTargets lets jobs reach loopback and private network hosts
+	AllowLocalTargets bool
+	// Project is the name of the current project, runs are recorded under it
+	Project string
+	// AllowedDomains limits the hosts jobs may reach, it is set by the current project
+	AllowedDomains []string
+	// StorageState is the Playwright storage state file generated tests start signed in with, it is set by the current project
+	StorageState string
+	// RunnerTimeout and RunnerInstallTimeout limit the test and install phases of the sandboxed Playwright commands,
+	// RunnerMemoryMB and RunnerCPUSeconds limit every command
+	RunnerTimeout        time.Duration
+	RunnerInstallTimeout time.Duration
+	RunnerMemoryMB       int
+	RunnerCPUSeconds     int
+	// StabilityRuns is how often an accepted test file is run to detect flaky tests, 0 turns it off.
+	// Tests passing less than StabilityThreshold of the runs go back to the generator.
+	// StabilityCPUThrottle slows down the browser CPU by its factor during the runs.
+	// StabilityMode is "repeat" for one process with --repeat-each or "processes" for a process per run.
+	StabilityRuns        int
+	StabilityThreshold   float64
+	StabilityCPUThrottle int
+	StabilityMode        string
+	// TestProjects are the Playwright browser and device projects generated tests run on,
+	// with the browser context settings TestLocale, TestTimezone and TestColorScheme.
+	// RequireAllProjects only lets tests pass when they pass on every project.
+	TestProjects       []string
+	TestLocale         string
+	TestTimezone       string
+	TestColorScheme    string
+	RequireAllProjects bool
+	// EvaluatorScreenshots sends the screenshot of the last test failure to the evaluator
+	EvaluatorScreenshots bool
+	// AxeTests lets generated tests use @axe-core/playwright for accessibility checks
+	AxeTests bool
+	// ScreenshotViewports are the window sizes of the analyzer screenshots,
+	// ScreenshotBudgetKB limits the size of the screenshots of one tool call
+	ScreenshotViewports []models.Viewport
+	ScreenshotBudgetKB  int
 }
 
+// Load builds the configuration from its layers, each overriding the previous one:
+// defaults < user config file < .env < process environment
+// CLI flags and per-request overrides are applied on top of it with With
+// The loaded secrets are redacted from the log output
 func Load() *Config {
-	cfg := &Config{
-		Port:            "8080",
-		Environment:     "development",
-		APIKey:          "",
-		SentryAuthToken: "",
-		UmamiURL:        "https://api.umami.is/v1",
-		UmamiAPIKey:     "",
-		UmamiWebsiteId:  "",
+	cfg := load(readUserConfig(), readDotEnv(), environ())
+	cfg.registerSecrets()
+	return cfg
+}
+
+func load(userConfig *models.UserConfig, dotEnv, env map[string]string) *Config {
+	cfg := defaults()
+	cfg.applyUserConfig(userConfig)
+	cfg.applyEnv(dotEnv)
+	cfg.applyEnv(env)
+	return cfg
+}
+
+func defaults() *Config {
+	return &Config{
+		Port:                    "8080",
+		Environment:             "development",
+		APIKey:                  "",
+		SentryAuthToken:         "",
+		SentryURL:               "https://sentry.io/api/0",
+		UmamiURL:                "https://api.umami.is/v1",
+		UmamiAPIKey:             "",
+		UmamiWebsiteId:          "",
+		UmamiCacheDir:           "",
+		AnalyticsSource:         "umami",
+		AccessLogPath:           "",
+		AccessLogSessionTimeout: 30 * time.Minute,
+		DataDir:                 "",
+		LogLevel:                "info",
+		LogFormat:               "text",
+		OTLPEndpoint:            "",
+		AllowLocalTargets:       false,
+		RunnerTimeout:           10 * time.Minute,
+		RunnerInstallTimeout:    15 * time.Minute,
+		RunnerMemoryMB:          4096,
+		RunnerCPUSeconds:        1200,
+		StabilityRuns:           0,
+		StabilityThreshold:      1,
+		StabilityCPUThrottle:    0,
+		StabilityMode:           "repeat",
+		TestProjects:            []string{"chromium"},
+		ScreenshotViewports: []models.Viewport{
+			{Name: "desktop", Width: 1280, Height: 800},
+			{Name: "mobile", Width: 390, Height: 844, Mobile: true},
+		},
+		ScreenshotBudgetKB: 4096,
 	}
+}
 
-	workDir, _ := os.Getwd()
-	rootDir := workDir
+// With returns a copy of the config with the non-empty overrides applied
+// The Sentry and Umami URLs are only overridden together with their credentials,
+// so the stored token and key are never sent to a host picked by the request
+func (c *Config) With(overrides *models.ConfigOverrides) *Config {
+	cfg := *c
+	if overrides == nil {
+		return &cfg
+	}
 
-	for {
-		if _, err := os.Stat(filepath.Join(rootDir, "go.mod")); err == nil {
-			break
+	setIfPresent(&cfg.APIKey, overrides.AnthropicApiKey)
+	if strings.TrimSpace(overrides.SentryApiKey) != "" {
+		setIfPresent(&cfg.SentryAuthToken, overrides.SentryApiKey)
+		setIfPresent(&cfg.SentryURL, strings.TrimSuffix(overrides.SentryURL, "/"))
+	}
+	if strings.TrimSpace(overrides.UmamiAPIKey) != "" {
+		setIfPresent(&cfg.UmamiAPIKey, overrides.UmamiAPIKey)
+		setIfPresent(&cfg.UmamiURL, overrides.UmamiURL)
+	}
+	setIfPresent(&cfg.UmamiWebsiteId, overrides.UmamiWebsiteId)
+	cfg.registerSecrets()
+	return &cfg
+}
+
+func (c *Config) registerSecrets() {
+	logger.RegisterSecrets(c.APIKey, c.SentryAuthToken, c.UmamiAPIKey)
+}
+
+// applyUserConfig applies the keys saved through the config file and its current project
+func (c *Config) applyUserConfig(userConfig *models.UserConfig) {
+	if userConfig == nil {
+		return
+	}
+
+	setIfPresent(&c.APIKey, userConfig.AnthropicApiKey)
+	setIfPresent(&c.SentryAuthToken, userConfig.SentryApiKey)
+	setIfPresent(&c.UmamiAPIKey, userConfig.UmamiAPIKey)
+	setIfPresent(&c.UmamiWebsiteId, userConfig.UmamiWebsiteId)
+
+	for _, project := range userConfig.Projects {
+		if project.Name != userConfig.CurrentProject {
+			continue
 		}
-		parent := filepath.Dir(rootDir)
-		if parent == rootDir {
-			rootDir = workDir
-			break
+		c.Project = project.Name
+		c.applyGeneration(project.Generation)
+		setIfPresent(&c.StorageState, project.Auth.StorageState)
+
+		// The project URL is always allowed when the project limits its domains
+		if len(project.AllowedDomains) == 0 {
+			continue
+		}
+		c.AllowedDomains = append([]string{}, project.AllowedDomains...)
+		if parsed, err := url.Parse(project.URL); err == nil && parsed.Hostname() != "" {
+			c.AllowedDomains = append(c.AllowedDomains, parsed.Hostname())
 		}
-		rootDir = parent
 	}
+}
 
-	envMap, err := godotenv.Read(filepath.Join(rootDir, ".env"))
-	if err != nil {
-		log.Println("No .env file found, using default configuration")
-		return cfg
+// applyGeneration applies the test matrix of a project
+func (c *Config) applyGeneration(generation models.GenerationDefaults) {
+	if len(generation.Projects) > 0 {
+		c.TestProjects = append([]string{}, generation.Projects...)
+	}
+	setIfPresent(&c.TestLocale, generation.Locale)
+	setIfPresent(&c.TestTimezone, generation.Timezone)
+	setIfPresent(&c.TestColorScheme, generation.ColorScheme)
+	if generation.RequireAllProjects {
+		c.RequireAllProjects = true
 	}
+}
 
-	if port := envMap["PORT"]; strings.TrimSpace(port) != "" {
-		cfg.Port = port
+// applyEnv applies the variables of an environment layer
+func (c *Config) applyEnv(envMap map[string]string) {
+	setIfPresent(&c.Port, envMap["PORT"])
+	setIfPresent(&c.Environment, envMap["ENVIRONMENT"])
+	setIfPresent(&c.APIKey, envMap["API_KEY"])
+	setIfPresent(&c.SentryAuthToken, envMap["SENTRY_AUTH_TOKEN"])
+	setIfPresent(&c.SentryURL, strings.TrimSuffix(envMap["SENTRY_URL"], "/"))
+	setIfPresent(&c.UmamiURL, envMap["UMAMI_URL"])
+	setIfPresent(&c.UmamiAPIKey, envMap["UMAMI_API_KEY"])
+	setIfPresent(&c.UmamiWebsiteId, envMap["UMAMI_WEBSITE_ID"])
+	setIfPresent(&c.UmamiCacheDir, envMap["UMAMI_CACHE_DIR"])
+	setIfPresent(&c.AnalyticsSource, envMap["ANALYTICS_SOURCE"])
+	setIfPresent(&c.AccessLogPath, envMap["ACCESS_LOG_PATH"])
+	setIfPresent(&c.DataDir, envMap["DATA_DIR"])
+	setIfPresent(&c.LogLevel, envMap["LOG_LEVEL"])
+	setIfPresent(&c.LogFormat, envMap["LOG_FORMAT"])
+	setIfPresent(&c.OTLPEndpoint, envMap["OTEL_EXPORTER_OTLP_ENDPOINT"])
+
+	if allow := envMap["ALLOW_LOCAL_TARGETS"]; strings.TrimSpace(allow) != "" {
+		c.AllowLocalTargets = strings.ToLower(allow) == "true"
 	}
 
-	if env := envMap["ENVIRONMENT"]; strings.TrimSpace(env) != "" {
-		cfg.Environment = env
+	// DEBUG_MODE predates LOG_LEVEL and is still honored
+	if strings.ToLower(envMap["DEBUG_MODE"]) == "true" {
+		c.LogLevel = "debug"
 	}
 
-	if apiKey := envMap["API_KEY"]; strings.TrimSpace(apiKey) != "" {
-		cfg.APIKey = apiKey
+	if timeout := envMap["ACCESS_LOG_SESSION_TIMEOUT"]; strings.TrimSpace(timeout) != "" {
+		if d, err := time.ParseDuration(timeout); err == nil {
+			c.AccessLogSessionTimeout = d
+		} else {
+			logger.Default().Warn("invalid ACCESS_LOG_SESSION_TIMEOUT", "subsystem", "config", "value", timeout, "using", c.AccessLogSessionTimeout)
+		}
 	}
 
-	if sentryAuthToken := envMap["SENTRY_AUTH_TOKEN"]; strings.TrimSpace(sentryAuthToken) != "" {
-		cfg.SentryAuthToken = sentryAuthToken
+	setPositiveDuration(&c.RunnerTimeout, "RUNNER_TIMEOUT", envMap["RUNNER_TIMEOUT"])
+	setPositiveDuration(&c.RunnerInstallTimeout, "RUNNER_INSTALL_TIMEOUT", envMap["RUNNER_INSTALL_TIMEOUT"])
+	setPositiveInt(&c.RunnerMemoryMB, "RUNNER_MEMORY_MB", envMap["RUNNER_MEMORY_MB"])
+	setPositiveInt(&c.RunnerCPUSeconds, "RUNNER_CPU_SECONDS", envMap["RUNNER_CPU_SECONDS"])
+	setPositiveInt(&c.StabilityRuns, "STABILITY_RUNS", envMap["STABILITY_RUNS"])
+	setPositiveInt(&c.StabilityCPUThrottle, "STABILITY_CPU_THROTTLE", envMap["STABILITY_CPU_THROTTLE"])
+	setIfPresent(&c.StabilityMode, envMap["STABILITY_MODE"])
+
+	if projects := splitList(envMap["TEST_PROJECTS"]); len(projects) > 0 {
+		c.TestProjects = projects
+	}
+	setIfPresent(&c.TestLocale, envMap["TEST_LOCALE"])
+	setIfPresent(&c.TestTimezone, envMap["TEST_TIMEZONE"])
+	setIfPresent(&c.TestColorScheme, envMap["TEST_COLOR_SCHEME"])
+	if requireAll := envMap["REQUIRE_ALL_PROJECTS"]; strings.TrimSpace(requireAll) != "" {
+		c.RequireAllProjects = strings.ToLower(requireAll) == "true"
+	}
+	if screenshots := envMap["EVALUATOR_SCREENSHOTS"]; strings.TrimSpace(screenshots) != "" {
+		c.EvaluatorScreenshots = strings.ToLower(screenshots) == "true"
+	}
+	if axe := envMap["AXE_TESTS"]; strings.TrimSpace(axe) != "" {
+		c.AxeTests = strings.ToLower(axe) == "true"
 	}
 
-	if umamiURL := envMap["UMAMI_URL"]; strings.TrimSpace(umamiURL) != "" {
-		cfg.UmamiURL = umamiURL
+	setPositiveInt(&c.ScreenshotBudgetKB, "SCREENSHOT_BUDGET_KB", envMap["SCREENSHOT_BUDGET_KB"])
+	if viewports := envMap["SCREENSHOT_VIEWPORTS"]; strings.TrimSpace(viewports) != "" {
+		if parsed, err := parseViewports(viewports); err == nil {
+			c.ScreenshotViewports = parsed
+		} else {
+			logger.Default().Warn("invalid SCREENSHOT_VIEWPORTS, use name=WIDTHxHEIGHT pairs", "subsystem", "config", "value", viewports, "error", err)
+		}
 	}
 
-	if umamiAPIKey := envMap["UMAMI_API_KEY"]; strings.TrimSpace(umamiAPIKey) != "" {
-		cfg.UmamiAPIKey = umamiAPIKey
+	if threshold := envMap["STABILITY_THRESHOLD"]; strings.TrimSpace(threshold) != "" {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(threshold), 64); err == nil && f >= 0 && f <= 1 {
+			c.StabilityThreshold = f
+		} else {
+			logger.Default().Warn("invalid STABILITY_THRESHOLD, it must be between 0 and 1", "subsystem", "config", "value", threshold, "using", c.StabilityThreshold)
+		}
 	}
+}
 
-	if umamiWebsiteId := envMap["UMAMI_WEBSITE_ID"]; strings.TrimSpace(umamiWebsiteId) != "" {
-		cfg.UmamiWebsiteId = umamiWebsiteId
+// parseViewports parses a comma separated list of name=WIDTHxHEIGHT, viewports narrower than mobileWidth emulate a phone
+func parseViewports(value string) ([]models.Viewport, error) {
+	var viewports []models.Viewport
+	for _, item := range splitList(value) {
+		name, size, ok := strings.Cut(item, "=")
+		width, height, ok2 := strings.Cut(size, "x")
+		w, errW := strconv.Atoi(strings.TrimSpace(width))
+		h, errH := strconv.Atoi(strings.TrimSpace(height))
+		if !ok || !ok2 || errW != nil || errH != nil || w <= 0 || h <= 0 || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid viewport %q", item)
+		}
+		viewports = append(viewports, models.Viewport{Name: strings.TrimSpace(name), Width: w, Height: h, Mobile: w < mobileWidth})
 	}
+	return viewports, nil
+}
 
-	return cfg
+// splitList splits a comma separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func setIfPresent(target *string, value string) {
+	if strings.TrimSpace(value) != "" {
+		*target = value
+	}
+}
+
+func setPositiveDuration(target *time.Duration, name, value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	if d, err := time.ParseDuration(strings.TrimSpace(value)); err == nil && d > 0 {
+		*target = d
+	} else {
+		logger.Default().Warn("invalid "+name, "subsystem", "config", "value", value, "using", *target)
+	}
+}
+
+func setPositiveInt(target *int, name, value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n > 0 {
+		*target = n
+	} else {
+		logger.Default().Warn("invalid "+name, "subsystem", "config", "value", value, "using", *target)
+	}
+}
+
+// readUserConfig reads the user config file, a missing or broken file leaves the layer empty
+func readUserConfig() *models.UserConfig {
+	manager, err := userconfig.NewManager()
+	if err != nil {
+		logger.Default().Warn("couldn't open the user config", "subsystem", "config", "error", err)
+		return nil
+	}
+
+	userConfig, err := manager.GetConfig()
+	if err != nil {
+		logger.Default().Warn("couldn't read the user config", "subsystem", "config", "error", err)
+		return nil
+	}
+	return userConfig
+}
+
+// readDotEnv reads the .env file in the module root
+func readDotEnv() map[string]string {
+	workDir, _ := os.Getwd()
+	rootDir := workDir
+
+	for {
+		if _, err := os.Stat(filepath.Join(rootDir, "go.mod")); err == nil {
+			break
+		}
+		parent := filepath.Dir(rootDir)
+		if parent == rootDir {
+			rootDir = workDir
+			break
+		}
+		rootDir = parent
+	}
+
+	envMap, err := godotenv.Read(filepath.Join(rootDir, ".env"))
+	if err != nil {
+		logger.Default().Debug("no .env file found, using the environment and the user config", "subsystem", "config")
+		return nil
+	}
+	return envMap
+}
+
+func environ() map[string]string {
+	envMap := map[string]string{}
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			envMap[key] = value
+		}
+	}
+	return envMap
 }
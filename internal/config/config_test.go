@@ -0,0 +1,102 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestLoadLayers(t *testing.T) {
+	userConfig := &models.UserConfig{
+		AnthropicApiKey: "file-anthropic",
+		SentryApiKey:    "file-sentry",
+		UmamiAPIKey:     "file-umami",
+		UmamiWebsiteId:  "file-website",
+		CurrentProject:  "shop",
+		Projects: []models.ProjectConfig{
+			{Name: "shop", URL: "https://shop.example.com/", AllowedDomains: []string{"cdn.example.net"},
+				Auth:       models.ProjectAuth{StorageState: "/home/user/shop-auth.json"},
+				Generation: models.GenerationDefaults{Projects: []string{"chromium", "iphone"}, Locale: "de-DE"}},
+		},
+	}
+	dotEnv := map[string]string{
+		"SENTRY_AUTH_TOKEN": "dotenv-sentry",
+		"UMAMI_API_KEY":     "dotenv-umami",
+		"PORT":              "9090",
+	}
+	env := map[string]string{
+		"UMAMI_API_KEY":        "env-umami",
+		"API_KEY":              "  ",
+		"ALLOW_LOCAL_TARGETS":  "true",
+		"RUNNER_MEMORY_MB":     "512",
+		"RUNNER_CPU_SECONDS":   "-1",
+		"TEST_PROJECTS":        "chromium, webkit,",
+		"SCREENSHOT_VIEWPORTS": "wide=1920x1080, phone=375x667",
+	}
+
+	cfg := load(userConfig, dotEnv, env)
+
+	if cfg.APIKey != "file-anthropic" {
+		t.Errorf("Expected the config file key when the environment is blank, got %q", cfg.APIKey)
+	}
+	if cfg.SentryAuthToken != "dotenv-sentry" {
+		t.Errorf("Expected .env to override the config file, got %q", cfg.SentryAuthToken)
+	}
+	if cfg.UmamiAPIKey != "env-umami" {
+		t.Errorf("Expected the environment to override .env, got %q", cfg.UmamiAPIKey)
+	}
+	if cfg.UmamiWebsiteId != "file-website" || cfg.Port != "9090" {
+		t.Errorf("Expected values of lower layers to be kept, got website %q and port %q", cfg.UmamiWebsiteId, cfg.Port)
+	}
+	if !cfg.AllowLocalTargets || len(cfg.AllowedDomains) != 2 || cfg.AllowedDomains[1] != "shop.example.com" {
+		t.Errorf("Expected the target policy of the environment and current project, got %v and %v", cfg.AllowLocalTargets, cfg.AllowedDomains)
+	}
+	if cfg.Project != "shop" || cfg.StorageState != "/home/user/shop-auth.json" {
+		t.Errorf("Expected the name and storage state of the current project, got %q and %q", cfg.Project, cfg.StorageState)
+	}
+	if cfg.RunnerMemoryMB != 512 || cfg.RunnerCPUSeconds != 1200 {
+		t.Errorf("Expected the memory limit of the environment and the default CPU limit, got %d and %d", cfg.RunnerMemoryMB, cfg.RunnerCPUSeconds)
+	}
+	if len(cfg.TestProjects) != 2 || cfg.TestProjects[1] != "webkit" || cfg.TestLocale != "de-DE" {
+		t.Errorf("Expected the projects of the environment and the locale of the current project, got %v and %q", cfg.TestProjects, cfg.TestLocale)
+	}
+	if len(cfg.ScreenshotViewports) != 2 || cfg.ScreenshotViewports[0].Mobile || !cfg.ScreenshotViewports[1].Mobile || cfg.ScreenshotViewports[1].Width != 375 {
+		t.Errorf("Expected a desktop and a phone viewport from the environment, got %+v", cfg.ScreenshotViewports)
+	}
+	if cfg.SentryURL != "https://sentry.io/api/0" {
+		t.Errorf("Expected the default Sentry URL, got %q", cfg.SentryURL)
+	}
+
+	overridden := cfg.With(&models.ConfigOverrides{UmamiAPIKey: "request-umami", SentryApiKey: "request-sentry", SentryURL: "https://sentry.example.com/api/0/"})
+	if overridden.UmamiAPIKey != "request-umami" || overridden.SentryURL != "https://sentry.example.com/api/0" {
+		t.Errorf("Expected overrides to apply, got %q and %q", overridden.UmamiAPIKey, overridden.SentryURL)
+	}
+	if redirected := cfg.With(&models.ConfigOverrides{SentryURL: "https://attacker.example.org", UmamiURL: "https://attacker.example.org"}); redirected.SentryURL != cfg.SentryURL || redirected.UmamiURL != cfg.UmamiURL {
+		t.Errorf("Expected URL overrides without credentials to be ignored, got %q and %q", redirected.SentryURL, redirected.UmamiURL)
+	}
+	if overridden.APIKey != "file-anthropic" {
+		t.Errorf("Expected empty overrides to keep the loaded value, got %q", overridden.APIKey)
+	}
+	if cfg.UmamiAPIKey != "env-umami" {
+		t.Errorf("Expected With not to modify the loaded config, got %q", cfg.UmamiAPIKey)
+	}
+}
+
+func TestProviderReload(t *testing.T) {
+	apiKey := "first"
+	provider := NewProvider(func() *Config {
+		return &Config{APIKey: apiKey}
+	})
+
+	var notified string
+	provider.OnChange(func(cfg *Config) {
+		notified = cfg.APIKey
+	})
+
+	apiKey = "second"
+	provider.Reload()
+
+	if provider.Get().APIKey != "second" || notified != "second" {
+		t.Errorf("Expected the reloaded key to be live and notified, got %q and %q", provider.Get().APIKey, notified)
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import "sync"
+
+// Provider holds the live configuration of a long-running process
+// Clients created per call from Get pick up reloaded values, long-lived clients subscribe with OnChange
+type Provider struct {
+	mutex       sync.RWMutex
+	cfg         *Config
+	load        func() *Config
+	subscribers []func(*Config)
+}
+
+// NewProvider creates a provider with the configuration returned by load
+func NewProvider(load func() *Config) *Provider {
+	return &Provider{cfg: load(), load: load}
+}
+
+// Get returns the current configuration, callers must not modify it
+func (p *Provider) Get() *Config {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.cfg
+}
+
+// OnChange registers a function called with the new configuration after every reload
+func (p *Provider) OnChange(fn func(*Config)) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.subscribers = append(p.subscribers, fn)
+}
+
+// Reload loads the configuration again and notifies the subscribers
+func (p *Provider) Reload() *Config {
+	cfg := p.load()
+
+	p.mutex.Lock()
+	p.cfg = cfg
+	subscribers := append([]func(*Config){}, p.subscribers...)
+	p.mutex.Unlock()
+
+	for _, fn := range subscribers {
+		fn(cfg)
+	}
+	return cfg
+}
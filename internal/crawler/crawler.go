@@ -9,29 +9,24 @@ import (
 	"time"
 
 	"github.com/gocolly/colly/v2"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
 )
 
 func Crawl(ctx context.Context, urlStr string, maxDepth int, maxPathSegments int) ([]string, map[string]string, error) {
+	log := logger.For(ctx, "crawler")
+
 	if urlStr == "" {
 		return nil, nil, errors.New("empty URL provided")
 	}
 
-	parsedURL, err := url.Parse(urlStr)
+	// URLs without a scheme get https
+	policy := netguard.FromContext(ctx)
+	parsedURL, err := policy.CheckString(ctx, urlStr)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		if strings.Contains(parsedURL.String(), "localhost") || strings.Contains(parsedURL.String(), ":") {
-			urlStr = "http://" + urlStr
-		} else {
-			urlStr = "https://" + urlStr
-		}
-		parsedURL, err = url.Parse(urlStr)
-		if err != nil {
-			return nil, nil, err
-		}
-	}
+	urlStr = parsedURL.String()
 
 	basePathSegments := 0
 	trimmedBasePath := strings.Trim(parsedURL.Path, "/")
@@ -56,6 +51,8 @@ func Crawl(ctx context.Context, urlStr string, maxDepth int, maxPathSegments int
 
 	c.SetRequestTimeout(10 * time.Second)
 	c.AllowedDomains = []string{parsedURL.Host}
+	c.WithTransport(policy.Transport())
+	c.SetRedirectHandler(policy.CheckRedirect)
 
 	done := make(chan struct{})
 	go func() {
@@ -83,6 +80,10 @@ func Crawl(ctx context.Context, urlStr string, maxDepth int, maxPathSegments int
 		mutex.Unlock()
 	})
 
+	c.OnError(func(r *colly.Response, err error) {
+		log.Debug("failed to crawl page", "url", r.Request.URL.String(), "status", r.StatusCode, "error", err)
+	})
+
 	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
 		link := e.Attr("href")
 		parsedLink, err := url.Parse(link)
@@ -124,5 +125,6 @@ func Crawl(ctx context.Context, urlStr string, maxDepth int, maxPathSegments int
 
 	c.Wait()
 
+	log.Info("crawled site", "url", urlStr, "pages", len(links))
 	return links, results, nil
 }
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+// Streams of the events, commands write to stdout and stderr, the end of a run is a status event
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+	StreamStatus = "status"
+)
+
+// subscriberBuffer is the number of events a slow subscriber may fall behind before events are dropped for it
+const subscriberBuffer = 256
+
+// Sink receives the events of running jobs, Emit must not block
+type Sink interface {
+	Emit(event models.RunEvent)
+}
+
+type discard struct{}
+
+func (discard) Emit(models.RunEvent) {}
+
+type contextKey struct{}
+
+// NewContext returns a context whose events go to sink
+func NewContext(ctx context.Context, sink Sink) context.Context {
+	return context.WithValue(ctx, contextKey{}, sink)
+}
+
+// FromContext returns the sink of the context, events are dropped without one
+func FromContext(ctx context.Context) Sink {
+	if sink, ok := ctx.Value(contextKey{}).(Sink); ok {
+		return sink
+	}
+	return discard{}
+}
+
+// Emit sends the event to the sink of the context, with the run ID and time of the context filled in
+func Emit(ctx context.Context, event models.RunEvent) {
+	if event.RunID == "" {
+		event.RunID = logger.Correlation(ctx, logger.RunIDKey)
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	FromContext(ctx).Emit(event)
+}
+
+// Hub passes the events of each run to the subscribers of the run
+// Only live events are delivered, nothing is kept for later subscribers
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan models.RunEvent]struct{}
+}
+
+// NewHub creates a hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: map[string]map[chan models.RunEvent]struct{}{}}
+}
+
+// Emit delivers the event to the subscribers of its run, events without a run ID are dropped
+func (h *Hub) Emit(event models.RunEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[event.RunID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the events of a run and a function ending the subscription
+func (h *Hub) Subscribe(runID string) (<-chan models.RunEvent, func()) {
+	ch := make(chan models.RunEvent, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[runID] == nil {
+		h.subscribers[runID] = map[chan models.RunEvent]struct{}{}
+	}
+	h.subscribers[runID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[runID], ch)
+			if len(h.subscribers[runID]) == 0 {
+				delete(h.subscribers, runID)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Middleware sends the events of the jobs of every request to the hub
+func Middleware(hub *Hub) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), hub)))
+		})
+	}
+}
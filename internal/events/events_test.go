@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	ctx := NewContext(logger.WithCorrelation(context.Background(), logger.RunIDKey, "run-1"), hub)
+
+	stream, unsubscribe := hub.Subscribe("run-1")
+	other, unsubscribeOther := hub.Subscribe("run-2")
+	defer unsubscribeOther()
+
+	Emit(ctx, models.RunEvent{Step: "test", Stream: StreamStdout, Line: "1 passed"})
+
+	event := <-stream
+	if event.RunID != "run-1" || event.Line != "1 passed" || event.Time.IsZero() {
+		t.Errorf("Expected the event with the run ID of the context, got %+v", event)
+	}
+	if len(other) != 0 {
+		t.Errorf("Expected no events for other runs, got %d", len(other))
+	}
+
+	unsubscribe()
+	Emit(ctx, models.RunEvent{Step: "test", Stream: StreamStdout, Line: "late"})
+	if len(stream) != 0 {
+		t.Errorf("Expected no events after unsubscribing, got %d", len(stream))
+	}
+}
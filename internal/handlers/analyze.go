@@ -1,16 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/webscopeio/ai-hackathon/internal/config"
 	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
 	"github.com/webscopeio/ai-hackathon/internal/repository/analyzer"
+	"github.com/webscopeio/ai-hackathon/internal/store"
 )
 
-func Analyze(cfg *config.Config, client *llm.Client) http.HandlerFunc {
+func Analyze(provider *config.Provider, client *llm.Client, repo store.Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		args, err := decode[models.AnalyzerArgs](r)
 		if err != nil {
@@ -20,7 +24,27 @@ func Analyze(cfg *config.Config, client *llm.Client) http.HandlerFunc {
 			return
 		}
 
+		cfg, client := requestConfig(provider, client, args.Config)
+
+		r, err = guardTarget(r, cfg, args.Url)
+		if err != nil {
+			encode(w, http.StatusBadRequest, models.ErrorReturn{
+				Error: fmt.Sprintf("URL not allowed, %v", err),
+			})
+			return
+		}
+
+		run, err := store.StartRun(r.Context(), repo, cfg.Project, args.Url, models.RunKindAnalyze)
+		if err != nil {
+			logger.For(r.Context(), "handlers").Error("failed to record run", "error", err)
+		} else {
+			r = r.WithContext(logger.WithCorrelation(r.Context(), logger.RunIDKey, run.ID))
+		}
+
 		res, err := analyzer.Analyze(r.Context(), cfg, client, args.Url, args.Prompt)
+		if run != nil {
+			recordAnalysis(r.Context(), repo, run, res, err)
+		}
 		if err != nil {
 			encode(w, http.StatusInternalServerError, models.ErrorReturn{
 				Error: fmt.Sprintf("Couldn't analyze website, %v", err),
@@ -31,3 +55,24 @@ func Analyze(cfg *config.Config, client *llm.Client) http.HandlerFunc {
 		encode(w, http.StatusOK, res)
 	}
 }
+
+// recordAnalysis stores the outcome of an analysis run, failures are only logged
+func recordAnalysis(ctx context.Context, repo store.Repository, run *models.Run, res *models.AnalyzerReturn, runErr error) {
+	log := logger.For(ctx, "handlers")
+
+	if res != nil {
+		run.TechSpec = res.TechSpec
+		for page := range res.ContentMap {
+			run.Pages = append(run.Pages, page)
+		}
+		sort.Strings(run.Pages)
+
+		if _, err := store.RecordCriteria(ctx, repo, run, models.ParseCriteria(res.Criteria)); err != nil {
+			log.Error("failed to record criteria", "error", err)
+		}
+	}
+
+	if err := store.FinishRun(ctx, repo, run, runErr); err != nil {
+		log.Error("failed to record run", "error", err)
+	}
+}
@@ -2,20 +2,23 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
+	appconfig "github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
 	"github.com/webscopeio/ai-hackathon/internal/repository/config"
 )
 
-// GetConfig handles retrieving the user configuration
+// GetConfig handles retrieving the user configuration, secrets are masked
 func GetConfig() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.For(r.Context(), "handlers")
+
 		// Create config manager
 		configManager, err := config.NewManager()
 		if err != nil {
-			log.Printf("Error creating config manager: %v", err)
+			log.Error("failed to create config manager", "error", err)
 			http.Error(w, "Failed to initialize configuration manager", http.StatusInternalServerError)
 			return
 		}
@@ -23,15 +26,15 @@ func GetConfig() http.HandlerFunc {
 		// Get current configuration
 		userConfig, err := configManager.GetConfig()
 		if err != nil {
-			log.Printf("Error retrieving configuration: %v", err)
+			log.Error("failed to retrieve configuration", "error", err)
 			http.Error(w, "Failed to retrieve configuration", http.StatusInternalServerError)
 			return
 		}
 
 		// Return configuration as JSON
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(userConfig); err != nil {
-			log.Printf("Error encoding response: %v", err)
+		if err := json.NewEncoder(w).Encode(config.Masked(userConfig)); err != nil {
+			log.Error("failed to encode response", "error", err)
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 			return
 		}
@@ -39,12 +42,15 @@ func GetConfig() http.HandlerFunc {
 }
 
 // SaveConfig handles saving or updating the user configuration
-func SaveConfig() http.HandlerFunc {
+// The saved keys are applied to the live config, unless .env or the environment set them
+func SaveConfig(provider *appconfig.Provider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		log := logger.For(r.Context(), "handlers")
+
 		// Parse request body
 		var configUpdate models.UserConfig
 		if err := json.NewDecoder(r.Body).Decode(&configUpdate); err != nil {
-			log.Printf("Error parsing request body: %v", err)
+			log.Error("failed to parse request body", "error", err)
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
@@ -52,22 +58,24 @@ func SaveConfig() http.HandlerFunc {
 		// Create config manager
 		configManager, err := config.NewManager()
 		if err != nil {
-			log.Printf("Error creating config manager: %v", err)
+			log.Error("failed to create config manager", "error", err)
 			http.Error(w, "Failed to initialize configuration manager", http.StatusInternalServerError)
 			return
 		}
 
 		// Update configuration
 		if err := configManager.UpdateConfig(&configUpdate); err != nil {
-			log.Printf("Error updating configuration: %v", err)
+			log.Error("failed to update configuration", "error", err)
 			http.Error(w, "Failed to update configuration", http.StatusInternalServerError)
 			return
 		}
 
+		provider.Reload()
+
 		// Get updated configuration to return
 		updatedConfig, err := configManager.GetConfig()
 		if err != nil {
-			log.Printf("Error retrieving updated configuration: %v", err)
+			log.Error("failed to retrieve updated configuration", "error", err)
 			http.Error(w, "Failed to retrieve updated configuration", http.StatusInternalServerError)
 			return
 		}
@@ -75,15 +83,15 @@ func SaveConfig() http.HandlerFunc {
 		// Return success response
 		w.Header().Set("Content-Type", "application/json")
 		response := struct {
-			Success bool              `json:"success"`
-			Config  *models.UserConfig `json:"config"`
+			Success bool                   `json:"success"`
+			Config  *models.UserConfigView `json:"config"`
 		}{
 			Success: true,
-			Config:  updatedConfig,
+			Config:  config.Masked(updatedConfig),
 		}
 
 		if err := json.NewEncoder(w).Encode(response); err != nil {
-			log.Printf("Error encoding response: %v", err)
+			log.Error("failed to encode response", "error", err)
 			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 			return
 		}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/repository/session_replay"
+	"github.com/webscopeio/ai-hackathon/internal/store"
+)
+
+func FromSession(provider *config.Provider, client *llm.Client, repo store.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		args, err := decode[models.SessionReplayArgs](r)
+		if err != nil {
+			encode(w, http.StatusBadRequest, models.ErrorReturn{
+				Error: fmt.Sprintf("Bad request, %v", err),
+			})
+			return
+		}
+
+		if args.Url == "" || args.SessionID == "" {
+			encode(w, http.StatusBadRequest, models.ErrorReturn{
+				Error: "Bad request, url and sessionId are required",
+			})
+			return
+		}
+
+		cfg, client := requestConfig(provider, client, args.Config)
+
+		r, err = guardTarget(r, cfg, args.Url)
+		if err != nil {
+			encode(w, http.StatusBadRequest, models.ErrorReturn{
+				Error: fmt.Sprintf("URL not allowed, %v", err),
+			})
+			return
+		}
+
+		run, err := store.StartRun(r.Context(), repo, cfg.Project, args.Url, models.RunKindFromSession)
+		if err != nil {
+			logger.For(r.Context(), "handlers").Error("failed to record run", "error", err)
+		} else {
+			r = r.WithContext(logger.WithCorrelation(r.Context(), logger.RunIDKey, run.ID))
+		}
+
+		res, err := session_replay.Replay(r.Context(), cfg, client, args.Url, args.SessionID, 6)
+		if run != nil {
+			recordGenerated(r.Context(), repo, run, res, err)
+		}
+		if err != nil {
+			encode(w, http.StatusInternalServerError, models.ErrorReturn{
+				Error: fmt.Sprintf("Couldn't generate test from session, %v", err),
+			})
+			return
+		}
+
+		// The test content is returned in the response, the temporary path is of no use to clients
+		res.Filename = filepath.Base(res.Filename)
+
+		encode(w, http.StatusOK, res)
+	}
+}
+
+// recordGenerated stores a test generated for a single criterion, failures are only logged
+func recordGenerated(ctx context.Context, repo store.Repository, run *models.Run, res *models.GeneratedTestReturn, runErr error) {
+	log := logger.For(ctx, "handlers")
+
+	if res != nil {
+		run.TechSpec = res.TechSpec
+		run.Pages = res.Pages
+
+		criteria, err := store.RecordCriteria(ctx, repo, run, []models.TestCriterion{res.Criterion})
+		if err != nil {
+			log.Error("failed to record criteria", "error", err)
+		} else if err := store.RecordGenEval(ctx, repo, run, criteria[0].ID, filepath.Base(res.Filename), res.Result); err != nil {
+			log.Error("failed to record generated test", "error", err)
+		}
+	}
+
+	if err := store.FinishRun(ctx, repo, run, runErr); err != nil {
+		log.Error("failed to record run", "error", err)
+	}
+}
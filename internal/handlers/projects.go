@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	appconfig "github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/repository/config"
+)
+
+// ListProjects handles listing the configured projects
+func ListProjects() http.HandlerFunc {
+	return withConfigManager(func(w http.ResponseWriter, r *http.Request, manager *config.Manager) {
+		projects, err := manager.ListProjects()
+		if err != nil {
+			encodeProjectError(w, "Couldn't list projects", err)
+			return
+		}
+
+		encode(w, http.StatusOK, projects)
+	})
+}
+
+// CreateProject handles adding a project
+// Changes to the current project are applied to the live config
+func CreateProject(provider *appconfig.Provider) http.HandlerFunc {
+	return withConfigManager(func(w http.ResponseWriter, r *http.Request, manager *config.Manager) {
+		project, err := decode[models.ProjectConfig](r)
+		if err != nil || project.Name == "" {
+			encode(w, http.StatusBadRequest, models.ErrorReturn{
+				Error: "Bad request, a project with a name is required",
+			})
+			return
+		}
+
+		if err := manager.AddProject(project); err != nil {
+			encodeProjectError(w, "Couldn't create project", err)
+			return
+		}
+		provider.Reload()
+
+		encode(w, http.StatusCreated, project)
+	})
+}
+
+// GetProject handles retrieving a project by name
+func GetProject() http.HandlerFunc {
+	return withConfigManager(func(w http.ResponseWriter, r *http.Request, manager *config.Manager) {
+		project, err := manager.GetProject(chi.URLParam(r, "name"))
+		if err != nil {
+			encodeProjectError(w, "Couldn't get project", err)
+			return
+		}
+
+		encode(w, http.StatusOK, *project)
+	})
+}
+
+// UpdateProject handles replacing the settings of a project
+func UpdateProject(provider *appconfig.Provider) http.HandlerFunc {
+	return withConfigManager(func(w http.ResponseWriter, r *http.Request, manager *config.Manager) {
+		project, err := decode[models.ProjectConfig](r)
+		if err != nil {
+			encode(w, http.StatusBadRequest, models.ErrorReturn{
+				Error: fmt.Sprintf("Bad request, %v", err),
+			})
+			return
+		}
+
+		name := chi.URLParam(r, "name")
+		if project.Name == "" {
+			project.Name = name
+		}
+
+		if err := manager.UpdateProject(name, project); err != nil {
+			encodeProjectError(w, "Couldn't update project", err)
+			return
+		}
+		provider.Reload()
+
+		encode(w, http.StatusOK, project)
+	})
+}
+
+// DeleteProject handles removing a project
+func DeleteProject(provider *appconfig.Provider) http.HandlerFunc {
+	return withConfigManager(func(w http.ResponseWriter, r *http.Request, manager *config.Manager) {
+		if err := manager.DeleteProject(chi.URLParam(r, "name")); err != nil {
+			encodeProjectError(w, "Couldn't delete project", err)
+			return
+		}
+		provider.Reload()
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// UseProject handles making a project the current one
+func UseProject(provider *appconfig.Provider) http.HandlerFunc {
+	return withConfigManager(func(w http.ResponseWriter, r *http.Request, manager *config.Manager) {
+		name := chi.URLParam(r, "name")
+		if err := manager.UseProject(name); err != nil {
+			encodeProjectError(w, "Couldn't use project", err)
+			return
+		}
+		provider.Reload()
+
+		project, err := manager.GetProject(name)
+		if err != nil {
+			encodeProjectError(w, "Couldn't get project", err)
+			return
+		}
+
+		encode(w, http.StatusOK, *project)
+	})
+}
+
+func withConfigManager(handler func(w http.ResponseWriter, r *http.Request, manager *config.Manager)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		manager, err := config.NewManager()
+		if err != nil {
+			encode(w, http.StatusInternalServerError, models.ErrorReturn{
+				Error: fmt.Sprintf("Failed to initialize configuration manager, %v", err),
+			})
+			return
+		}
+
+		handler(w, r, manager)
+	}
+}
+
+func encodeProjectError(w http.ResponseWriter, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, config.ErrProjectNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, config.ErrProjectExists):
+		status = http.StatusConflict
+	}
+	encode(w, status, models.ErrorReturn{
+		Error: fmt.Sprintf("%s, %v", message, err),
+	})
+}
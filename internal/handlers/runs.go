@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/webscopeio/ai-hackathon/internal/events"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/store"
+)
+
+// ListRuns handles listing runs, optionally of a single project with ?project=<name>
+func ListRuns(repo store.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runs, err := repo.ListRuns(r.Context(), r.URL.Query().Get("project"))
+		if err != nil {
+			encode(w, http.StatusInternalServerError, models.ErrorReturn{
+				Error: fmt.Sprintf("Couldn't list runs, %v", err),
+			})
+			return
+		}
+
+		encode(w, http.StatusOK, runs)
+	}
+}
+
+// GetRun handles retrieving a run with its criteria, test versions and results
+func GetRun(repo store.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		details, err := store.Details(r.Context(), repo, chi.URLParam(r, "id"))
+		if err != nil {
+			encodeStoreError(w, "Couldn't get run", err)
+			return
+		}
+
+		encode(w, http.StatusOK, details)
+	}
+}
+
+// CompareRuns handles comparing two runs given as ?base= and ?head=
+func CompareRuns(repo store.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		baseID, headID := r.URL.Query().Get("base"), r.URL.Query().Get("head")
+		if baseID == "" || headID == "" {
+			encode(w, http.StatusBadRequest, models.ErrorReturn{
+				Error: "Bad request, base and head run IDs are required",
+			})
+			return
+		}
+
+		base, err := store.Details(r.Context(), repo, baseID)
+		if err != nil {
+			encodeStoreError(w, "Couldn't get base run", err)
+			return
+		}
+
+		head, err := store.Details(r.Context(), repo, headID)
+		if err != nil {
+			encodeStoreError(w, "Couldn't get head run", err)
+			return
+		}
+
+		encode(w, http.StatusOK, store.Compare(base, head))
+	}
+}
+
+// RunEvents streams the output of a running run as server-sent events
+// The stream ends with a status event when the run finishes, finished runs get 409
+func RunEvents(repo store.Repository, hub *events.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runID := chi.URLParam(r, "id")
+		run, err := repo.GetRun(r.Context(), runID)
+		if err != nil {
+			encodeStoreError(w, "Couldn't get run", err)
+			return
+		}
+		if run.Status != models.RunStatusRunning {
+			encode(w, http.StatusConflict, models.ErrorReturn{
+				Error: fmt.Sprintf("Run is %s, its output is in the test results", run.Status),
+			})
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			encode(w, http.StatusInternalServerError, models.ErrorReturn{
+				Error: "Streaming isn't supported",
+			})
+			return
+		}
+
+		stream, unsubscribe := hub.Subscribe(runID)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event := <-stream:
+				data, _ := json.Marshal(event)
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Stream, data)
+				flusher.Flush()
+				if event.Stream == events.StreamStatus {
+					return
+				}
+			}
+		}
+	}
+}
+
+// RunArtifact serves a trace, screenshot or video collected in a run, e.g. /runs/{id}/artifacts/{versionId}/test-failed-1.png
+// The files come from the tests, so browsers must neither sniff their type nor run their scripts
+func RunArtifact(repo store.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runID, name := chi.URLParam(r, "id"), chi.URLParam(r, "*")
+		file, err := repo.OpenArtifact(r.Context(), runID, name)
+		if err != nil {
+			encodeStoreError(w, "Couldn't get artifact", err)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		http.ServeContent(w, r, path.Base(name), time.Time{}, file)
+	}
+}
+
+func encodeStoreError(w http.ResponseWriter, message string, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, store.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	encode(w, status, models.ErrorReturn{
+		Error: fmt.Sprintf("%s, %v", message, err),
+	})
+}
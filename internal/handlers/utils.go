@@ -3,6 +3,13 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+	"github.com/webscopeio/ai-hackathon/internal/repository/gen_eval_loop"
+	"github.com/webscopeio/ai-hackathon/internal/runner"
 )
 
 func encode[T any](w http.ResponseWriter, statusCode int, payload T) {
@@ -19,3 +26,27 @@ func decode[T any](r *http.Request) (T, error) {
 
 	return v, nil
 }
+
+// requestConfig applies the per-request overrides to the live config
+// A request with its own Anthropic API key gets its own LLM client
+func requestConfig(provider *config.Provider, client *llm.Client, overrides *models.ConfigOverrides) (*config.Config, *llm.Client) {
+	cfg := provider.Get().With(overrides)
+	if overrides != nil && overrides.AnthropicApiKey != "" {
+		return cfg, llm.New(cfg)
+	}
+	return cfg, client
+}
+
+// guardTarget checks the target URL against the outbound URL policy of cfg
+// The returned request carries the policy, so everything fetched for it is checked too,
+// the sandbox generated tests run in, their stabilization and their project matrix
+func guardTarget(r *http.Request, cfg *config.Config, target string) (*http.Request, error) {
+	policy := netguard.ForConfig(cfg)
+	ctx := netguard.NewContext(r.Context(), policy)
+	ctx = runner.NewContext(ctx, runner.ForConfig(cfg))
+	ctx = gen_eval_loop.WithConfig(ctx, cfg)
+	if _, err := policy.CheckString(ctx, target); err != nil {
+		return r, err
+	}
+	return r.WithContext(ctx), nil
+}
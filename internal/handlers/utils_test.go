@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestRequestConfigOverrides(t *testing.T) {
+	provider := config.NewProvider(func() *config.Config {
+		return &config.Config{
+			SentryAuthToken: "stored-sentry",
+			SentryURL:       "https://sentry.io/api/0",
+			UmamiAPIKey:     "stored-umami",
+			UmamiURL:        "https://api.umami.is/v1",
+		}
+	})
+
+	// Any member can send overrides, the stored credentials must stay with the configured hosts
+	cfg, _ := requestConfig(provider, nil, &models.ConfigOverrides{
+		SentryURL: "https://attacker.example.org/api/0",
+		UmamiURL:  "https://attacker.example.org/v1",
+	})
+	if cfg.SentryURL != "https://sentry.io/api/0" || cfg.UmamiURL != "https://api.umami.is/v1" {
+		t.Errorf("Expected URL overrides without credentials to be ignored, got %q and %q", cfg.SentryURL, cfg.UmamiURL)
+	}
+
+	cfg, _ = requestConfig(provider, nil, &models.ConfigOverrides{
+		SentryURL:    "https://sentry.example.com/api/0",
+		SentryApiKey: "request-sentry",
+		UmamiURL:     "https://umami.example.com/v1",
+		UmamiAPIKey:  "request-umami",
+	})
+	if cfg.SentryURL != "https://sentry.example.com/api/0" || cfg.SentryAuthToken != "request-sentry" {
+		t.Errorf("Expected the Sentry override with its own token, got %q and %q", cfg.SentryURL, cfg.SentryAuthToken)
+	}
+	if cfg.UmamiURL != "https://umami.example.com/v1" || cfg.UmamiAPIKey != "request-umami" {
+		t.Errorf("Expected the Umami override with its own key, got %q and %q", cfg.UmamiURL, cfg.UmamiAPIKey)
+	}
+}
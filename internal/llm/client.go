@@ -1,12 +1,15 @@
 package llm
 
 import (
+	"sync"
+
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/webscopeio/ai-hackathon/internal/config"
 )
 
 type Client struct {
+	mutex        sync.RWMutex
 	client       *anthropic.Client
 	systemPrompt string
 	apiKey       string
@@ -24,8 +27,22 @@ func New(cfg *config.Config) *Client {
 }
 
 // UpdateAPIKey updates the API key and recreates the client with the new key
+// Requests already in flight keep using the previous key
 func (c *Client) UpdateAPIKey(apiKey string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if apiKey == c.apiKey {
+		return
+	}
 	c.apiKey = apiKey
 	client := anthropic.NewClient(option.WithAPIKey(apiKey))
 	c.client = &client
 }
+
+// api returns the Anthropic client for the current API key
+func (c *Client) api() *anthropic.Client {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.client
+}
@@ -9,7 +9,7 @@ import (
 )
 
 func (c *Client) GetCompletion(ctx context.Context, prompt string) (string, error) {
-	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+	message, err := c.NewMessage(ctx, anthropic.MessageNewParams{
 		Model:     anthropic.ModelClaude3_5HaikuLatest,
 		MaxTokens: 4096,
 		System: []anthropic.TextBlockParam{
@@ -67,7 +67,7 @@ func (c *Client) GetStructuredCompletion(
 
 	messages := append(prevMessages, anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)))
 
-	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
+	message, err := c.NewMessage(ctx, anthropic.MessageNewParams{
 		Model: anthropic.ModelClaude3_5SonnetLatest,
 		// INFO: tools typically require more tokens
 		MaxTokens: 2400,
@@ -1,15 +1,40 @@
 package llm
 
 import (
+	"time"
+
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/webscopeio/ai-hackathon/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/net/context"
 )
 
+// NewMessage sends the message in a span and records its latency and token usage
+// The phase of the context labels the metrics, see telemetry.WithPhase
 func (c *Client) NewMessage(
 	ctx context.Context,
 	body anthropic.MessageNewParams,
 	opts ...option.RequestOption,
 ) (res *anthropic.Message, err error) {
-	return c.client.Messages.New(ctx, body, opts...)
+	model := string(body.Model)
+	ctx, span := telemetry.StartSpan(ctx, "llm.message",
+		attribute.String("llm.model", model),
+		attribute.String("llm.phase", telemetry.Phase(ctx)),
+	)
+
+	start := time.Now()
+	res, err = c.api().Messages.New(ctx, body, opts...)
+
+	var inputTokens, outputTokens int64
+	if res != nil {
+		inputTokens, outputTokens = res.Usage.InputTokens, res.Usage.OutputTokens
+		span.SetAttributes(
+			attribute.Int64("llm.input_tokens", inputTokens),
+			attribute.Int64("llm.output_tokens", outputTokens),
+		)
+	}
+	telemetry.ObserveLLMCall(ctx, model, time.Since(start), inputTokens, outputTokens, err)
+	telemetry.EndSpan(span, err)
+	return res, err
 }
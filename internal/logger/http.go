@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Middleware gives every request a job ID and a logger carrying it, then logs the response
+// The job ID is the request ID set by middleware.RequestID, or a new ID without it
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jobID := middleware.GetReqID(r.Context())
+		if jobID == "" {
+			jobID = NewID()
+		}
+		ctx := WithCorrelation(r.Context(), JobIDKey, jobID)
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		For(ctx, "http").Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", ww.Status(),
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start),
+		)
+	})
+}
@@ -1,40 +1,118 @@
 package logger
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
+)
+
+// Correlation ID attributes carried through the context
+const (
+	JobIDKey       = "job_id"
+	RunIDKey       = "run_id"
+	CriterionIDKey = "criterion_id"
+	TokenIDKey     = "token_id"
+	subsystemKey   = "subsystem"
+)
 
-	"github.com/joho/godotenv"
+type contextKey struct{}
+
+type correlationKey string
+
+var (
+	mutex   sync.RWMutex
+	level   = new(slog.LevelVar)
+	current *slog.Logger
 )
 
-// Debug prints a debug message if debug mode is enabled
-// Debug mode is enabled if the DEBUG_MODE environment variable is set to "true"
-// or if it's defined as DEBUG_MODE=true in a .env file in the current directory
-func Debug(format string, args ...interface{}) {
-	// Check if debug mode is enabled
-	if isDebugModeEnabled() {
-		log.Printf("[DEBUG] "+format, args...)
+func init() {
+	// DEBUG_MODE is kept for existing setups, LOG_LEVEL and LOG_FORMAT are applied by Configure
+	if strings.ToLower(os.Getenv("DEBUG_MODE")) == "true" {
+		level.Set(slog.LevelDebug)
+	}
+	setOutput(os.Stderr, "text")
+}
+
+// Configure sets the level ("debug", "info", "warn" or "error") and format ("text" or "json") of the logger
+// Invalid values keep the current setting
+func Configure(levelName, format string) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(levelName)); err == nil {
+		level.Set(l)
+	}
+	if format != "" {
+		setOutput(os.Stderr, format)
 	}
 }
 
-// isDebugModeEnabled checks if debug mode is enabled via environment variable
-// or .env file
-func isDebugModeEnabled() bool {
-	// First check environment variable
-	debugMode := os.Getenv("DEBUG_MODE")
-	if strings.ToLower(debugMode) == "true" {
-		return true
+// setOutput replaces the handler, log package output is routed through it too
+func setOutput(w io.Writer, format string) {
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if strings.ToLower(format) == "json" {
+		handler = slog.NewJSONHandler(Writer(w), options)
+	} else {
+		handler = slog.NewTextHandler(Writer(w), options)
 	}
 
-	// Then check .env file if it exists
-	envMap, err := godotenv.Read()
-	if err == nil {
-		// Check if DEBUG_MODE is set to true in .env file
-		if value, exists := envMap["DEBUG_MODE"]; exists && strings.ToLower(value) == "true" {
-			return true
+	l := slog.New(handler)
+	mutex.Lock()
+	current = l
+	mutex.Unlock()
+	slog.SetDefault(l)
+}
+
+// Default returns the process-wide logger
+func Default() *slog.Logger {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return current
+}
+
+// NewContext returns a context carrying l
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of the context, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return l
 		}
 	}
+	return Default()
+}
+
+// For returns the child logger of a subsystem, carrying the correlation IDs of the context
+func For(ctx context.Context, subsystem string) *slog.Logger {
+	return FromContext(ctx).With(subsystemKey, subsystem)
+}
+
+// WithCorrelation returns a context whose logger adds the correlation ID to every record
+func WithCorrelation(ctx context.Context, key, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, correlationKey(key), id)
+	return NewContext(ctx, FromContext(ctx).With(key, id))
+}
+
+// Correlation returns the correlation ID of the context, or an empty string
+func Correlation(ctx context.Context, key string) string {
+	id, _ := ctx.Value(correlationKey(key)).(string)
+	return id
+}
 
-	return false
+// NewID returns a random correlation ID
+func NewID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
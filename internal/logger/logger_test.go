@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestCorrelation(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	ctx := NewContext(context.Background(), base)
+	ctx = WithCorrelation(ctx, JobIDKey, "job-1")
+	ctx = WithCorrelation(ctx, RunIDKey, "run-1")
+	ctx = WithCorrelation(ctx, CriterionIDKey, "")
+
+	For(ctx, "analyzer").Info("analyzed", "pages", 3)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q: %v", buf.String(), err)
+	}
+	if record[JobIDKey] != "job-1" || record[RunIDKey] != "run-1" || record[subsystemKey] != "analyzer" {
+		t.Errorf("Expected the correlation IDs and subsystem, got %v", record)
+	}
+	if Correlation(ctx, RunIDKey) != "run-1" {
+		t.Errorf("Expected the run ID in the context, got %q", Correlation(ctx, RunIDKey))
+	}
+	if _, ok := record[CriterionIDKey]; ok {
+		t.Errorf("Expected empty correlation IDs to be left out, got %v", record)
+	}
+}
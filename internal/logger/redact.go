@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	redacted = "[REDACTED]"
+	// minSecretLength keeps short values from redacting unrelated output
+	minSecretLength = 6
+)
+
+var (
+	secretsMutex sync.RWMutex
+	secretValues []string
+
+	// headerPattern matches the values of auth headers in dumped requests and header maps
+	headerPattern = regexp.MustCompile(`(?i)(authorization|x-umami-api-key)(["']?\s*[:=]\s*\[?["']?)(?:bearer\s+)?[^\s"',;\]]+`)
+)
+
+// RegisterSecrets adds values that are scrubbed from all log output
+func RegisterSecrets(values ...string) {
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) < minSecretLength || containsString(secretValues, value) {
+			continue
+		}
+		secretValues = append(secretValues, value)
+	}
+
+	// Longer secrets go first so a secret containing another one is scrubbed whole
+	sort.Slice(secretValues, func(i, j int) bool {
+		return len(secretValues[i]) > len(secretValues[j])
+	})
+}
+
+// Redact scrubs the registered secrets and auth header values from s
+func Redact(s string) string {
+	s = headerPattern.ReplaceAllString(s, "${1}${2}"+redacted)
+
+	secretsMutex.RLock()
+	defer secretsMutex.RUnlock()
+	for _, secret := range secretValues {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+// Writer wraps w so everything written to it is redacted
+func Writer(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	RegisterSecrets("sk-ant-api03-secret", "tiny")
+
+	var buf bytes.Buffer
+	l := log.New(Writer(&buf), "", 0)
+	l.Printf("calling with key %s", "sk-ant-api03-secret")
+	l.Printf("Authorization: Bearer sntrys_token123")
+	l.Printf("headers map[Content-Type:[application/json] X-Umami-Api-Key:[umami-key]]")
+	l.Printf(`{"x-umami-api-key": "umami-key", "tiny": true}`)
+
+	output := buf.String()
+	for _, leaked := range []string{"sk-ant-api03-secret", "sntrys_token123", "umami-key"} {
+		if strings.Contains(output, leaked) {
+			t.Errorf("Expected %q to be redacted, got:\n%s", leaked, output)
+		}
+	}
+	if !strings.Contains(output, "application/json") || !strings.Contains(output, "tiny") {
+		t.Errorf("Expected other values and short secrets to be kept, got:\n%s", output)
+	}
+}
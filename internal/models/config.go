@@ -1,11 +1,69 @@
 package models
 
 // UserConfig represents the user configuration for the test generation
+// API keys are shared by all projects. The single-project fields are kept for the
+// /config API, they mirror the current project and aren't stored on their own.
 type UserConfig struct {
-	AnthropicApiKey       string `json:"anthropicApiKey" yaml:"anthropicApiKey"`
-	SentryApiKey          string `json:"sentryApiKey" yaml:"sentryApiKey"`
-	UmamiAPIKey           string `json:"umamiAPIKey" yaml:"umamiAPIKey"`
-	UmamiWebsiteId        string `json:"umamiWebsiteId" yaml:"umamiWebsiteId"`
-	TechSpecification     string `json:"techSpecification" yaml:"techSpecification"`
-	ProductSpecification  string `json:"productSpecification" yaml:"productSpecification"`
+	AnthropicApiKey      string          `json:"anthropicApiKey" yaml:"anthropicApiKey"`
+	SentryApiKey         string          `json:"sentryApiKey" yaml:"sentryApiKey"`
+	UmamiAPIKey          string          `json:"umamiAPIKey" yaml:"umamiAPIKey"`
+	UmamiWebsiteId       string          `json:"umamiWebsiteId" yaml:"umamiWebsiteId,omitempty"`
+	TechSpecification    string          `json:"techSpecification" yaml:"techSpecification,omitempty"`
+	ProductSpecification string          `json:"productSpecification" yaml:"productSpecification,omitempty"`
+	CurrentProject       string          `json:"currentProject" yaml:"currentProject,omitempty"`
+	Projects             []ProjectConfig `json:"projects" yaml:"projects,omitempty"`
+}
+
+// UserConfigView is the user configuration returned by the API
+// Secrets are masked, the Set flags tell whether they are configured
+type UserConfigView struct {
+	UserConfig
+	AnthropicApiKeySet bool `json:"anthropicApiKeySet"`
+	SentryApiKeySet    bool `json:"sentryApiKeySet"`
+	UmamiAPIKeySet     bool `json:"umamiAPIKeySet"`
+}
+
+// ProjectConfig represents the settings of a single target app
+// AllowedDomains limits the hosts its jobs may reach, the project URL is always allowed
+type ProjectConfig struct {
+	Name                 string             `json:"name" yaml:"name"`
+	URL                  string             `json:"url" yaml:"url"`
+	TechSpecification    string             `json:"techSpecification" yaml:"techSpecification,omitempty"`
+	ProductSpecification string             `json:"productSpecification" yaml:"productSpecification,omitempty"`
+	SentryOrg            string             `json:"sentryOrg" yaml:"sentryOrg,omitempty"`
+	SentryProject        string             `json:"sentryProject" yaml:"sentryProject,omitempty"`
+	UmamiWebsiteId       string             `json:"umamiWebsiteId" yaml:"umamiWebsiteId,omitempty"`
+	AllowedDomains       []string           `json:"allowedDomains" yaml:"allowedDomains,omitempty"`
+	Auth                 ProjectAuth        `json:"auth" yaml:"auth,omitempty"`
+	Generation           GenerationDefaults `json:"generation" yaml:"generation,omitempty"`
+}
+
+// ProjectAuth holds how generated tests sign in to the target app
+type ProjectAuth struct {
+	// StorageState is a Playwright storage state file with an already signed-in session, generated tests start with it
+	StorageState string `json:"storageState" yaml:"storageState,omitempty"`
+}
+
+// GenerationDefaults holds the test generation settings of a project, zero values use the built-in defaults
+type GenerationDefaults struct {
+	// Loops is the maximum number of gen-eval iterations per test
+	Loops int `json:"loops" yaml:"loops,omitempty"`
+	// Projects are the browser and device projects generated tests run on, e.g. chromium, webkit or iphone
+	Projects    []string `json:"projects" yaml:"projects,omitempty"`
+	Locale      string   `json:"locale" yaml:"locale,omitempty"`
+	Timezone    string   `json:"timezone" yaml:"timezone,omitempty"`
+	ColorScheme string   `json:"colorScheme" yaml:"colorScheme,omitempty"`
+	// RequireAllProjects only accepts tests passing on every project, otherwise the first project decides
+	RequireAllProjects bool `json:"requireAllProjects" yaml:"requireAllProjects,omitempty"`
+}
+
+// ConfigOverrides holds settings that replace the loaded configuration for a single command or request
+// Empty fields keep the loaded value
+type ConfigOverrides struct {
+	AnthropicApiKey string `json:"anthropicApiKey,omitempty"`
+	SentryApiKey    string `json:"sentryApiKey,omitempty"`
+	SentryURL       string `json:"sentryUrl,omitempty"`
+	UmamiAPIKey     string `json:"umamiAPIKey,omitempty"`
+	UmamiURL        string `json:"umamiUrl,omitempty"`
+	UmamiWebsiteId  string `json:"umamiWebsiteId,omitempty"`
 }
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// CoverageReport maps what the generated tests exercise against the known pages, flows and errors
+type CoverageReport struct {
+	BaseURL     string           `json:"baseUrl"`
+	GeneratedAt time.Time        `json:"generatedAt"`
+	Tests       []TestCoverage   `json:"tests"`
+	Pages       []CoverageTarget `json:"pages"`
+	Flows       []CoverageTarget `json:"flows"`
+	SentryPaths []CoverageTarget `json:"sentryPaths"`
+	Gaps        []CoverageTarget `json:"gaps"`
+}
+
+// TestCoverage holds what a single test visited and interacted with, taken from its trace
+type TestCoverage struct {
+	Test      string   `json:"test"`
+	URLs      []string `json:"urls"`
+	Selectors []string `json:"selectors"`
+}
+
+// CoverageTarget is a page, significant flow or Sentry-affected path checked for coverage
+// Weight is the flow frequency or the Sentry event count, and 0 for sitemap pages
+type CoverageTarget struct {
+	Kind      string   `json:"kind"`
+	Target    string   `json:"target"`
+	Weight    int      `json:"weight,omitempty"`
+	Covered   bool     `json:"covered"`
+	CoveredBy []string `json:"coveredBy,omitempty"`
+}
+
+const (
+	CoverageKindPage   = "page"
+	CoverageKindFlow   = "flow"
+	CoverageKindSentry = "sentry"
+)
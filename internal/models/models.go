@@ -1,9 +1,11 @@
 package models
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // Sitemap represents the XML structure of a sitemap
@@ -96,7 +98,7 @@ type SentryEvent struct {
 	Title     string                   `json:"title"`
 	Message   string                   `json:"message"`
 	Timestamp string                   `json:"dateCreated"`
-	Tags      [][]string               `json:"tags"`
+	Tags      []SentryEventTag         `json:"tags"`
 	Platform  string                   `json:"platform"`
 	User      map[string]interface{}   `json:"user,omitempty"`
 	Contexts  map[string]interface{}   `json:"contexts,omitempty"`
@@ -104,6 +106,44 @@ type SentryEvent struct {
 	Metadata  Metadata                 `json:"metadata"`
 }
 
+// SentryEventTag represents a single tag of a Sentry event
+type SentryEventTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// UnmarshalJSON accepts both the {"key", "value"} object form returned by the API
+// and the ["key", "value"] pair form used in raw event payloads
+func (t *SentryEventTag) UnmarshalJSON(data []byte) error {
+	var pair []string
+	if err := json.Unmarshal(data, &pair); err == nil {
+		if len(pair) != 2 {
+			return fmt.Errorf("invalid tag pair: %s", string(data))
+		}
+		t.Key, t.Value = pair[0], pair[1]
+		return nil
+	}
+
+	type tag SentryEventTag
+	return json.Unmarshal(data, (*tag)(t))
+}
+
+// SentryException represents an exception captured in a Sentry event
+type SentryException struct {
+	Type   string             `json:"type"`
+	Value  string             `json:"value"`
+	Frames []SentryStackFrame `json:"frames"`
+}
+
+// SentryStackFrame represents a single frame of an exception stack trace
+type SentryStackFrame struct {
+	Filename string `json:"filename"`
+	Function string `json:"function"`
+	LineNo   int    `json:"lineNo"`
+	ColNo    int    `json:"colNo"`
+	InApp    bool   `json:"inApp"`
+}
+
 // SentryTagValue represents a single tag value with its metadata
 type SentryTagValue struct {
 	Value     string `json:"value"`
@@ -138,6 +178,22 @@ type SentryIssuesResponse struct {
 	Issues []SentryIssue `json:"issues"`
 }
 
+// SentryBreadcrumb represents a single breadcrumb recorded before a Sentry event
+type SentryBreadcrumb struct {
+	Timestamp string                 `json:"timestamp"`
+	Type      string                 `json:"type"`
+	Category  string                 `json:"category"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// SentryIssueWithBreadcrumbs extends SentryIssue with the breadcrumbs of its latest event
+type SentryIssueWithBreadcrumbs struct {
+	SentryIssue
+	Breadcrumbs []SentryBreadcrumb `json:"breadcrumbs,omitempty"`
+}
+
 // SentryIssueHash represents a hash for a specific issue
 type SentryIssueHash struct {
 	ID          string `json:"id"`
@@ -199,9 +255,77 @@ type GetContentToolReturn struct {
 	Contents map[string]string `json:"contents"`
 }
 
+type GetScreenshotTool struct {
+	Urls     []string `json:"urls" jsonschema_description:"Array of the URLs to take screenshots of"`
+	FullPage bool     `json:"fullPage,omitempty" jsonschema_description:"Also capture the whole page below the fold, not only the part visible without scrolling"`
+}
+
+// Viewport is a browser window size screenshots are taken at
+type Viewport struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Mobile bool   `json:"mobile,omitempty"`
+}
+
+// PageScreenshot is a JPEG screenshot of a page, Kind is above_the_fold or full_page
+type PageScreenshot struct {
+	URL      string `json:"url"`
+	Viewport string `json:"viewport"`
+	Kind     string `json:"kind"`
+	Bytes    int    `json:"bytes"`
+	Data     []byte `json:"-"`
+}
+
+// GetScreenshotToolReturn holds the screenshots within the image budget and the ones left out
+type GetScreenshotToolReturn struct {
+	Screenshots []PageScreenshot `json:"screenshots"`
+	Skipped     []string         `json:"skipped,omitempty"`
+}
+
+type GetA11yAuditTool struct {
+	Urls []string `json:"urls" jsonschema_description:"Array of the URLs to audit for accessibility"`
+}
+
+// A11y rules of the accessibility audit
+const (
+	A11yMissingLabel   = "missing_label"
+	A11yContrast       = "contrast"
+	A11yLandmarks      = "landmarks"
+	A11yDuplicateID    = "duplicate_id"
+	A11yKeyboardTrap   = "keyboard_trap"
+	A11yImpactCritical = "critical"
+	A11yImpactSerious  = "serious"
+	A11yImpactModerate = "moderate"
+)
+
+// A11yViolation is an accessibility problem of an element, Selector is empty for problems of the whole page
+type A11yViolation struct {
+	Rule     string `json:"rule"`
+	Impact   string `json:"impact"`
+	Selector string `json:"selector,omitempty"`
+	Message  string `json:"message"`
+}
+
+// PageA11yAudit holds the accessibility violations found on a page
+type PageA11yAudit struct {
+	URL        string          `json:"url"`
+	Violations []A11yViolation `json:"violations"`
+}
+
+// GetA11yAuditToolReturn holds the audited pages and the ones that couldn't be audited
+type GetA11yAuditToolReturn struct {
+	Pages   []PageA11yAudit `json:"pages"`
+	Skipped []string        `json:"skipped,omitempty"`
+}
+
 type SentryTool struct {
 	OrgSlug     string `json:"orgSlug" jsonschema_description:"The Sentry organization slug"`
 	ProjectSlug string `json:"projectSlug" jsonschema_description:"The Sentry project slug"`
+	Period      string `json:"period,omitempty" jsonschema_description:"Stats period of the issues, e.g. 24h, 14d or 90d (defaults to 14d)"`
+	Query       string `json:"query,omitempty" jsonschema_description:"Sentry search query (defaults to is:unresolved)"`
+	Environment string `json:"environment,omitempty" jsonschema_description:"Only include issues from this environment, e.g. production"`
+	Release     string `json:"release,omitempty" jsonschema_description:"Only include issues seen in this release"`
 }
 
 type FinalCriteriaTool struct {
@@ -214,14 +338,82 @@ type AnalyzerToolExample struct {
 }
 
 type AnalyzerArgs struct {
-	Url    string `json:"url"`
-	Prompt string `json:"prompt"`
+	Url    string           `json:"url"`
+	Prompt string           `json:"prompt"`
+	Config *ConfigOverrides `json:"config,omitempty"`
 }
 
 type AnalyzerReturn struct {
 	TechSpec   string            `json:"techSpec"`
 	ContentMap map[string]string `json:"siteMap"`
 	Criteria   string            `json:"criteria"`
+	// BaseURL is the analyzed target, generated tests run against it
+	BaseURL string `json:"baseUrl,omitempty"`
+	// Accessibility holds the violations of the audited pages, accessibility criteria are based on them
+	Accessibility []PageA11yAudit `json:"accessibility,omitempty"`
+}
+
+// SessionReplayArgs represents the request to turn a recorded session into a test
+type SessionReplayArgs struct {
+	Url       string           `json:"url"`
+	SessionID string           `json:"sessionId"`
+	Config    *ConfigOverrides `json:"config,omitempty"`
+}
+
+// GeneratedTestReturn represents a test generated for a single criterion
+type GeneratedTestReturn struct {
+	TechSpec  string         `json:"techSpec"`
+	Criterion TestCriterion  `json:"criterion"`
+	Pages     []string       `json:"pages"`
+	Filename  string         `json:"filename"`
+	Test      string         `json:"test"`
+	Result    *GenEvalResult `json:"result"`
+}
+
+// TestCriterion represents a single test scenario proposed by the analyzer
+type TestCriterion struct {
+	Title    string `json:"title"`
+	Scenario string `json:"scenario"`
+	Expected string `json:"expected"`
+}
+
+// String formats the criterion the same way the analyzer is asked to format it
+func (c TestCriterion) String() string {
+	return fmt.Sprintf("TITLE: %s\nSCENARIO: %s\nEXPECTED: %s", c.Title, c.Scenario, c.Expected)
+}
+
+// ParseCriteria splits the analyzer criteria output into separate criteria
+// Each criterion is expected to be separated by 2 newlines
+func ParseCriteria(raw string) []TestCriterion {
+	var criteria []TestCriterion
+	for _, block := range strings.Split(raw, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+
+		var c TestCriterion
+		for _, line := range strings.Split(block, "\n") {
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "TITLE:"):
+				c.Title = strings.TrimSpace(strings.TrimPrefix(line, "TITLE:"))
+			case strings.HasPrefix(line, "SCENARIO:"):
+				c.Scenario = strings.TrimSpace(strings.TrimPrefix(line, "SCENARIO:"))
+			case strings.HasPrefix(line, "EXPECTED:"):
+				c.Expected = strings.TrimSpace(strings.TrimPrefix(line, "EXPECTED:"))
+			}
+		}
+
+		// Keep unstructured blocks as a plain scenario
+		if c.Title == "" && c.Scenario == "" && c.Expected == "" {
+			c.Scenario = block
+		}
+
+		criteria = append(criteria, c)
+	}
+
+	return criteria
 }
 
 type EvaluationReturn struct {
@@ -285,7 +477,10 @@ type UmamiLoginResponse struct {
 
 // UmamiSessionsResponse represents the response from the Umami API for sessions
 type UmamiSessionsResponse struct {
-	Data []UmamiSession `json:"data"`
+	Data     []UmamiSession `json:"data"`
+	Count    int            `json:"count"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"pageSize"`
 }
 
 // UmamiSession represents a user session from Umami API
@@ -315,13 +510,28 @@ type UmamiSessionActivity struct {
 	VisitID        string `json:"visitId"`
 }
 
+// AnalyticsSession represents a visitor session from any analytics source
+type AnalyticsSession struct {
+	ID    string          `json:"id"`
+	Steps []AnalyticsStep `json:"steps"`
+}
+
+// AnalyticsStep represents a page view, or a custom event when Event is set
+type AnalyticsStep struct {
+	Path      string    `json:"path"`
+	Event     string    `json:"event,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // UmamiUserFlow represents a user's path through a website
+// Path steps are page paths or custom events prefixed with "event:"
 type UmamiUserFlow struct {
 	SessionID string   `json:"sessionId"`
 	Path      []string `json:"path"`
 }
 
 // UmamiSignificantFlow represents a common user flow pattern with its frequency
+// Frequency is the number of sessions following the pattern
 type UmamiSignificantFlow struct {
 	Path       []string `json:"path"`
 	Frequency  int      `json:"frequency"`
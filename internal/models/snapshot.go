@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// SiteSnapshot is the persisted state of a previous analysis of a website
+type SiteSnapshot struct {
+	BaseURL   string                  `json:"baseUrl"`
+	TechSpec  string                  `json:"techSpec"`
+	CreatedAt time.Time               `json:"createdAt"`
+	UpdatedAt time.Time               `json:"updatedAt"`
+	Pages     map[string]SnapshotPage `json:"pages"`
+	Criteria  []TestCriterion         `json:"criteria"`
+	Tests     []SnapshotTest          `json:"tests"`
+}
+
+// SnapshotPage holds the change-detection data for a single page
+type SnapshotPage struct {
+	URL       string    `json:"url"`
+	LastMod   string    `json:"lastMod,omitempty"`
+	Hash      string    `json:"hash"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// SnapshotTest links a generated test file to the criterion and pages it covers
+type SnapshotTest struct {
+	File        string        `json:"file"`
+	Criterion   TestCriterion `json:"criterion"`
+	Pages       []string      `json:"pages"`
+	Stale       bool          `json:"stale"`
+	StaleReason string        `json:"staleReason,omitempty"`
+}
+
+// SnapshotUpdate describes the difference between a snapshot and the current site
+type SnapshotUpdate struct {
+	Added       []string          `json:"added"`
+	Changed     []string          `json:"changed"`
+	Removed     []string          `json:"removed"`
+	Unchanged   []string          `json:"unchanged"`
+	Contents    map[string]string `json:"contents"`
+	NewCriteria []TestCriterion   `json:"newCriteria"`
+	StaleTests  []SnapshotTest    `json:"staleTests"`
+}
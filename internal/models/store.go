@@ -0,0 +1,212 @@
+package models
+
+import "time"
+
+// Run kinds
+const (
+	RunKindAnalyze     = "analyze"
+	RunKindGenerate    = "generate"
+	RunKindUpdate      = "update"
+	RunKindReproduce   = "reproduce"
+	RunKindFromSession = "from-session"
+)
+
+// Run statuses
+const (
+	RunStatusRunning  = "running"
+	RunStatusFinished = "finished"
+	RunStatusFailed   = "failed"
+)
+
+// Reasons the sandbox stops a command
+const (
+	FailureTimeout       = "timeout"
+	FailureCanceled      = "canceled"
+	FailureCPULimit      = "cpu_limit"
+	FailureMemoryLimit   = "memory_limit"
+	FailureOutputLimit   = "output_limit"
+	FailureFileSizeLimit = "file_size_limit"
+	FailureEgressBlocked = "egress_blocked"
+)
+
+// API token roles, an admin can do everything a member can
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// Run is a single analysis or generation run of a website
+// Project is the name of the ProjectConfig the run was started with, empty without a current project
+type Run struct {
+	ID         string    `json:"id"`
+	Project    string    `json:"project,omitempty"`
+	URL        string    `json:"url"`
+	Kind       string    `json:"kind"`
+	Status     string    `json:"status"`
+	TechSpec   string    `json:"techSpec,omitempty"`
+	Pages      []string  `json:"pages,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+}
+
+// APIToken is a bearer token of the API server
+// Only the SHA-256 hash of the token is stored, the prefix identifies it in listings
+type APIToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	Hash      string    `json:"hash"`
+	Prefix    string    `json:"prefix"`
+	RateLimit int       `json:"rateLimit,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	RevokedAt time.Time `json:"revokedAt,omitempty"`
+}
+
+// RunCriterion is a test criterion produced in a run
+type RunCriterion struct {
+	ID        string        `json:"id"`
+	RunID     string        `json:"runId"`
+	Index     int           `json:"index"`
+	Criterion TestCriterion `json:"criterion"`
+}
+
+// TestVersion is the content of a generated test file after one gen-eval iteration
+type TestVersion struct {
+	ID          string    `json:"id"`
+	RunID       string    `json:"runId"`
+	CriterionID string    `json:"criterionId,omitempty"`
+	File        string    `json:"file"`
+	Version     int       `json:"version"`
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// TestResult is the outcome of executing a test version
+type TestResult struct {
+	ID            string           `json:"id"`
+	RunID         string           `json:"runId"`
+	TestVersionID string           `json:"testVersionId"`
+	Passed        bool             `json:"passed"`
+	Accepted      bool             `json:"accepted"`
+	Output        string           `json:"output"`
+	Feedback      string           `json:"feedback,omitempty"`
+	Failure       *RunFailure      `json:"failure,omitempty"`
+	Stability     *StabilityReport `json:"stability,omitempty"`
+	Projects      []ProjectResult  `json:"projects,omitempty"`
+	Artifacts     []Artifact       `json:"artifacts,omitempty"`
+	DurationMs    int64            `json:"durationMs"`
+	CreatedAt     time.Time        `json:"createdAt"`
+}
+
+// RunDetails is a run together with everything produced in it
+type RunDetails struct {
+	Run      Run            `json:"run"`
+	Criteria []RunCriterion `json:"criteria"`
+	Tests    []TestVersion  `json:"tests"`
+	Results  []TestResult   `json:"results"`
+}
+
+// RunComparison compares the criteria and final test results of two runs
+// Criteria are matched by title
+type RunComparison struct {
+	Base            string   `json:"base"`
+	Head            string   `json:"head"`
+	AddedCriteria   []string `json:"addedCriteria"`
+	RemovedCriteria []string `json:"removedCriteria"`
+	Fixed           []string `json:"fixed"`
+	Broken          []string `json:"broken"`
+	Unchanged       []string `json:"unchanged"`
+}
+
+// GenEvalResult is the outcome of a gen-eval loop
+type GenEvalResult struct {
+	Filename   string             `json:"filename"`
+	Accepted   bool               `json:"accepted"`
+	Iterations []GenEvalIteration `json:"iterations"`
+}
+
+// GenEvalIteration is a single generation and evaluation of a test file
+type GenEvalIteration struct {
+	Content     string `json:"content"`
+	TestsPassed bool   `json:"testsPassed"`
+	Output      string `json:"output"`
+	Feedback    string `json:"feedback,omitempty"`
+	Accepted    bool   `json:"accepted"`
+	DurationMs  int64  `json:"durationMs"`
+	// Findings are the reasons the safety scan didn't let the tests run
+	Findings []string `json:"findings,omitempty"`
+	// Failure is set when the sandbox stopped the tests before they finished
+	Failure *RunFailure `json:"failure,omitempty"`
+	// Stability is the outcome of the repeated runs of an accepted file
+	Stability *StabilityReport `json:"stability,omitempty"`
+	// Projects are the results per browser and device project
+	Projects []ProjectResult `json:"projects,omitempty"`
+	// Artifacts are the traces, screenshots and videos of the test run, their paths are relative to ArtifactDir
+	Artifacts   []Artifact `json:"artifacts,omitempty"`
+	ArtifactDir string     `json:"-"`
+}
+
+// StabilityReport is the outcome of running a test file repeatedly
+// Score is the share of passed runs over all tests, a file is stable when every test reaches the threshold
+type StabilityReport struct {
+	Runs        int             `json:"runs"`
+	CPUThrottle int             `json:"cpuThrottle,omitempty"`
+	Threshold   float64         `json:"threshold"`
+	Score       float64         `json:"score"`
+	Stable      bool            `json:"stable"`
+	Tests       []TestStability `json:"tests"`
+}
+
+// TestStability is the pass rate and timing of a single test over the repeated runs
+type TestStability struct {
+	Title    string  `json:"title"`
+	Runs     int     `json:"runs"`
+	Passed   int     `json:"passed"`
+	PassRate float64 `json:"passRate"`
+	MeanMs   float64 `json:"meanMs"`
+	StdDevMs float64 `json:"stdDevMs"`
+	// Failures are the distinct error messages of the failed runs
+	Failures []string `json:"failures,omitempty"`
+}
+
+// ProjectResult is the outcome of a test file on one Playwright project
+type ProjectResult struct {
+	Project string `json:"project"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Skipped int    `json:"skipped"`
+	// Failures are the titles and first errors of the failed tests
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Kinds of artifacts
+const (
+	ArtifactTrace      = "trace"
+	ArtifactScreenshot = "screenshot"
+	ArtifactVideo      = "video"
+	ArtifactOther      = "other"
+)
+
+// Artifact is a file Playwright left in test-results, Kind is one of the Artifact constants
+// Stored artifacts are served at /runs/{runId}/artifacts/{path}
+type Artifact struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Size int64  `json:"size"`
+}
+
+// RunEvent is a line of output of a command run for a job, streamed while the command runs
+type RunEvent struct {
+	RunID  string    `json:"runId,omitempty"`
+	Time   time.Time `json:"time"`
+	Step   string    `json:"step"`
+	Stream string    `json:"stream"`
+	Line   string    `json:"line"`
+}
+
+// RunFailure is why the sandbox stopped a command, Reason is one of the Failure constants
+type RunFailure struct {
+	Reason string `json:"reason"`
+	Detail string `json:"detail"`
+}
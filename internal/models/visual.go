@@ -0,0 +1,30 @@
+package models
+
+// VisualPlan is what the visual regression tests compare on the key pages of a website
+type VisualPlan struct {
+	Pages []VisualPage `json:"pages" jsonschema_description:"The key pages to compare visually, at most 5"`
+}
+
+// VisualPage is a page compared as a whole and by its key components, with its dynamic regions masked
+type VisualPage struct {
+	URL        string            `json:"url" jsonschema_description:"URL of the page, it must be one of the analyzed pages"`
+	Name       string            `json:"name" jsonschema_description:"Short name of the page, e.g. home or pricing"`
+	Components []VisualComponent `json:"components" jsonschema_description:"Key components compared on their own, at most 3"`
+	Masks      []string          `json:"masks" jsonschema_description:"CSS selectors of regions that change between visits and are masked: timestamps, dates, counters, carousels, ads, embedded videos and iframes, random or personalized content"`
+}
+
+// VisualComponent is a component of a page compared on its own
+type VisualComponent struct {
+	Name     string `json:"name" jsonschema_description:"Short name of the component, e.g. header or pricing-table"`
+	Selector string `json:"selector" jsonschema_description:"Stable CSS selector of the component"`
+}
+
+// VisualTestReturn is the outcome of generating visual regression tests
+// Stable is set when the tests passed against their fresh baselines, Failures lists the tests that didn't
+type VisualTestReturn struct {
+	SpecFile  string     `json:"specFile"`
+	Baselines []string   `json:"baselines"`
+	Plan      VisualPlan `json:"plan"`
+	Stable    bool       `json:"stable"`
+	Failures  []string   `json:"failures,omitempty"`
+}
@@ -0,0 +1,46 @@
+package netguard
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+)
+
+// Browser returns an action that checks every request of the browser tab against the policy
+// Requests are paused until checked, so navigations, redirects and subresources all go through it.
+// Run it once per tab before navigating.
+func (p *Policy) Browser() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		log := logger.For(ctx, "netguard")
+
+		chromedp.ListenTarget(ctx, func(ev any) {
+			paused, ok := ev.(*fetch.EventRequestPaused)
+			if !ok {
+				return
+			}
+
+			// Commands can't be sent from the listener itself
+			go func() {
+				executor := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+
+				u, err := url.Parse(paused.Request.URL)
+				if err == nil {
+					err = p.Check(ctx, u)
+				}
+				if err != nil {
+					log.Warn("blocked browser request", "url", paused.Request.URL, "error", err)
+					fetch.FailRequest(paused.RequestID, network.ErrorReasonBlockedByClient).Do(executor)
+					return
+				}
+				fetch.ContinueRequest(paused.RequestID).Do(executor)
+			}()
+		})
+
+		return fetch.Enable().Do(ctx)
+	})
+}
@@ -0,0 +1,294 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+)
+
+// ErrBlocked is wrapped by every error of a URL the policy doesn't allow
+var ErrBlocked = errors.New("blocked by the outbound URL policy")
+
+// maxRedirects matches the default of http.Client
+const maxRedirects = 10
+
+var (
+	// localRanges are loopback, private, link-local and other non-public ranges, allowed only by opt-in
+	localRanges = parsePrefixes(
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"64:ff9b::/96",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	)
+
+	// metadataAddrs are the cloud instance metadata endpoints, blocked even for local targets
+	metadataAddrs = []netip.Addr{
+		netip.MustParseAddr("169.254.169.254"),
+		netip.MustParseAddr("fd00:ec2::254"),
+	}
+)
+
+// Policy decides which URLs outbound requests may reach:
+// only http and https, hosts resolving to public addresses unless local targets are allowed,
+// and when domains are set only those domains and their subdomains
+type Policy struct {
+	allowLocal bool
+	domains    []string
+	// restricted policies allow only their domains, without any they allow nothing
+	restricted bool
+	resolver   *net.Resolver
+}
+
+// New creates a policy, an empty domain list allows every public host
+func New(allowLocal bool, domains []string) *Policy {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "*.")
+		if domain != "" {
+			normalized = append(normalized, strings.TrimSuffix(domain, "."))
+		}
+	}
+	return &Policy{allowLocal: allowLocal, domains: normalized, resolver: net.DefaultResolver}
+}
+
+// ForConfig returns the policy for the targets of a job, with the domain allowlist of the current project
+func ForConfig(cfg *config.Config) *Policy {
+	return New(cfg.AllowLocalTargets, cfg.AllowedDomains)
+}
+
+// ForAPI returns the policy for the configured Sentry and Umami APIs, they aren't limited to the project domains
+func ForAPI(cfg *config.Config) *Policy {
+	return New(cfg.AllowLocalTargets, nil)
+}
+
+// Restrict returns a copy of the policy that only allows the domains and subdomains the policy also allows
+// Restricting to no domains allows nothing
+func (p *Policy) Restrict(domains []string) *Policy {
+	requested := New(p.allowLocal, domains).domains
+	allowed := requested
+	if p.restricted || len(p.domains) > 0 {
+		allowed = nil
+		for _, domain := range requested {
+			for _, parent := range p.domains {
+				switch {
+				case inDomain(domain, parent):
+					allowed = append(allowed, domain)
+				case inDomain(parent, domain):
+					allowed = append(allowed, parent)
+				}
+			}
+		}
+	}
+	return &Policy{allowLocal: p.allowLocal, domains: allowed, restricted: true, resolver: p.resolver}
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the policy
+func NewContext(ctx context.Context, policy *Policy) context.Context {
+	return context.WithValue(ctx, contextKey{}, policy)
+}
+
+// FromContext returns the policy of the context, or the strictest policy without local targets
+func FromContext(ctx context.Context) *Policy {
+	if policy, ok := ctx.Value(contextKey{}).(*Policy); ok {
+		return policy
+	}
+	return New(false, nil)
+}
+
+// Normalize parses a target URL, URLs without a scheme get https
+func Normalize(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%s: missing host", raw)
+	}
+	return u, nil
+}
+
+// Check validates the scheme and host of u and resolves the host to check its addresses
+// Connections made through Transport are checked again when dialing, so DNS changes can't bypass it
+func (p *Policy) Check(ctx context.Context, u *url.URL) error {
+	if err := p.checkURL(u); err != nil {
+		return err
+	}
+
+	_, err := p.resolve(ctx, u.Hostname())
+	return err
+}
+
+// CheckString normalizes and checks a raw URL
+func (p *Policy) CheckString(ctx context.Context, raw string) (*url.URL, error) {
+	u, err := Normalize(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Check(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// checkURL checks the scheme and the domain allowlist without resolving the host
+func (p *Policy) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q of %s isn't allowed", ErrBlocked, u.Scheme, u.Redacted())
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("%w: %s has no host", ErrBlocked, u.Redacted())
+	}
+	if len(p.domains) == 0 && !p.restricted {
+		return nil
+	}
+	for _, domain := range p.domains {
+		if inDomain(host, domain) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s isn't in the allowed domains of the project", ErrBlocked, host)
+}
+
+// inDomain reports whether host is domain or one of its subdomains
+func inDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// resolve returns the addresses of host, failing when any of them is blocked
+func (p *Policy) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		resolved, err := p.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		addrs = resolved
+	}
+
+	for _, addr := range addrs {
+		if err := p.checkAddr(addr); err != nil {
+			return nil, fmt.Errorf("%s: %w", host, err)
+		}
+	}
+	return addrs, nil
+}
+
+func (p *Policy) checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, metadata := range metadataAddrs {
+		if addr == metadata {
+			return fmt.Errorf("%w: %s is a cloud metadata address", ErrBlocked, addr)
+		}
+	}
+	if p.allowLocal {
+		return nil
+	}
+	for _, prefix := range localRanges {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s is a local address, local targets need ALLOW_LOCAL_TARGETS=true", ErrBlocked, addr)
+		}
+	}
+	return nil
+}
+
+// DialContext resolves and checks the host, then dials a checked address
+func (p *Policy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := p.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	var dialErr error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+	}
+	return nil, dialErr
+}
+
+// Transport returns an HTTP transport whose requests are checked by the policy
+// It connects only to addresses allowed by the policy. Proxies from the environment are
+// ignored, they would hide the real target from the check.
+func (p *Policy) Transport() http.RoundTripper {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = nil
+	base.DialContext = p.DialContext
+	return &transport{policy: p, base: base}
+}
+
+type transport struct {
+	policy *Policy
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.policy.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// CheckRedirect checks every redirect target, it fits http.Client and colly
+func (p *Policy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return p.checkURL(req.URL)
+}
+
+// Client returns an HTTP client whose requests and redirects are checked by the policy
+func (p *Policy) Client() *http.Client {
+	return &http.Client{
+		Transport:     p.Transport(),
+		CheckRedirect: p.CheckRedirect,
+	}
+}
+
+func parsePrefixes(cidrs ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, len(cidrs))
+	for i, cidr := range cidrs {
+		prefixes[i] = netip.MustParsePrefix(cidr)
+	}
+	return prefixes
+}
@@ -0,0 +1,111 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	strict := New(false, nil)
+	local := New(true, nil)
+
+	tests := []struct {
+		url     string
+		policy  *Policy
+		blocked bool
+	}{
+		{"https://93.184.215.14/", strict, false},
+		{"http://127.0.0.1:8080/", strict, true},
+		{"http://127.0.0.1:8080/", local, false},
+		{"http://10.1.2.3/", strict, true},
+		{"http://[::1]/", strict, true},
+		{"http://[::ffff:192.168.1.1]/", strict, true},
+		{"http://169.254.169.254/latest/meta-data/", strict, true},
+		{"http://169.254.169.254/latest/meta-data/", local, true},
+		{"file:///etc/passwd", local, true},
+		{"gopher://93.184.215.14/", strict, true},
+	}
+
+	for _, test := range tests {
+		u, _ := url.Parse(test.url)
+		err := test.policy.Check(ctx, u)
+		if test.blocked != errors.Is(err, ErrBlocked) {
+			t.Errorf("%s: expected blocked=%v, got %v", test.url, test.blocked, err)
+		}
+	}
+}
+
+func TestDomains(t *testing.T) {
+	policy := New(false, []string{"example.com", "*.shop.test"})
+
+	for host, allowed := range map[string]bool{
+		"example.com":      true,
+		"www.example.com":  true,
+		"eu.shop.test":     true,
+		"notexample.com":   false,
+		"example.com.evil": false,
+	} {
+		err := policy.checkURL(&url.URL{Scheme: "https", Host: host})
+		if allowed != (err == nil) {
+			t.Errorf("%s: expected allowed=%v, got %v", host, allowed, err)
+		}
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	if err := New(false, nil).Restrict(nil).checkURL(&url.URL{Scheme: "https", Host: "example.com"}); err == nil {
+		t.Error("Expected a policy restricted to no domains to allow nothing")
+	}
+
+	// Only the domains both policies allow are left
+	policy := New(false, []string{"example.com", "shop.test"}).Restrict([]string{"www.example.com", "other.test", "test"})
+	for host, allowed := range map[string]bool{
+		"www.example.com": true,
+		"example.com":     false,
+		"other.test":      false,
+		"shop.test":       true,
+		"eu.shop.test":    true,
+	} {
+		err := policy.checkURL(&url.URL{Scheme: "https", Host: host})
+		if allowed != (err == nil) {
+			t.Errorf("%s: expected allowed=%v, got %v", host, allowed, err)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if _, err := New(false, nil).Client().Get(server.URL); !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected the local server to be blocked, got %v", err)
+	}
+
+	resp, err := New(true, nil).Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the local server to be allowed by opt-in, got %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := New(true, nil).Client().Get(server.URL + "/redirect"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected the redirect to the metadata address to be blocked, got %v", err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	u, err := Normalize("localhost:3000/login")
+	if err != nil || u.String() != "https://localhost:3000/login" {
+		t.Errorf("Expected https to be added, got %v, %v", u, err)
+	}
+}
@@ -0,0 +1,84 @@
+package promptguard
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const allowedModule = "@playwright/test"
+
+var (
+	// modulePattern matches ES imports, dynamic imports and require calls
+	modulePattern = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*\(?\s*|\brequire\s*\(\s*)['"]([^'"]+)['"]`)
+
+	// dangerousPatterns match Node APIs a browser test never needs
+	dangerousPatterns = map[string]*regexp.Regexp{
+		"reads the environment":      regexp.MustCompile(`\bprocess\.env\b`),
+		"evaluates generated code":   regexp.MustCompile(`\beval\s*\(|\bnew\s+Function\s*\(`),
+		"spawns processes":           regexp.MustCompile(`\b(execSync|spawnSync|execFile|fork)\s*\(`),
+		"opens raw network sockets":  regexp.MustCompile(`\bnet\.(connect|createConnection)\s*\(`),
+		"exits the test process":     regexp.MustCompile(`\bprocess\.(exit|kill)\s*\(`),
+		"accesses the global object": regexp.MustCompile(`\bglobalThis\.(process|require)\b`),
+	}
+
+	urlPattern = regexp.MustCompile("https?://[^\\s'\"`)]+")
+)
+
+// ScanTestCode returns the reasons generated test code isn't safe to run:
+// imports other than @playwright/test and the opted-in modules (fs and child_process included),
+// Node APIs for processes and the environment, and URLs outside the scope
+func ScanTestCode(code string, scope *Scope, modules ...string) []string {
+	var findings []string
+
+	allowed := append([]string{allowedModule}, modules...)
+	for _, match := range modulePattern.FindAllStringSubmatch(code, -1) {
+		module := match[1]
+		if slices.Contains(allowed, module) {
+			continue
+		}
+		findings = append(findings, fmt.Sprintf("imports %q, only %s allowed", module, allowedList(allowed)))
+	}
+
+	for reason, pattern := range dangerousPatterns {
+		if match := pattern.FindString(code); match != "" {
+			findings = append(findings, fmt.Sprintf("%s (%s)", reason, strings.TrimSpace(match)))
+		}
+	}
+
+	if scope != nil && !scope.Empty() {
+		seen := map[string]bool{}
+		for _, u := range urlPattern.FindAllString(code, -1) {
+			if seen[u] || scope.Contains(u) {
+				continue
+			}
+			seen[u] = true
+			findings = append(findings, fmt.Sprintf("reaches %s outside the website under test", u))
+		}
+	}
+
+	return sortedUnique(findings)
+}
+
+// allowedList names the allowed modules for a finding, e.g. "@playwright/test is"
+func allowedList(allowed []string) string {
+	if len(allowed) == 1 {
+		return allowed[0] + " is"
+	}
+	return strings.Join(allowed[:len(allowed)-1], ", ") + " and " + allowed[len(allowed)-1] + " are"
+}
+
+func sortedUnique(values []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,176 @@
+package analyzer
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+)
+
+const (
+	// maxViolationsPerRule keeps pages with a broken pattern repeated on every element from flooding the model
+	maxViolationsPerRule = 10
+	// maxTabPresses bounds the keyboard walk of pages with many focusable elements
+	maxTabPresses     = 100
+	maxSelectorLength = 200
+)
+
+// a11yScript defines the checks of the audit in the page
+//
+//go:embed a11y_audit.js
+var a11yScript string
+
+// AuditAccessibility checks each page for missing labels, low contrast, landmark and heading structure, duplicate IDs and keyboard traps
+// The checks run in Chrome on the rendered page, keyboard traps are found by pressing Tab through the page
+func AuditAccessibility(ctx context.Context, urls []string) (*models.GetA11yAuditToolReturn, error) {
+	log := logger.For(ctx, "crawler")
+
+	if len(urls) == 0 {
+		return nil, errors.New("empty URLs list provided")
+	}
+
+	policy := netguard.FromContext(ctx)
+	validatedUrls := make([]string, 0, len(urls))
+	for _, urlStr := range urls {
+		parsedURL, err := policy.CheckString(ctx, urlStr)
+		if err != nil {
+			log.Warn("skipping URL", "url", urlStr, "error", err)
+			continue
+		}
+		validatedUrls = append(validatedUrls, parsedURL.String())
+	}
+	if len(validatedUrls) == 0 {
+		return nil, errors.New("no valid URLs provided")
+	}
+
+	browserCtx, cancel := chromedp.NewContext(ctx)
+	defer cancel()
+
+	if err := chromedp.Run(browserCtx, policy.Browser()); err != nil {
+		return nil, fmt.Errorf("failed to start the browser: %w", err)
+	}
+
+	result := &models.GetA11yAuditToolReturn{Pages: []models.PageA11yAudit{}}
+	violations := 0
+	for _, urlStr := range validatedUrls {
+		audit, err := auditPage(browserCtx, urlStr)
+		if err != nil {
+			log.Warn("failed to audit page", "url", urlStr, "error", err)
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", urlStr, err))
+			continue
+		}
+		violations += len(audit.Violations)
+		result.Pages = append(result.Pages, *audit)
+	}
+
+	log.Info("audited accessibility", "pages", len(result.Pages), "violations", violations, "skipped", len(result.Skipped))
+	return result, nil
+}
+
+// auditPage loads a page, runs the checks of the script and walks it with the keyboard
+func auditPage(browserCtx context.Context, urlStr string) (*models.PageA11yAudit, error) {
+	ctx, cancel := context.WithTimeout(browserCtx, 60*time.Second)
+	defer cancel()
+
+	var violations []models.A11yViolation
+	var focusable int
+	err := chromedp.Run(ctx,
+		chromedp.Navigate(urlStr),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		// Wait for fonts, styles and client-side rendering to settle
+		chromedp.Sleep(1*time.Second),
+		chromedp.Evaluate(a11yScript, nil),
+		chromedp.Evaluate(`window.__testbuddyA11y.audit()`, &violations),
+		chromedp.Evaluate(`window.__testbuddyA11y.focusable()`, &focusable),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	trap, err := keyboardTrap(ctx, focusable)
+	if err != nil {
+		return nil, fmt.Errorf("keyboard walk failed: %w", err)
+	}
+	if trap != nil {
+		violations = append(violations, *trap)
+	}
+
+	return &models.PageA11yAudit{URL: urlStr, Violations: limitViolations(violations)}, nil
+}
+
+// keyboardTrap presses Tab until focus leaves the page or comes back to an element it visited
+// Coming back before every focusable element was reached means the focus is trapped
+func keyboardTrap(ctx context.Context, focusable int) (*models.A11yViolation, error) {
+	if focusable < 2 {
+		return nil, nil
+	}
+
+	if err := chromedp.Run(ctx, chromedp.Evaluate(`document.activeElement && document.activeElement.blur()`, nil)); err != nil {
+		return nil, err
+	}
+
+	visited := map[string]bool{}
+	for i := 0; i < min(focusable+1, maxTabPresses); i++ {
+		var active string
+		if err := chromedp.Run(ctx,
+			chromedp.KeyEvent(kb.Tab),
+			chromedp.Evaluate(`window.__testbuddyA11y.active()`, &active),
+		); err != nil {
+			return nil, err
+		}
+
+		if active == "" {
+			return nil, nil
+		}
+		if visited[active] {
+			if len(visited) < focusable {
+				return &models.A11yViolation{
+					Rule:     models.A11yKeyboardTrap,
+					Impact:   models.A11yImpactCritical,
+					Selector: active,
+					Message:  fmt.Sprintf("Tab keeps cycling through %d of the %d focusable elements, the others can't be reached with the keyboard", len(visited), focusable),
+				}, nil
+			}
+			return nil, nil
+		}
+		visited[active] = true
+	}
+	return nil, nil
+}
+
+// limitViolations keeps the first maxViolationsPerRule violations of each rule and shortens long selectors
+func limitViolations(violations []models.A11yViolation) []models.A11yViolation {
+	limited := []models.A11yViolation{}
+	perRule := map[string]int{}
+	var rules []string
+	for _, violation := range violations {
+		if perRule[violation.Rule] == 0 {
+			rules = append(rules, violation.Rule)
+		}
+		perRule[violation.Rule]++
+		if perRule[violation.Rule] > maxViolationsPerRule {
+			continue
+		}
+		if len(violation.Selector) > maxSelectorLength {
+			violation.Selector = violation.Selector[:maxSelectorLength]
+		}
+		limited = append(limited, violation)
+	}
+	for _, rule := range rules {
+		if count := perRule[rule]; count > maxViolationsPerRule {
+			limited = append(limited, models.A11yViolation{
+				Rule:    rule,
+				Impact:  models.A11yImpactModerate,
+				Message: fmt.Sprintf("%d more %s violations left out", count-maxViolationsPerRule, rule),
+			})
+		}
+	}
+	return limited
+}
@@ -0,0 +1,178 @@
+// Accessibility checks run in the audited page, see AuditAccessibility
+window.__testbuddyA11y = (() => {
+  const visible = (el) => {
+    const style = getComputedStyle(el);
+    return el.getClientRects().length > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+  };
+
+  const selectorOf = (el) => {
+    const parts = [];
+    for (let node = el; node && node.nodeType === 1 && parts.length < 6; node = node.parentElement) {
+      if (node.id && document.querySelectorAll('#' + CSS.escape(node.id)).length === 1) {
+        parts.unshift('#' + CSS.escape(node.id));
+        break;
+      }
+      let part = node.localName;
+      const siblings = node.parentElement ? [...node.parentElement.children].filter((s) => s.localName === node.localName) : [];
+      if (siblings.length > 1) {
+        part += `:nth-of-type(${siblings.indexOf(node) + 1})`;
+      }
+      parts.unshift(part);
+    }
+    return parts.join(' > ');
+  };
+
+  const accessibleName = (el) => {
+    const labelledBy = (el.getAttribute('aria-labelledby') || '')
+      .split(/\s+/)
+      .map((id) => id && document.getElementById(id))
+      .filter(Boolean)
+      .map((label) => label.textContent)
+      .join(' ');
+    const labels = el.labels ? [...el.labels].map((label) => label.textContent).join(' ') : '';
+    const images = [...el.querySelectorAll('img[alt]')].map((img) => img.alt).join(' ');
+    return [labelledBy, el.getAttribute('aria-label'), labels, el.getAttribute('title'), images]
+      .concat(el.matches('input, select, textarea') ? [] : [el.innerText])
+      .join(' ')
+      .trim();
+  };
+
+  const parseColor = (value) => {
+    const match = value.match(/^rgba?\(([\d.]+),\s*([\d.]+),\s*([\d.]+)(?:,\s*([\d.]+))?\)$/);
+    return match ? { r: +match[1], g: +match[2], b: +match[3], a: match[4] === undefined ? 1 : +match[4] } : null;
+  };
+
+  const luminance = ({ r, g, b }) => {
+    const [lr, lg, lb] = [r, g, b].map((c) => {
+      c /= 255;
+      return c <= 0.03928 ? c / 12.92 : Math.pow((c + 0.055) / 1.055, 2.4);
+    });
+    return 0.2126 * lr + 0.7152 * lg + 0.0722 * lb;
+  };
+
+  // background returns the opaque color behind el, or null when an image or gradient is in the way
+  const background = (el) => {
+    for (let node = el; node; node = node.parentElement) {
+      const style = getComputedStyle(node);
+      if (style.backgroundImage !== 'none') {
+        return null;
+      }
+      const color = parseColor(style.backgroundColor);
+      if (color && color.a >= 1) {
+        return color;
+      }
+      if (color && color.a > 0) {
+        return null;
+      }
+    }
+    return { r: 255, g: 255, b: 255, a: 1 };
+  };
+
+  const hex = ({ r, g, b }) => '#' + [r, g, b].map((c) => Math.round(c).toString(16).padStart(2, '0')).join('');
+
+  const missingLabels = (report) => {
+    const controls = 'input:not([type=hidden]):not([type=submit]):not([type=button]):not([type=reset]), select, textarea';
+    for (const el of document.querySelectorAll(controls)) {
+      if (visible(el) && !accessibleName(el) && !(el.type === 'image' && el.alt)) {
+        report('missing_label', 'critical', el, `${el.localName} field has no label`);
+      }
+    }
+    for (const el of document.querySelectorAll('button, a[href], [role=button], [role=link]')) {
+      if (visible(el) && !accessibleName(el)) {
+        report('missing_label', 'critical', el, `${el.getAttribute('role') || el.localName} has no accessible name`);
+      }
+    }
+    for (const el of document.querySelectorAll('img:not([alt])')) {
+      if (visible(el) && !['presentation', 'none'].includes(el.getAttribute('role')) && !el.getAttribute('aria-label')) {
+        report('missing_label', 'serious', el, 'image has no alt text');
+      }
+    }
+  };
+
+  const contrast = (report) => {
+    for (const el of document.querySelectorAll('body *')) {
+      const hasText = [...el.childNodes].some((node) => node.nodeType === 3 && node.textContent.trim());
+      if (!hasText || !visible(el)) {
+        continue;
+      }
+      const style = getComputedStyle(el);
+      const color = parseColor(style.color);
+      const bg = background(el);
+      if (!color || !bg) {
+        continue;
+      }
+      const fg = {
+        r: color.r * color.a + bg.r * (1 - color.a),
+        g: color.g * color.a + bg.g * (1 - color.a),
+        b: color.b * color.a + bg.b * (1 - color.a),
+      };
+      const [light, dark] = [luminance(fg), luminance(bg)].sort((a, b) => b - a);
+      const ratio = (light + 0.05) / (dark + 0.05);
+      const size = parseFloat(style.fontSize);
+      const large = size >= 24 || (size >= 18.66 && parseInt(style.fontWeight, 10) >= 700);
+      const required = large ? 3 : 4.5;
+      if (ratio < required) {
+        report('contrast', 'serious', el, `text contrast ${ratio.toFixed(2)}:1 of ${hex(fg)} on ${hex(bg)}, at least ${required}:1 is needed`);
+      }
+    }
+  };
+
+  const landmarks = (report) => {
+    const mains = document.querySelectorAll('main, [role=main]');
+    if (mains.length === 0) {
+      report('landmarks', 'moderate', null, 'page has no main landmark');
+    } else if (mains.length > 1) {
+      report('landmarks', 'moderate', mains[1], `page has ${mains.length} main landmarks`);
+    }
+    const headings = [...document.querySelectorAll('h1, h2, h3, h4, h5, h6')].filter(visible);
+    if (!headings.some((h) => h.localName === 'h1')) {
+      report('landmarks', 'moderate', null, 'page has no h1 heading');
+    }
+    let previous = 0;
+    for (const heading of headings) {
+      const level = +heading.localName[1];
+      if (previous && level > previous + 1) {
+        report('landmarks', 'moderate', heading, `heading level skips from h${previous} to h${level}`);
+      }
+      previous = level;
+    }
+  };
+
+  const duplicateIDs = (report) => {
+    const counts = new Map();
+    for (const el of document.querySelectorAll('[id]')) {
+      counts.set(el.id, (counts.get(el.id) || 0) + 1);
+    }
+    for (const [id, count] of counts) {
+      if (id && count > 1) {
+        report('duplicate_id', 'serious', null, `id "${id}" is used by ${count} elements`, `[id="${CSS.escape(id)}"]`);
+      }
+    }
+  };
+
+  return {
+    audit() {
+      const violations = [];
+      const report = (rule, impact, el, message, selector) => {
+        violations.push({ rule, impact, selector: selector || (el ? selectorOf(el) : ''), message });
+      };
+      missingLabels(report);
+      contrast(report);
+      landmarks(report);
+      duplicateIDs(report);
+      return violations;
+    },
+    // focusable counts the elements Tab can reach
+    focusable() {
+      const candidates = 'a[href], button, input:not([type=hidden]), select, textarea, iframe, [tabindex], [contenteditable=true]';
+      return [...document.querySelectorAll(candidates)].filter(
+        (el) => visible(el) && !el.disabled && el.tabIndex >= 0 && !el.closest('[inert], [aria-hidden=true]'),
+      ).length;
+    },
+    // active returns the selector of the focused element, empty when nothing on the page has focus
+    active() {
+      const el = document.activeElement;
+      return el && el !== document.body && el !== document.documentElement ? selectorOf(el) : '';
+    },
+  };
+})();
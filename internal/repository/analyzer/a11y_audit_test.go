@@ -0,0 +1,36 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestLimitViolations(t *testing.T) {
+	violations := []models.A11yViolation{{Rule: models.A11yLandmarks, Message: "page has no main landmark"}}
+	for i := 0; i < maxViolationsPerRule+3; i++ {
+		violations = append(violations, models.A11yViolation{Rule: models.A11yContrast, Selector: strings.Repeat("div > ", 50)})
+	}
+
+	limited := limitViolations(violations)
+	if len(limited) != maxViolationsPerRule+2 {
+		t.Fatalf("Expected %d contrast violations, the landmark one and a note, got %d", maxViolationsPerRule, len(limited))
+	}
+	if len(limited[1].Selector) != maxSelectorLength {
+		t.Errorf("Expected long selectors to be shortened, got %d characters", len(limited[1].Selector))
+	}
+	if note := limited[len(limited)-1]; note.Rule != models.A11yContrast || note.Message != "3 more contrast violations left out" {
+		t.Errorf("Expected a note on the left out violations, got %+v", note)
+	}
+}
+
+func TestAuditAccessibilityValidation(t *testing.T) {
+	if _, err := AuditAccessibility(context.Background(), nil); err == nil {
+		t.Error("Expected an error without URLs")
+	}
+	if _, err := AuditAccessibility(context.Background(), []string{"http://127.0.0.1/"}); err == nil {
+		t.Error("Expected local URLs to be rejected by the default policy")
+	}
+}
@@ -5,15 +5,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/webscopeio/ai-hackathon/internal/analytics"
 	"github.com/webscopeio/ai-hackathon/internal/config"
 	"github.com/webscopeio/ai-hackathon/internal/llm"
 	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/promptguard"
+	"github.com/webscopeio/ai-hackathon/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func Analyze(ctx context.Context, cfg *config.Config, client *llm.Client, urlStr string, prompt string) (*models.AnalyzerReturn, error) {
+// errToolRejected marks tool calls that fail validation, for example URLs outside the website under test
+var errToolRejected = errors.New("tool call rejected")
+
+// Analyze runs the analyzer agent in a span, every tool call gets a child span
+func Analyze(ctx context.Context, cfg *config.Config, client *llm.Client, urlStr string, prompt string) (result *models.AnalyzerReturn, err error) {
+	ctx, span := telemetry.StartSpan(telemetry.WithPhase(ctx, "analyzer"), "analyzer.Analyze", attribute.String("url", urlStr))
+	defer func() { telemetry.EndSpan(span, err) }()
+	log := logger.For(ctx, "analyzer")
+
 	userMessage := fmt.Sprintf("The website is: %s - %s", urlStr, prompt)
 	messages := []anthropic.MessageParam{
 		anthropic.NewUserMessage(anthropic.NewTextBlock(userMessage)),
@@ -21,16 +34,22 @@ func Analyze(ctx context.Context, cfg *config.Config, client *llm.Client, urlStr
 
 	sitemapTool, _ := llm.GenerateTool[models.SitemapTool]("sitemap_tool", "This tool is able to get a website's sitemap using a base URL")
 	getContentTool, _ := llm.GenerateTool[models.GetContentTool]("get_content_tool", "This tool is able to get the body content for a list of important URLs")
-	sentryTool, _ := llm.GenerateTool[models.SentryTool]("get_sentry_tool", "This tool is able to get error information from Sentry for a specific project to give you a better context about the website")
+	screenshotTool, _ := llm.GenerateTool[models.GetScreenshotTool]("get_screenshot_tool", "This tool is able to take screenshots of a list of important URLs at desktop and mobile viewports, showing the layout, visual prominence and content that is only visible in the rendered page. Use them to rank the criteria by what users see first: prominent content and calls to action above the fold come first")
+	a11yAuditTool, _ := llm.GenerateTool[models.GetA11yAuditTool]("get_a11y_audit_tool", "This tool is able to audit the accessibility of a list of important URLs in a real browser. It returns the violations of each page: form fields, buttons, links and images without labels, text with too little contrast, missing landmarks and skipped heading levels, duplicate IDs and keyboard traps. Critical and serious violations on key pages are good accessibility criteria, describe the affected elements by their selectors")
+	sentryTool, _ := llm.GenerateTool[models.SentryTool]("get_sentry_tool", "This tool is able to get error information from Sentry for a specific project to give you a better context about the website. The most frequent issues include the breadcrumbs (user actions) that led to the error")
+	sentryPathsTool, _ := llm.GenerateTool[models.SentryTool]("get_sentry_affected_paths_tool", "This tool is able to get the URL paths most affected by Sentry errors for a specific project, sorted by the number of occurrences")
 	finalCriteriaTool, _ := llm.GenerateTool[models.FinalCriteriaTool]("get_final_criteria_tool", "This tool is able to get the final criteria for the analysis of the website from results of the other tools, run this always as the last step")
 
 	toolParams := []anthropic.ToolParam{
 		*sitemapTool,
 		*getContentTool,
+		*screenshotTool,
+		*a11yAuditTool,
 		*sentryTool,
+		*sentryPathsTool,
 		{
 			Name:        "get_significant_user_flows",
-			Description: anthropic.String("This tool is very important to understand what are the most critical user flows. It will be super helpful to run it before generating a final criteria."),
+			Description: anthropic.String("This tool is very important to understand what are the most critical user flows. It will be super helpful to run it before generating a final criteria. Each flow is a sequence of page paths (dynamic segments are shown as :id) and custom user actions prefixed with event:, with the number and percentage of sessions following it."),
 			InputSchema: anthropic.ToolInputSchemaParam{
 				Type:       "object",
 				Properties: map[string]string{},
@@ -45,15 +64,150 @@ func Analyze(ctx context.Context, cfg *config.Config, client *llm.Client, urlStr
 	}
 
 	var contentMap map[string]string
+	var accessibility []models.PageA11yAudit
+
+	// The LLM picks the URLs of the tools, they have to stay on the analyzed site and the project domains
+	scope := promptguard.NewScope([]string{urlStr}, cfg.AllowedDomains)
+
+	// runTool executes a tool call, the final criteria tool ends the analysis with its result
+	runTool := func(ctx context.Context, variant anthropic.ToolUseBlock) (any, *models.AnalyzerReturn, error) {
+		var response any
+		switch variant.Name {
+		case sitemapTool.Name:
+			input := models.SitemapTool{}
+			err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if !scope.Contains(input.BaseUrl) {
+				return nil, nil, fmt.Errorf("%w: %s is outside the website under test", errToolRejected, input.BaseUrl)
+			}
+
+			response, err = GetSitemap(ctx, input.BaseUrl)
+			if err != nil {
+				return nil, nil, err
+			}
+		case getContentTool.Name:
+			input := models.GetContentTool{}
+			err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if outside := scope.OutOfScope(input.Urls); len(outside) > 0 {
+				return nil, nil, fmt.Errorf("%w: %s outside the website under test", errToolRejected, strings.Join(outside, ", "))
+			}
+
+			result, err := GetContent(ctx, input.Urls)
+			if err != nil {
+				return nil, nil, err
+			}
+			contentMap = result.Contents
+
+			// Pages go back to the model as delimited data blocks
+			wrapped, flagged := promptguard.WrapAll(result.Contents)
+			if len(flagged) > 0 {
+				log.Warn("instruction-like text in fetched pages", "urls", flagged)
+			}
+			response = models.GetContentToolReturn{Contents: wrapped}
+		case screenshotTool.Name:
+			input := models.GetScreenshotTool{}
+			err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if outside := scope.OutOfScope(input.Urls); len(outside) > 0 {
+				return nil, nil, fmt.Errorf("%w: %s outside the website under test", errToolRejected, strings.Join(outside, ", "))
+			}
+
+			response, err = GetScreenshots(ctx, input.Urls, cfg.ScreenshotViewports, input.FullPage, cfg.ScreenshotBudgetKB<<10)
+			if err != nil {
+				return nil, nil, err
+			}
+		case a11yAuditTool.Name:
+			input := models.GetA11yAuditTool{}
+			err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if outside := scope.OutOfScope(input.Urls); len(outside) > 0 {
+				return nil, nil, fmt.Errorf("%w: %s outside the website under test", errToolRejected, strings.Join(outside, ", "))
+			}
+
+			result, err := AuditAccessibility(ctx, input.Urls)
+			if err != nil {
+				return nil, nil, err
+			}
+			accessibility = append(accessibility, result.Pages...)
+			response = result
+		case sentryTool.Name:
+			input := models.SentryTool{}
+			err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			response, err = GetSentryIssues(ctx, cfg, input)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get Sentry issues: %w", err)
+			}
+		case sentryPathsTool.Name:
+			input := models.SentryTool{}
+			err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			response, err = GetAffectedSentryPaths(ctx, cfg, input)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get Sentry affected paths: %w", err)
+			}
+		case "get_significant_user_flows":
+			// We analyze the significant user flows based on fixed criteria
+			source, err := analytics.New(cfg)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create analytics source: %w", err)
+			}
+			response, err = GetSignificantUserFlows(ctx, source, 7, 2, 2)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get significant user flows: %w", err)
+			}
+		case finalCriteriaTool.Name:
+			log.Debug("final criteria tool call", "input", variant.JSON.Input.Raw())
+			input := models.FinalCriteriaTool{}
+			err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
+			if err != nil {
+				return nil, nil, err
+			}
 
-	fmt.Println("\n[ANALYZER] User Message: \n\n", userMessage)
+			log.Debug("final content map", "content_map", input.ContentMap)
+
+			return nil, &models.AnalyzerReturn{
+				TechSpec:   prompt,
+				ContentMap: contentMap,
+				Criteria:   input.Criteria,
+				// The audits are kept for the generator of accessibility tests
+				Accessibility: accessibility,
+			}, nil
+		}
+
+		return response, nil, nil
+	}
+
+	log.Info("starting analysis", "message", userMessage)
 
 	for {
 		message, err := client.NewMessage(ctx, anthropic.MessageNewParams{
 			Model:     anthropic.ModelClaude3_5SonnetLatest,
 			MaxTokens: 2048,
-			Messages:  messages,
-			Tools:     tools,
+			System: []anthropic.TextBlockParam{
+				{Type: "text", Text: promptguard.Instructions},
+			},
+			Messages: messages,
+			Tools:    tools,
 		})
 		if err != nil {
 			return nil, err
@@ -63,10 +217,10 @@ func Analyze(ctx context.Context, cfg *config.Config, client *llm.Client, urlStr
 		for _, block := range message.Content {
 			switch block := block.AsAny().(type) {
 			case anthropic.TextBlock:
-				fmt.Printf("\n[ANALYZER] Agent response: \n\n%s\n", block.Text)
+				log.Info("agent response", "text", block.Text)
 			case anthropic.ToolUseBlock:
 				inputJSON, _ := json.Marshal(block.Input)
-				fmt.Printf("\n[ANALYZER] Tool call: \n\n%s\n", block.Name+": "+string(inputJSON))
+				log.Info("tool call", "tool", block.Name, "input", string(inputJSON))
 			}
 		}
 
@@ -76,65 +230,31 @@ func Analyze(ctx context.Context, cfg *config.Config, client *llm.Client, urlStr
 		for _, block := range message.Content {
 			switch variant := block.AsAny().(type) {
 			case anthropic.ToolUseBlock:
-				var response any
-				// fmt.Println("Running block", block.Name)
-				switch block.Name {
-				case sitemapTool.Name:
-					input := models.SitemapTool{}
-					err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
-					if err != nil {
-						return nil, err
-					}
-
-					response, err = GetSitemap(ctx, input.BaseUrl)
-					if err != nil {
-						return nil, err
-					}
-				case getContentTool.Name:
-					input := models.GetContentTool{}
-					err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
-					if err != nil {
-						return nil, err
-					}
-
-					result, err := GetContent(ctx, input.Urls)
-					if err != nil {
-						return nil, err
-					}
-					contentMap = result.Contents
-					response = result
-				case sentryTool.Name:
-					input := models.SentryTool{}
-					err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
-					if err != nil {
-						return nil, err
-					}
+				toolCtx, span := telemetry.StartSpan(ctx, "tool "+variant.Name, attribute.String("tool.name", variant.Name))
+				response, final, err := runTool(toolCtx, variant)
+				telemetry.ObserveToolCall(variant.Name, err)
+				telemetry.EndSpan(span, err)
+				if errors.Is(err, errToolRejected) {
+					// The model gets the rejection as the tool result and can correct the call
+					log.Warn("rejected tool call", "tool", variant.Name, "error", err)
+					toolResults = append(toolResults, anthropic.NewToolResultBlock(block.ID, err.Error(), true))
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				if final != nil {
+					return final, nil
+				}
 
-					response, err = GetSentryIssues(ctx, cfg, input.OrgSlug, input.ProjectSlug)
-					if err != nil {
-						return nil, fmt.Errorf("failed to get Sentry issues: %w", err)
-					}
-				case "get_significant_user_flows":
-					// We analyze the significant user flows based on fixed criteria
-					response, err = GetSignificantUserFlows(ctx, cfg, 7, 2, 2)
-					if err != nil {
-						return nil, fmt.Errorf("failed to get significant user flows: %w", err)
-					}
-				case finalCriteriaTool.Name:
-					logger.Debug("FROM ANALYZE: Final criteria tool raw: %s", variant.JSON.Input.Raw())
-					input := models.FinalCriteriaTool{}
-					err := json.Unmarshal([]byte(variant.JSON.Input.Raw()), &input)
+				// Screenshots go back as image blocks
+				if screenshots, ok := response.(*models.GetScreenshotToolReturn); ok {
+					result, err := screenshotToolResult(block.ID, screenshots)
 					if err != nil {
 						return nil, err
 					}
-
-					logger.Debug("FROM ANALYZE: Final contentMap: %s", input.ContentMap)
-
-					return &models.AnalyzerReturn{
-						TechSpec:   prompt,
-						ContentMap: contentMap,
-						Criteria:   input.Criteria,
-					}, nil
+					toolResults = append(toolResults, result)
+					continue
 				}
 
 				b, err := json.Marshal(response)
@@ -0,0 +1,170 @@
+package analyzer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+const (
+	// eventStepPrefix marks a custom event step in a user flow
+	eventStepPrefix = "event:"
+	// maxPatternLength bounds the length of mined flow patterns
+	maxPatternLength = 10
+)
+
+var (
+	numericSegmentRegex = regexp.MustCompile(`^\d+$`)
+	uuidSegmentRegex    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexSegmentRegex     = regexp.MustCompile(`^[0-9a-fA-F]{12,}$`)
+)
+
+// buildUserFlows constructs user flows from analytics sessions
+// Page views become normalized paths and custom events become "event:<name>" steps.
+// Consecutive duplicate steps such as reloads are collapsed.
+func buildUserFlows(sessions []models.AnalyticsSession) []models.UmamiUserFlow {
+	var userFlows []models.UmamiUserFlow
+
+	for _, session := range sessions {
+		if len(session.Steps) == 0 {
+			continue // Skip sessions with no activities
+		}
+
+		// Sort steps by time
+		sessionSteps := append([]models.AnalyticsStep(nil), session.Steps...)
+		sort.SliceStable(sessionSteps, func(i, j int) bool {
+			return sessionSteps[i].Timestamp.Before(sessionSteps[j].Timestamp)
+		})
+
+		steps := make([]string, 0, len(sessionSteps))
+		lastPage := ""
+		addStep := func(step string) {
+			if len(steps) > 0 && steps[len(steps)-1] == step {
+				return
+			}
+			steps = append(steps, step)
+		}
+
+		for _, step := range sessionSteps {
+			page := NormalizeFlowPath(step.Path)
+
+			if step.Event != "" {
+				// Events fired before any page view still need the page they happened on
+				if page != lastPage {
+					addStep(page)
+					lastPage = page
+				}
+				addStep(eventStepPrefix + step.Event)
+				continue
+			}
+
+			addStep(page)
+			lastPage = page
+		}
+
+		userFlows = append(userFlows, models.UmamiUserFlow{
+			SessionID: session.ID,
+			Path:      steps,
+		})
+	}
+
+	return userFlows
+}
+
+// NormalizeFlowPath collapses dynamic path segments such as IDs into ":id"
+func NormalizeFlowPath(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if numericSegmentRegex.MatchString(segment) || uuidSegmentRegex.MatchString(segment) || hexSegmentRegex.MatchString(segment) {
+			segments[i] = ":id"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// findSignificantFlows identifies common patterns in user flows
+// A pattern is a contiguous sequence of steps and is counted at most once per session.
+// Only closed patterns are kept: a pattern is dropped if a longer pattern containing it
+// is followed by the same sessions, so overlapping fragments of one flow collapse into it.
+func findSignificantFlows(userFlows []models.UmamiUserFlow, minPathLength, minFrequency int) []models.UmamiSignificantFlow {
+	// Count the sessions following each path pattern
+	pathCounts := make(map[string]int)
+	pathArrays := make(map[string][]string)
+
+	for _, flow := range userFlows {
+		// Skip flows that are too short
+		if len(flow.Path) < minPathLength {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for i := 0; i <= len(flow.Path)-minPathLength; i++ {
+			for j := i + minPathLength; j <= len(flow.Path) && j-i <= maxPatternLength; j++ {
+				subPath := flow.Path[i:j]
+				pathKey := strings.Join(subPath, "|")
+				if seen[pathKey] {
+					continue
+				}
+				seen[pathKey] = true
+				pathCounts[pathKey]++
+				pathArrays[pathKey] = subPath
+			}
+		}
+	}
+
+	// A pattern that has a one step longer extension with the same support is not closed
+	notClosed := make(map[string]bool)
+	for pathKey, count := range pathCounts {
+		path := pathArrays[pathKey]
+		if count < minFrequency || len(path) <= minPathLength {
+			continue
+		}
+		for _, sub := range [][]string{path[1:], path[:len(path)-1]} {
+			subKey := strings.Join(sub, "|")
+			if pathCounts[subKey] == count {
+				notClosed[subKey] = true
+			}
+		}
+	}
+
+	// Convert to significant flows
+	significantFlows := []models.UmamiSignificantFlow{}
+	totalFlows := len(userFlows)
+
+	for pathKey, count := range pathCounts {
+		// Skip paths that don't meet the minimum frequency
+		if count < minFrequency || notClosed[pathKey] {
+			continue
+		}
+
+		percentage := float64(count) / float64(totalFlows) * 100.0
+
+		significantFlows = append(significantFlows, models.UmamiSignificantFlow{
+			Path:       pathArrays[pathKey],
+			Frequency:  count,
+			Percentage: percentage,
+		})
+	}
+
+	// Sort by frequency (descending), then by length and path for a stable order
+	sort.Slice(significantFlows, func(i, j int) bool {
+		a, b := significantFlows[i], significantFlows[j]
+		if a.Frequency != b.Frequency {
+			return a.Frequency > b.Frequency
+		}
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		return strings.Join(a.Path, "|") < strings.Join(b.Path, "|")
+	})
+
+	return significantFlows
+}
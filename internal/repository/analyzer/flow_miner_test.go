@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestBuildUserFlows(t *testing.T) {
+	at := func(second int) time.Time {
+		return time.Date(2025, 4, 1, 10, 0, second, 0, time.UTC)
+	}
+	sessions := []models.AnalyticsSession{
+		{ID: "a", Steps: []models.AnalyticsStep{
+			{Timestamp: at(3), Path: "/products/42"},
+			{Timestamp: at(1), Path: "/"},
+			{Timestamp: at(2), Path: "/"},
+			{Timestamp: at(4), Path: "/products/42", Event: "signup-click"},
+			{Timestamp: at(5), Path: "/orders/3f2b8c1e-1d2a-4c3b-9a8b-7c6d5e4f3a2b/"},
+		}},
+		{ID: "empty"},
+	}
+
+	flows := buildUserFlows(sessions)
+	if len(flows) != 1 {
+		t.Fatalf("Expected 1 flow, got %d", len(flows))
+	}
+
+	expected := []string{"/", "/products/:id", "event:signup-click", "/orders/:id"}
+	if !reflect.DeepEqual(flows[0].Path, expected) {
+		t.Errorf("Expected %v, got %v", expected, flows[0].Path)
+	}
+}
+
+func TestFindSignificantFlows(t *testing.T) {
+	flows := []models.UmamiUserFlow{
+		{SessionID: "1", Path: []string{"/", "/pricing", "event:signup-click", "/signup"}},
+		{SessionID: "2", Path: []string{"/", "/pricing", "event:signup-click", "/signup"}},
+		{SessionID: "3", Path: []string{"/", "/pricing", "/", "/pricing"}},
+		{SessionID: "4", Path: []string{"/blog"}},
+	}
+
+	significant := findSignificantFlows(flows, 2, 2)
+	if len(significant) != 2 {
+		t.Fatalf("Expected 2 closed flows, got %d: %+v", len(significant), significant)
+	}
+
+	// The repeated fragment in session 3 is counted once
+	if !reflect.DeepEqual(significant[0].Path, []string{"/", "/pricing"}) || significant[0].Frequency != 3 {
+		t.Errorf("Expected / -> /pricing followed by 3 sessions, got %+v", significant[0])
+	}
+
+	// Fragments of the full signup flow are not reported on their own
+	if !reflect.DeepEqual(significant[1].Path, []string{"/", "/pricing", "event:signup-click", "/signup"}) || significant[1].Frequency != 2 {
+		t.Errorf("Expected the full signup flow followed by 2 sessions, got %+v", significant[1])
+	}
+
+	for _, flow := range significant {
+		if flow.Percentage > 100 {
+			t.Errorf("Expected percentage to be at most 100, got %f", flow.Percentage)
+		}
+	}
+	if significant[0].Percentage != 75 {
+		t.Errorf("Expected 75%% of sessions, got %f", significant[0].Percentage)
+	}
+}
@@ -4,15 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/url"
 	"regexp"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/chromedp/chromedp"
 	"github.com/gocolly/colly/v2"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
 )
 
 func GetContent_OLD(ctx context.Context, urls []string) (*models.GetContentToolReturn, error) {
@@ -20,28 +20,17 @@ func GetContent_OLD(ctx context.Context, urls []string) (*models.GetContentToolR
 		return nil, errors.New("empty URLs list provided")
 	}
 
+	policy := netguard.FromContext(ctx)
 	validatedUrls := make([]string, 0, len(urls))
 	for _, urlStr := range urls {
 		if urlStr == "" {
 			continue
 		}
 
-		parsedURL, err := url.Parse(urlStr)
+		parsedURL, err := policy.CheckString(ctx, urlStr)
 		if err != nil {
 			continue
 		}
-
-		if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-			if strings.Contains(parsedURL.String(), "localhost") || strings.Contains(parsedURL.String(), ":") {
-				urlStr = "http://" + urlStr
-			} else {
-				urlStr = "https://" + urlStr
-			}
-			parsedURL, err = url.Parse(urlStr)
-			if err != nil {
-				continue
-			}
-		}
 		validatedUrls = append(validatedUrls, parsedURL.String())
 	}
 
@@ -60,6 +49,8 @@ func GetContent_OLD(ctx context.Context, urls []string) (*models.GetContentToolR
 	c := colly.NewCollector(
 		colly.Async(true),
 	)
+	c.WithTransport(policy.Transport())
+	c.SetRedirectHandler(policy.CheckRedirect)
 
 	c.Limit(&colly.LimitRule{
 		DomainGlob:  "*",
@@ -120,7 +111,7 @@ func GetContent_OLD(ctx context.Context, urls []string) (*models.GetContentToolR
 		return nil, errors.New("failed to fetch content from any URL")
 	}
 
-	fmt.Println("results are ready", len(results))
+	logger.For(ctx, "crawler").Debug("results are ready", "urls", len(results))
 
 	return &models.GetContentToolReturn{
 		Contents: results,
@@ -130,29 +121,24 @@ func GetContent_OLD(ctx context.Context, urls []string) (*models.GetContentToolR
 // GetContentCdp uses Chrome DevTools Protocol via chromedp to fetch content from URLs
 // This is especially useful for SPAs and JavaScript-heavy websites
 func GetContent(ctx context.Context, urls []string) (*models.GetContentToolReturn, error) {
+	log := logger.For(ctx, "crawler")
+
 	if len(urls) == 0 {
 		return nil, errors.New("empty URLs list provided")
 	}
 
-	// Validate and normalize URLs
+	// Validate and normalize URLs, URLs without a scheme get https
+	// The LLM picks the URLs, so each one is checked against the outbound URL policy
+	policy := netguard.FromContext(ctx)
 	validatedUrls := make([]string, 0, len(urls))
 	for _, urlStr := range urls {
 		if urlStr == "" {
 			continue
 		}
 
-		// Ensure URL has a scheme
-		if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
-			if strings.Contains(urlStr, "localhost") || strings.Contains(urlStr, ":") {
-				urlStr = "http://" + urlStr
-			} else {
-				urlStr = "https://" + urlStr
-			}
-		}
-
-		parsedURL, err := url.Parse(urlStr)
+		parsedURL, err := policy.CheckString(ctx, urlStr)
 		if err != nil {
-			fmt.Printf("Warning: Could not parse URL %s: %v\n", urlStr, err)
+			log.Warn("skipping URL", "url", urlStr, "error", err)
 			continue
 		}
 
@@ -179,6 +165,11 @@ func GetContent(ctx context.Context, urls []string) (*models.GetContentToolRetur
 	browserCtx, cancel := chromedp.NewContext(ctx)
 	defer cancel()
 
+	// Redirects and subresources of the pages are checked by the browser
+	if err := chromedp.Run(browserCtx, policy.Browser()); err != nil {
+		return nil, fmt.Errorf("failed to start the browser: %w", err)
+	}
+
 	// Process URLs sequentially to avoid overwhelming the browser
 	for _, urlStr := range validatedUrls {
 		// Create a timeout context for each URL
@@ -204,7 +195,7 @@ func GetContent(ctx context.Context, urls []string) (*models.GetContentToolRetur
 		)		
 		
 		if err != nil {
-			fmt.Printf("Error fetching %s with chromedp: %v\n", urlStr, err)
+			log.Warn("failed to fetch page with chromedp", "url", urlStr, "error", err)
 			
 			// Store empty string for failed URLs
 			mutex.Lock()
@@ -223,7 +214,7 @@ func GetContent(ctx context.Context, urls []string) (*models.GetContentToolRetur
 		results[urlStr] = bodyHTML
 		mutex.Unlock()
 		
-		fmt.Printf("Successfully fetched %s with chromedp\n", urlStr)
+		log.Debug("fetched page with chromedp", "url", urlStr)
 	}
 
 	// Check if we got any results
@@ -231,7 +222,7 @@ func GetContent(ctx context.Context, urls []string) (*models.GetContentToolRetur
 		return nil, errors.New("failed to fetch content from any URL")
 	}
 
-	fmt.Printf("Results are ready, found data for %d URLs\n", len(results))
+	log.Info("fetched page content", "urls", len(results))
 
 	return &models.GetContentToolReturn{
 		Contents: results,
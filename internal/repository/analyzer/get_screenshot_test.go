@@ -0,0 +1,46 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestScreenshotToolResult(t *testing.T) {
+	result := &models.GetScreenshotToolReturn{
+		Screenshots: []models.PageScreenshot{
+			{URL: "https://example.com/", Viewport: "desktop", Kind: ScreenshotAboveTheFold, Bytes: 3, Data: []byte("jpg")},
+			{URL: "https://example.com/", Viewport: "mobile", Kind: ScreenshotAboveTheFold, Bytes: 3, Data: []byte("jpg")},
+		},
+		Skipped: []string{"full_page https://example.com/ at desktop: over the image budget"},
+	}
+
+	block, err := screenshotToolResult("tool-1", result)
+	if err != nil {
+		t.Fatalf("screenshotToolResult failed: %v", err)
+	}
+
+	toolResult := block.OfRequestToolResultBlock
+	if toolResult == nil || toolResult.ToolUseID != "tool-1" || len(toolResult.Content) != 3 {
+		t.Fatalf("Expected a tool result with a summary and two images, got %+v", toolResult)
+	}
+	summary := toolResult.Content[0].OfRequestTextBlock
+	if summary == nil || !strings.Contains(summary.Text, `"viewport":"mobile"`) || !strings.Contains(summary.Text, "over the image budget") || strings.Contains(summary.Text, `"Data"`) {
+		t.Errorf("Expected the summary of the screenshots without their data, got %+v", summary)
+	}
+	if image := toolResult.Content[2].OfRequestImageBlock; image == nil || image.Source.OfBase64ImageSource.Data != "anBn" {
+		t.Errorf("Expected the base64 encoded image, got %+v", image)
+	}
+}
+
+func TestGetScreenshotsValidation(t *testing.T) {
+	viewports := []models.Viewport{{Name: "desktop", Width: 1280, Height: 800}}
+	if _, err := GetScreenshots(context.Background(), nil, viewports, false, 1<<20); err == nil {
+		t.Error("Expected an error without URLs")
+	}
+	if _, err := GetScreenshots(context.Background(), []string{"http://127.0.0.1/"}, viewports, false, 1<<20); err == nil {
+		t.Error("Expected local URLs to be rejected by the default policy")
+	}
+}
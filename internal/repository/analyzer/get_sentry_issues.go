@@ -2,145 +2,66 @@ package analyzer
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"sort"
+	"strconv"
 
 	"github.com/webscopeio/ai-hackathon/internal/config"
 	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/sentry"
 )
 
+// maxBreadcrumbIssues is the number of top issues the latest event breadcrumbs are fetched for
+const maxBreadcrumbIssues = 5
+
 // GetSentryIssues retrieves issues from a Sentry project using the Sentry API
 // It requires organization/project slugs and gets the auth token from config
-func GetSentryIssues(ctx context.Context, cfg *config.Config, orgSlug, projectSlug string) ([]models.SentryIssue, error) {
-	logger.Debug("Getting Sentry issues for project: %s/%s", orgSlug, projectSlug)
-
-	// Get auth token from config
-	authToken := cfg.SentryAuthToken
-	if authToken == "" {
-		return nil, fmt.Errorf("sentry auth token not configured")
-	}
+// The top issues by event count are enriched with the breadcrumbs of their latest event
+func GetSentryIssues(ctx context.Context, cfg *config.Config, input models.SentryTool) ([]models.SentryIssueWithBreadcrumbs, error) {
+	client := sentry.New(cfg)
 
-	// Construct the API URL
-	apiURL := fmt.Sprintf("https://sentry.io/api/0/projects/%s/%s/issues/", orgSlug, projectSlug)
-
-	// Create a new request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	issues, err := client.Issues(ctx, input.OrgSlug, input.ProjectSlug, issuesQuery(input))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	// Add authorization header
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", authToken))
-	req.Header.Add("Content-Type", "application/json")
-
-	// Add query parameters if needed
-	query := url.Values{}
-	query.Add("statsPeriod", "14d")     // Get issues from the last 14 days
-	query.Add("query", "is:unresolved") // Only get unresolved issues
-	req.URL.RawQuery = query.Encode()
-
-	// Make the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	result := make([]models.SentryIssueWithBreadcrumbs, len(issues))
+	for i, issue := range issues {
+		result[i] = models.SentryIssueWithBreadcrumbs{SentryIssue: issue}
 	}
-	defer resp.Body.Close()
 
-	// Check the response status code
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned non-OK status: %d, body: %s", resp.StatusCode, string(body))
-	}
+	// Sort by event count so the breadcrumbs are fetched for the most frequent issues
+	sort.SliceStable(result, func(i, j int) bool {
+		return issueCount(result[i].SentryIssue) > issueCount(result[j].SentryIssue)
+	})
 
-	// Read and parse the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
+	for i := range result {
+		if i >= maxBreadcrumbIssues {
+			break
+		}
 
-	// Unmarshal the JSON response
-	var issues []models.SentryIssue
-	err = json.Unmarshal(body, &issues)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+		event, err := client.LatestEvent(ctx, input.OrgSlug, result[i].ID)
+		if err != nil {
+			logger.For(ctx, "analyzer").Warn("failed to get latest event", "issue_id", result[i].ID, "error", err)
+			// Continue with the next issue even if this one fails
+			continue
+		}
+		result[i].Breadcrumbs = sentry.Breadcrumbs(event)
 	}
 
-	logger.Debug("Retrieved %d Sentry issues", len(issues))
-	return issues, nil
+	return result, nil
 }
 
 // GetSentryIssueTagDetails retrieves details for a specific tag of an issue
 func GetSentryIssueTagDetails(ctx context.Context, cfg *config.Config, orgSlug, issueID, tagKey string) (*models.SentryTagDetails, error) {
-	logger.Debug("Getting tag details for Sentry issue: %s, tag: %s in organization: %s", issueID, tagKey, orgSlug)
-
-	// Get auth token from config
-	authToken := cfg.SentryAuthToken
-	if authToken == "" {
-		return nil, fmt.Errorf("sentry auth token not configured")
-	}
-
-	// Construct the API URL
-	apiURL := fmt.Sprintf("https://sentry.io/api/0/organizations/%s/issues/%s/tags/%s/", orgSlug, issueID, tagKey)
-	logger.Debug("Requesting URL: %s", apiURL)
-
-	// Create a new request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Add authorization header
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", authToken))
-	req.Header.Add("Content-Type", "application/json")
-
-	// Make the request
-	client := &http.Client{}
-	logger.Debug("Sending request to Sentry API for issue tag details...")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check the response status code
-	logger.Debug("Received response with status code: %d", resp.StatusCode)
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		logger.Debug("Error response body: %s", string(body))
-		return nil, fmt.Errorf("API returned non-OK status: %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	// Read and parse the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// Log the response body for debugging
-	logger.Debug("Response body: %s", string(body))
-
-	// Unmarshal the JSON response
-	var tagDetails models.SentryTagDetails
-	err = json.Unmarshal(body, &tagDetails)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
-	}
-
-	logger.Debug("Retrieved tag details for issue %s, tag: %s with %d unique values",
-		issueID, tagKey, tagDetails.UniqueValues)
-	return &tagDetails, nil
+	return sentry.New(cfg).IssueTagDetails(ctx, orgSlug, issueID, tagKey)
 }
 
 // GetSentryIssueTagValuesSorted retrieves all unique values for a specific tag of an issue,
 // sorted by occurrence count (most frequent first)
 func GetSentryIssueTagValuesSorted(ctx context.Context, cfg *config.Config, orgSlug, issueID, tagKey string) ([]models.SentryTagValueSorted, error) {
-	logger.Debug("Getting sorted tag values for Sentry issue: %s, tag: %s", issueID, tagKey)
+	logger.For(ctx, "analyzer").Debug("getting sorted tag values", "issue_id", issueID, "tag", tagKey)
 
 	// First get the tag details
 	tagDetails, err := GetSentryIssueTagDetails(ctx, cfg, orgSlug, issueID, tagKey)
@@ -165,25 +86,27 @@ func GetSentryIssueTagValuesSorted(ctx context.Context, cfg *config.Config, orgS
 		return sortedValues[i].Count > sortedValues[j].Count
 	})
 
-	logger.Debug("Retrieved and sorted %d tag values for issue %s, tag: %s",
-		len(sortedValues), issueID, tagKey)
+	logger.For(ctx, "analyzer").Debug("retrieved sorted tag values", "issue_id", issueID, "tag", tagKey, "count", len(sortedValues))
 	return sortedValues, nil
 }
 
 // GetAffectedSentryPaths retrieves a list of URL paths affected by errors from Sentry
 // The paths are sorted by occurrence count (most frequent first)
-func GetAffectedSentryPaths(ctx context.Context, cfg *config.Config, orgSlug, projectSlug string) ([]models.SentryAffectedPath, error) {
-	logger.Debug("Getting affected URL paths from Sentry for organization: %s, project: %s", orgSlug, projectSlug)
+func GetAffectedSentryPaths(ctx context.Context, cfg *config.Config, input models.SentryTool) ([]models.SentryAffectedPath, error) {
+	log := logger.For(ctx, "analyzer")
+	log.Debug("getting affected URL paths", "org", input.OrgSlug, "project", input.ProjectSlug)
+
+	client := sentry.New(cfg)
 
 	// First get the issues
-	issues, err := GetSentryIssues(ctx, cfg, orgSlug, projectSlug)
+	issues, err := client.Issues(ctx, input.OrgSlug, input.ProjectSlug, issuesQuery(input))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get issues: %w", err)
 	}
 
-	logger.Debug("Retrieved %d issues, now fetching URL tag details for each", len(issues))
+	log.Debug("fetching URL tag details", "issues", len(issues))
 	if len(issues) == 0 {
-		logger.Debug("No issues found to fetch URL paths for")
+		log.Debug("no issues found to fetch URL paths for")
 		return []models.SentryAffectedPath{}, nil
 	}
 
@@ -198,17 +121,17 @@ func GetAffectedSentryPaths(ctx context.Context, cfg *config.Config, orgSlug, pr
 
 	// For each issue, get URL tag details
 	for i, issue := range issues[:maxIssues] {
-		logger.Debug("Processing issue %d/%d: ID=%s, ShortID=%s", i+1, maxIssues, issue.ID, issue.ShortID)
+		log.Debug("processing issue", "position", i+1, "total", maxIssues, "issue_id", issue.ID, "short_id", issue.ShortID)
 
 		// Get URL tag details for this issue
-		tagDetails, err := GetSentryIssueTagDetails(ctx, cfg, orgSlug, issue.ID, "url")
+		tagDetails, err := client.IssueTagDetails(ctx, input.OrgSlug, issue.ID, "url")
 		if err != nil {
-			logger.Debug("Failed to get URL tag details for issue %s: %v", issue.ID, err)
+			log.Warn("failed to get URL tag details", "issue_id", issue.ID, "error", err)
 			// Continue with the next issue even if this one fails
 			continue
 		}
 
-		logger.Debug("Retrieved %d URL values for issue %s", len(tagDetails.TopValues), issue.ID)
+		log.Debug("retrieved URL values", "issue_id", issue.ID, "count", len(tagDetails.TopValues))
 
 		// Add the URL values to our aggregate map
 		for _, value := range tagDetails.TopValues {
@@ -230,7 +153,25 @@ func GetAffectedSentryPaths(ctx context.Context, cfg *config.Config, orgSlug, pr
 		return affectedPaths[i].Count > affectedPaths[j].Count
 	})
 
-	logger.Debug("Retrieved and aggregated %d affected URL paths across %d issues",
-		len(affectedPaths), maxIssues)
+	log.Debug("aggregated affected URL paths", "paths", len(affectedPaths), "issues", maxIssues)
 	return affectedPaths, nil
 }
+
+// issuesQuery converts the tool input to the Sentry client filters
+func issuesQuery(input models.SentryTool) sentry.IssuesQuery {
+	return sentry.IssuesQuery{
+		Period:      input.Period,
+		Query:       input.Query,
+		Environment: input.Environment,
+		Release:     input.Release,
+	}
+}
+
+// issueCount parses the event count of an issue, which Sentry returns as a string
+func issueCount(issue models.SentryIssue) int {
+	count, err := strconv.Atoi(issue.Count)
+	if err != nil {
+		return 0
+	}
+	return count
+}
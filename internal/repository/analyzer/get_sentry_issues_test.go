@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
 )
 
 func TestGetSentryIssues(t *testing.T) {
@@ -27,7 +28,7 @@ func TestGetSentryIssues(t *testing.T) {
 	projectSlug := "ai-hackathon-demo"
 
 	// Call the function with context and config
-	issues, err := GetSentryIssues(context.Background(), cfg, orgSlug, projectSlug)
+	issues, err := GetSentryIssues(context.Background(), cfg, models.SentryTool{OrgSlug: orgSlug, ProjectSlug: projectSlug})
 	if err != nil {
 		t.Logf("Note: This test requires valid Sentry credentials to pass")
 		t.Logf("Error getting Sentry issues: %v", err)
@@ -70,7 +71,7 @@ func TestGetSentryIssuesWithTagDetails(t *testing.T) {
 	tagKey := "url"
 
 	// Call the function to get issues
-	issues, err := GetSentryIssues(context.Background(), cfg, orgSlug, projectSlug)
+	issues, err := GetSentryIssues(context.Background(), cfg, models.SentryTool{OrgSlug: orgSlug, ProjectSlug: projectSlug})
 	if err != nil {
 		t.Logf("Note: This test requires valid Sentry credentials to pass")
 		t.Logf("Error getting Sentry issues: %v", err)
@@ -182,7 +183,7 @@ func TestGetAffectedSentryPaths(t *testing.T) {
 	projectSlug := "ai-hackathon-demo"
 
 	// Call the function with context and config
-	affectedPaths, err := GetAffectedSentryPaths(context.Background(), cfg, orgSlug, projectSlug)
+	affectedPaths, err := GetAffectedSentryPaths(context.Background(), cfg, models.SentryTool{OrgSlug: orgSlug, ProjectSlug: projectSlug})
 	if err != nil {
 		t.Logf("Note: This test requires valid Sentry credentials to pass")
 		t.Logf("Error getting affected Sentry paths: %v", err)
@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/webscopeio/ai-hackathon/internal/analytics"
 	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/umami"
 )
 
 func TestGetUmamiSessions(t *testing.T) {
@@ -32,14 +34,10 @@ func TestGetUmamiSessions(t *testing.T) {
 	endDate := time.Now()
 	startDate := endDate.AddDate(0, 0, -7)
 
-	// Get the API key
-	apiKey, err := getUmamiAPIKey(context.Background(), cfg)
-	if err != nil {
-		t.Fatalf("Failed to get Umami API key: %v", err)
-	}
+	client := umami.New(cfg)
 
-	// Call the function with context, config, and date range
-	sessions, err := getUmamiSessions(context.Background(), cfg, apiKey, startDate, endDate)
+	// Call the function with context and date range
+	sessions, err := client.Sessions(context.Background(), startDate, endDate)
 	if err != nil {
 		t.Logf("Note: This test requires valid Umami credentials to pass")
 		t.Fatalf("Error getting Umami sessions: %v", err)
@@ -58,7 +56,7 @@ func TestGetUmamiSessions(t *testing.T) {
 		t.Logf("Session %d:\n%s", i+1, string(sessionJSON))
 
 		// Get activities for this session
-		activities, err := getSessionPageViews(context.Background(), cfg, apiKey, session.ID)
+		activities, err := client.SessionActivity(context.Background(), session, startDate, endDate)
 		if err != nil {
 			t.Logf("Error getting activities for session %s: %v", session.ID, err)
 			continue
@@ -98,7 +96,7 @@ func TestGetUserPaths(t *testing.T) {
 	}
 
 	// Call the function with context, config, and days back
-	userPaths, err := GetUserPaths(context.Background(), cfg, 7) // Get paths for the last 7 days
+	userPaths, err := GetUserPaths(context.Background(), analytics.NewUmamiSource(umami.New(cfg)), 7) // Get paths for the last 7 days
 	if err != nil {
 		t.Logf("Note: This test requires valid Umami credentials to pass")
 		t.Fatalf("Error getting user paths: %v", err)
@@ -137,7 +135,7 @@ func TestGetSignificantUserFlows(t *testing.T) {
 	}
 
 	// Call the function with context, config, and parameters
-	significantFlows, err := GetSignificantUserFlows(context.Background(), cfg, 7, 2, 2) // 7 days back, min path length 2, min frequency 2
+	significantFlows, err := GetSignificantUserFlows(context.Background(), analytics.NewUmamiSource(umami.New(cfg)), 7, 2, 2) // 7 days back, min path length 2, min frequency 2
 	if err != nil {
 		t.Logf("Note: This test requires valid Umami credentials to pass")
 		t.Fatalf("Error getting significant user flows: %v", err)
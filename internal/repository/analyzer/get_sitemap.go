@@ -6,27 +6,23 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
 
 	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
 )
 
 // GetSitemap attempts to retrieve and parse a sitemap from a given URL
 // It tries common sitemap locations if not explicitly provided
 func GetSitemap(ctx context.Context, baseURL string) (*models.Sitemap, error) {
-	logger.Debug("Getting sitemap for URL: %s", baseURL)
+	log := logger.For(ctx, "crawler")
+	log.Debug("getting sitemap", "url", baseURL)
 
-	// Parse the base URL
-	parsedURL, err := url.Parse(baseURL)
+	// Parse the base URL, it gets https without a scheme
+	parsedURL, err := netguard.FromContext(ctx).CheckString(ctx, baseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse URL: %w", err)
-	}
-
-	// Ensure the URL has a scheme
-	if parsedURL.Scheme == "" {
-		parsedURL.Scheme = "https"
+		return nil, fmt.Errorf("failed to check URL: %w", err)
 	}
 
 	// Create a list of potential sitemap URLs to check
@@ -47,25 +43,25 @@ func GetSitemap(ctx context.Context, baseURL string) (*models.Sitemap, error) {
 
 	// Try each potential sitemap URL
 	for _, sitemapURL := range sitemapURLs {
-		logger.Debug("Trying sitemap URL: %s", sitemapURL)
+		log.Debug("trying sitemap", "url", sitemapURL)
 
 		// Try to get the sitemap
 		sitemap, err := fetchSitemap(ctx, sitemapURL)
 		if err == nil && sitemap != nil && len(sitemap.URLs) > 0 {
-			logger.Debug("Found sitemap at %s with %d URLs", sitemapURL, len(sitemap.URLs))
+			log.Debug("found sitemap", "url", sitemapURL, "urls", len(sitemap.URLs))
 			return sitemap, nil
 		}
 
 		// If we get a sitemap index instead, try to get the first sitemap from it
 		sitemapIndex, err := fetchSitemapIndex(ctx, sitemapURL)
 		if err == nil && sitemapIndex != nil && len(sitemapIndex.Sitemaps) > 0 {
-			logger.Debug("Found sitemap index at %s with %d sitemaps", sitemapURL, len(sitemapIndex.Sitemaps))
+			log.Debug("found sitemap index", "url", sitemapURL, "sitemaps", len(sitemapIndex.Sitemaps))
 
 			// Get the first sitemap from the index
 			firstSitemapURL := sitemapIndex.Sitemaps[0].Loc
 			sitemap, err := fetchSitemap(ctx, firstSitemapURL)
 			if err == nil && sitemap != nil {
-				logger.Debug("Found sitemap at %s with %d URLs", firstSitemapURL, len(sitemap.URLs))
+				log.Debug("found sitemap", "url", firstSitemapURL, "urls", len(sitemap.URLs))
 				return sitemap, nil
 			}
 		}
@@ -81,7 +77,7 @@ func fetchSitemap(ctx context.Context, sitemapURL string) (*models.Sitemap, erro
 		return nil, err
 	}
 
-	client := &http.Client{}
+	client := netguard.FromContext(ctx).Client()
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -113,7 +109,7 @@ func fetchSitemapIndex(ctx context.Context, sitemapURL string) (*models.SitemapI
 		return nil, err
 	}
 
-	client := &http.Client{}
+	client := netguard.FromContext(ctx).Client()
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -145,7 +141,7 @@ func getSitemapFromRobots(ctx context.Context, robotsURL string) string {
 		return ""
 	}
 
-	client := &http.Client{}
+	client := netguard.FromContext(ctx).Client()
 	resp, err := client.Do(req)
 	if err != nil {
 		return ""
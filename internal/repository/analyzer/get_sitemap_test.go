@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
 )
 
 func TestGetSitemap(t *testing.T) {
@@ -51,7 +53,7 @@ Sitemap: http://example.com/sitemap.xml`))
 	defer server.Close()
 
 	// Test getting the sitemap
-	sitemap, err := GetSitemap(context.Background(), server.URL)
+	sitemap, err := GetSitemap(netguard.NewContext(context.Background(), netguard.New(true, nil)), server.URL)
 	if err != nil {
 		t.Fatalf("Failed to get sitemap: %v", err)
 	}
@@ -131,7 +133,7 @@ func TestGetSitemapIndex(t *testing.T) {
 	})
 
 	// Test getting the sitemap from the index
-	sitemap, err := GetSitemap(context.Background(), server.URL)
+	sitemap, err := GetSitemap(netguard.NewContext(context.Background(), netguard.New(true, nil)), server.URL)
 	if err != nil {
 		t.Fatalf("Failed to get sitemap from index: %v", err)
 	}
@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/promptguard"
+	"github.com/webscopeio/ai-hackathon/internal/telemetry"
+)
+
+// ProposeCriteria asks the model for test criteria covering only the provided changed pages
+// Existing criteria are passed along so the model proposes only new or changed ones
+func ProposeCriteria(ctx context.Context, client *llm.Client, techSpec string, existing []models.TestCriterion, changedContents map[string]string) ([]models.TestCriterion, error) {
+	if len(changedContents) == 0 {
+		return []models.TestCriterion{}, nil
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString(promptguard.Instructions)
+	builder.WriteString("\n\nINPUTS: \n")
+	builder.WriteString("TECHNICAL SPECIFICATION: ")
+	builder.WriteString(techSpec)
+	builder.WriteString("\nEXISTING CRITERIA (SEPARATED BY 2 NEWLINES): \n")
+	for _, c := range existing {
+		builder.WriteString(c.String())
+		builder.WriteString("\n\n")
+	}
+	builder.WriteString("\nCHANGED PAGES (SEPARATED BY 2 NEWLINES): ")
+	wrapped, flagged := promptguard.WrapAll(changedContents)
+	if len(flagged) > 0 {
+		logger.For(ctx, "analyzer").Warn("instruction-like text in changed pages", "urls", flagged)
+	}
+	for url, content := range wrapped {
+		builder.WriteString(fmt.Sprintf("%s: %s\n\n", url, content))
+	}
+	builder.WriteString("\n---END PAGE---\n\n")
+
+	prompt := `You are a test planning expert. Some pages of a website have changed since the last analysis.
+Your task is to propose E2E test criteria ONLY for the functionality affected by the changed pages.
+
+Rules:
+1. Do not repeat an existing criterion that is still valid.
+2. If an existing criterion needs to change because of the page changes, return the updated version with the SAME TITLE.
+3. Propose new criteria for new functionality on the changed pages.
+4. If nothing needs to change, return an empty criteria string.
+
+IMPORTANT: Pass all the criteria into the get_final_criteria_tool. Format each criterion as follows:
+
+CRITERION #1:
+TITLE: [Short descriptive title]
+SCENARIO: [Clear description of what should be tested]
+EXPECTED: [Expected outcome or behavior]
+
+Each criterion must be separated by 2 newlines for proper parsing.`
+
+	tool, toolChoice := llm.GenerateTool[models.FinalCriteriaTool]("get_final_criteria_tool", "This tool is able to get the final criteria for the changed pages of the website")
+
+	rawResponse, err := client.GetStructuredCompletion(telemetry.WithPhase(ctx, "propose_criteria"), builder.String(), prompt, tool, toolChoice, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't process request: %w", err)
+	}
+
+	var response models.FinalCriteriaTool
+	if err := json.Unmarshal(rawResponse, &response); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal response: %w", err)
+	}
+
+	proposed := models.ParseCriteria(response.Criteria)
+	logger.For(ctx, "analyzer").Debug("proposed criteria", "criteria", len(proposed), "changed_pages", len(changedContents))
+
+	// Drop criteria that are identical to existing ones
+	criteria := make([]models.TestCriterion, 0, len(proposed))
+	for _, c := range proposed {
+		duplicate := false
+		for _, e := range existing {
+			if c == e {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			criteria = append(criteria, c)
+		}
+	}
+
+	return criteria, nil
+}
@@ -1,56 +1,79 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
 
+	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/secrets"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	configFileName = "user_config.yaml"
+	configFileName = "config.yaml"
+	// keyFileName is the generated secret key stored next to the config file
+	keyFileName = "secret.key"
+	// legacyConfigFileName is the config file previously stored in the source tree
+	legacyConfigFileName = "user_config.yaml"
+	// defaultProjectName is the project legacy single-project settings are migrated to
+	defaultProjectName = "default"
+)
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrProjectExists   = errors.New("project already exists")
 )
 
 // Manager handles the operations for user configuration
 type Manager struct {
 	configPath string
+	cipher     *secrets.Cipher
 	mutex      sync.RWMutex
 }
 
-// NewManager creates a new config manager
+// NewManager creates a config manager for the config file in the user config directory
+// The location is $XDG_CONFIG_HOME/testbuddy/config.yaml, or TESTBUDDY_CONFIG if set
 func NewManager() (*Manager, error) {
-	// Get the root directory of the project
-	workDir, err := os.Getwd()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	configPath := os.Getenv("TESTBUDDY_CONFIG")
+	if configPath == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user config directory: %w", err)
+		}
+		configPath = filepath.Join(configDir, "testbuddy", configFileName)
 	}
 
-	rootDir := workDir
-	for {
-		if _, err := os.Stat(filepath.Join(rootDir, "go.mod")); err == nil {
-			break
-		}
-		parent := filepath.Dir(rootDir)
-		if parent == rootDir {
-			rootDir = workDir
-			break
-		}
-		rootDir = parent
+	m, err := NewManagerAt(configPath)
+	if err != nil {
+		return nil, err
 	}
+	if err := m.migrateLegacyConfig(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
 
-	// Store config file in the internal/config directory (same as config.go)
-	configDir := filepath.Join(rootDir, "internal", "config")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+// NewManagerAt creates a config manager for the config file at configPath
+// Secrets are encrypted with the key from secrets.LoadKey, generated next to the config file by default
+func NewManagerAt(configPath string) (*Manager, error) {
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	configPath := filepath.Join(configDir, configFileName)
-	return &Manager{
-		configPath: configPath,
-	}, nil
+	key, err := secrets.LoadKey(filepath.Join(filepath.Dir(configPath), keyFileName))
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := secrets.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{configPath: configPath, cipher: cipher}, nil
 }
 
 // GetConfig retrieves the current user configuration
@@ -58,6 +81,193 @@ func (m *Manager) GetConfig() (*models.UserConfig, error) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
+	config, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+
+	// Mirror the current project into the single-project fields
+	if project := currentProject(config); project != nil {
+		config.UmamiWebsiteId = project.UmamiWebsiteId
+		config.TechSpecification = project.TechSpecification
+		config.ProductSpecification = project.ProductSpecification
+	}
+
+	return config, nil
+}
+
+// SaveConfig saves the user configuration to a YAML file
+func (m *Manager) SaveConfig(config *models.UserConfig) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.write(config)
+}
+
+// UpdateConfig updates only the provided fields in the configuration
+// The single-project fields update the current project, which is created if there is none
+func (m *Manager) UpdateConfig(updates *models.UserConfig) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// Get current config
+	current, err := m.read()
+	if err != nil {
+		return err
+	}
+
+	// Update only non-empty fields, masked secrets are sent back unchanged by clients
+	if updates.AnthropicApiKey != "" && !secrets.IsMasked(updates.AnthropicApiKey) {
+		current.AnthropicApiKey = updates.AnthropicApiKey
+	}
+	if updates.SentryApiKey != "" && !secrets.IsMasked(updates.SentryApiKey) {
+		current.SentryApiKey = updates.SentryApiKey
+	}
+	if updates.UmamiAPIKey != "" && !secrets.IsMasked(updates.UmamiAPIKey) {
+		current.UmamiAPIKey = updates.UmamiAPIKey
+	}
+
+	project := currentProject(current)
+	if project == nil {
+		current.Projects = append(current.Projects, models.ProjectConfig{Name: defaultProjectName})
+		current.CurrentProject = defaultProjectName
+		project = &current.Projects[len(current.Projects)-1]
+	}
+	if updates.UmamiWebsiteId != "" {
+		project.UmamiWebsiteId = updates.UmamiWebsiteId
+	}
+	if updates.TechSpecification != "" {
+		project.TechSpecification = updates.TechSpecification
+	}
+	// Always update the product specification, even if empty
+	project.ProductSpecification = updates.ProductSpecification
+
+	// Save updated config
+	return m.write(current)
+}
+
+// ListProjects returns all configured projects
+func (m *Manager) ListProjects() ([]models.ProjectConfig, error) {
+	config, err := m.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config.Projects == nil {
+		return []models.ProjectConfig{}, nil
+	}
+	return config.Projects, nil
+}
+
+// GetProject returns a project by name
+func (m *Manager) GetProject(name string) (*models.ProjectConfig, error) {
+	config, err := m.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if i := projectIndex(config, name); i >= 0 {
+		return &config.Projects[i], nil
+	}
+	return nil, ErrProjectNotFound
+}
+
+// CurrentProject returns the project in use, or nil if no project is configured
+func (m *Manager) CurrentProject() (*models.ProjectConfig, error) {
+	config, err := m.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return currentProject(config), nil
+}
+
+// AddProject adds a new project, the first project becomes the current one
+func (m *Manager) AddProject(project models.ProjectConfig) error {
+	if project.Name == "" {
+		return fmt.Errorf("project name is required")
+	}
+
+	return m.modify(func(config *models.UserConfig) error {
+		if projectIndex(config, project.Name) >= 0 {
+			return ErrProjectExists
+		}
+
+		config.Projects = append(config.Projects, project)
+		if config.CurrentProject == "" {
+			config.CurrentProject = project.Name
+		}
+		return nil
+	})
+}
+
+// UpdateProject replaces the settings of a project, renaming it if the name changed
+func (m *Manager) UpdateProject(name string, project models.ProjectConfig) error {
+	if project.Name == "" {
+		project.Name = name
+	}
+
+	return m.modify(func(config *models.UserConfig) error {
+		i := projectIndex(config, name)
+		if i < 0 {
+			return ErrProjectNotFound
+		}
+		if project.Name != name && projectIndex(config, project.Name) >= 0 {
+			return ErrProjectExists
+		}
+
+		config.Projects[i] = project
+		if config.CurrentProject == name {
+			config.CurrentProject = project.Name
+		}
+		return nil
+	})
+}
+
+// DeleteProject removes a project
+func (m *Manager) DeleteProject(name string) error {
+	return m.modify(func(config *models.UserConfig) error {
+		i := projectIndex(config, name)
+		if i < 0 {
+			return ErrProjectNotFound
+		}
+
+		config.Projects = append(config.Projects[:i], config.Projects[i+1:]...)
+		if config.CurrentProject == name {
+			config.CurrentProject = ""
+		}
+		return nil
+	})
+}
+
+// UseProject makes a project the current one
+func (m *Manager) UseProject(name string) error {
+	return m.modify(func(config *models.UserConfig) error {
+		if projectIndex(config, name) < 0 {
+			return ErrProjectNotFound
+		}
+		config.CurrentProject = name
+		return nil
+	})
+}
+
+// modify applies a change to the stored config under the write lock
+func (m *Manager) modify(change func(config *models.UserConfig) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	config, err := m.read()
+	if err != nil {
+		return err
+	}
+
+	if err := change(config); err != nil {
+		return err
+	}
+
+	return m.write(config)
+}
+
+// read loads the config file, a missing file is an empty config
+func (m *Manager) read() (*models.UserConfig, error) {
 	// Check if config file exists
 	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
 		// Return empty config if file doesn't exist
@@ -76,55 +286,139 @@ func (m *Manager) GetConfig() (*models.UserConfig, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	for _, secret := range secretFields(&config) {
+		plaintext, err := m.cipher.Decrypt(*secret)
+		if err != nil {
+			return nil, err
+		}
+		*secret = plaintext
+		logger.RegisterSecrets(plaintext)
+	}
+
 	return &config, nil
 }
 
-// SaveConfig saves the user configuration to a YAML file
-func (m *Manager) SaveConfig(config *models.UserConfig) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
+// write stores the config file readable only by the user, with the secrets encrypted
+// The single-project fields live in the projects
+func (m *Manager) write(config *models.UserConfig) error {
+	stored := *config
+	stored.UmamiWebsiteId = ""
+	stored.TechSpecification = ""
+	stored.ProductSpecification = ""
+	stored.Projects = append([]models.ProjectConfig(nil), config.Projects...)
+
+	for _, secret := range secretFields(&stored) {
+		encrypted, err := m.cipher.Encrypt(*secret)
+		if err != nil {
+			return err
+		}
+		*secret = encrypted
+	}
 
 	// Marshal config to YAML
-	data, err := yaml.Marshal(config)
+	data, err := yaml.Marshal(&stored)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	// Write to file
-	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
+	// Write to file, tightening the mode of files created before secrets were encrypted
+	if err := os.WriteFile(m.configPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	if err := os.Chmod(m.configPath, 0600); err != nil {
+		return fmt.Errorf("failed to set config file mode: %w", err)
+	}
 
 	return nil
 }
 
-// UpdateConfig updates only the provided fields in the configuration
-func (m *Manager) UpdateConfig(updates *models.UserConfig) error {
-	// Get current config
-	current, err := m.GetConfig()
+// migrateLegacyConfig imports internal/config/user_config.yaml from the source tree
+// Its single-project settings become the "default" project
+func (m *Manager) migrateLegacyConfig() error {
+	if _, err := os.Stat(m.configPath); err == nil {
+		return nil
+	}
+
+	legacyPath := findLegacyConfig()
+	if legacyPath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(legacyPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to read legacy config file: %w", err)
 	}
 
-	// Update only non-empty fields
-	if updates.AnthropicApiKey != "" {
-		current.AnthropicApiKey = updates.AnthropicApiKey
+	var legacy models.UserConfig
+	if err := yaml.Unmarshal(data, &legacy); err != nil {
+		return fmt.Errorf("failed to parse legacy config file: %w", err)
 	}
-	if updates.SentryApiKey != "" {
-		current.SentryApiKey = updates.SentryApiKey
+
+	if len(legacy.Projects) == 0 && (legacy.UmamiWebsiteId != "" || legacy.TechSpecification != "" || legacy.ProductSpecification != "") {
+		legacy.Projects = []models.ProjectConfig{{
+			Name:                 defaultProjectName,
+			UmamiWebsiteId:       legacy.UmamiWebsiteId,
+			TechSpecification:    legacy.TechSpecification,
+			ProductSpecification: legacy.ProductSpecification,
+		}}
+		legacy.CurrentProject = defaultProjectName
 	}
-	if updates.UmamiAPIKey != "" {
-		current.UmamiAPIKey = updates.UmamiAPIKey
+
+	return m.write(&legacy)
+}
+
+// findLegacyConfig looks for the legacy config file next to go.mod, walking up from the working directory
+func findLegacyConfig() string {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return ""
 	}
-	if updates.UmamiWebsiteId != "" {
-		current.UmamiWebsiteId = updates.UmamiWebsiteId
+
+	for dir := workDir; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			legacyPath := filepath.Join(dir, "internal", "config", legacyConfigFileName)
+			if _, err := os.Stat(legacyPath); err == nil {
+				return legacyPath
+			}
+			return ""
+		}
+		if filepath.Dir(dir) == dir {
+			return ""
+		}
 	}
-	if updates.TechSpecification != "" {
-		current.TechSpecification = updates.TechSpecification
+}
+
+// Masked returns a copy of the config for API responses with the secrets masked
+func Masked(config *models.UserConfig) *models.UserConfigView {
+	view := &models.UserConfigView{
+		UserConfig:         *config,
+		AnthropicApiKeySet: config.AnthropicApiKey != "",
+		SentryApiKeySet:    config.SentryApiKey != "",
+		UmamiAPIKeySet:     config.UmamiAPIKey != "",
 	}
-	// Always update the product specification, even if empty
-	current.ProductSpecification = updates.ProductSpecification
+	view.AnthropicApiKey = secrets.Mask(config.AnthropicApiKey)
+	view.SentryApiKey = secrets.Mask(config.SentryApiKey)
+	view.UmamiAPIKey = secrets.Mask(config.UmamiAPIKey)
+	return view
+}
 
-	// Save updated config
-	return m.SaveConfig(current)
+// secretFields returns pointers to the secrets of the config
+func secretFields(config *models.UserConfig) []*string {
+	return []*string{&config.AnthropicApiKey, &config.SentryApiKey, &config.UmamiAPIKey}
+}
+
+func currentProject(config *models.UserConfig) *models.ProjectConfig {
+	if i := projectIndex(config, config.CurrentProject); i >= 0 {
+		return &config.Projects[i]
+	}
+	return nil
+}
+
+func projectIndex(config *models.UserConfig, name string) int {
+	for i, project := range config.Projects {
+		if project.Name == name {
+			return i
+		}
+	}
+	return -1
 }
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestProjects(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "testbuddy", "config.yaml")
+	manager, err := NewManagerAt(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	if err := manager.AddProject(models.ProjectConfig{Name: "shop", URL: "https://shop.example.com", TechSpecification: "Next.js"}); err != nil {
+		t.Fatalf("Failed to add project: %v", err)
+	}
+	if err := manager.AddProject(models.ProjectConfig{Name: "blog", URL: "https://blog.example.com"}); err != nil {
+		t.Fatalf("Failed to add project: %v", err)
+	}
+	if err := manager.AddProject(models.ProjectConfig{Name: "blog"}); !errors.Is(err, ErrProjectExists) {
+		t.Errorf("Expected ErrProjectExists, got %v", err)
+	}
+
+	// The first project becomes the current one and is mirrored in the single-project fields
+	userConfig, err := manager.GetConfig()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if userConfig.CurrentProject != "shop" || userConfig.TechSpecification != "Next.js" {
+		t.Errorf("Expected shop to be current, got %q with spec %q", userConfig.CurrentProject, userConfig.TechSpecification)
+	}
+
+	if err := manager.UseProject("blog"); err != nil {
+		t.Fatalf("Failed to use project: %v", err)
+	}
+	if err := manager.UpdateConfig(&models.UserConfig{AnthropicApiKey: "key", ProductSpecification: "A blog"}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	blog, err := manager.GetProject("blog")
+	if err != nil {
+		t.Fatalf("Failed to get project: %v", err)
+	}
+	if blog.ProductSpecification != "A blog" {
+		t.Errorf("Expected the config update to go to the current project, got %+v", blog)
+	}
+
+	if err := manager.DeleteProject("blog"); err != nil {
+		t.Fatalf("Failed to delete project: %v", err)
+	}
+	if _, err := manager.GetProject("blog"); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Expected ErrProjectNotFound, got %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "productSpecification: A blog") || !strings.Contains(string(data), "anthropicApiKey: enc:v1:") {
+		t.Errorf("Expected only the shared keys and projects in the file, got:\n%s", data)
+	}
+}
+
+func TestSecretsAtRest(t *testing.T) {
+	t.Setenv("TESTBUDDY_SECRET_KEY", "")
+	t.Setenv("TESTBUDDY_SECRET_KEY_FILE", "")
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	manager, err := NewManagerAt(configPath)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	if err := manager.UpdateConfig(&models.UserConfig{AnthropicApiKey: "sk-ant-api03-secret"}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-ant-api03-secret") {
+		t.Errorf("Expected secrets to be encrypted, got:\n%s", data)
+	}
+	if info, _ := os.Stat(configPath); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the config file to be private, got %v", info.Mode().Perm())
+	}
+
+	userConfig, err := manager.GetConfig()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if userConfig.AnthropicApiKey != "sk-ant-api03-secret" {
+		t.Errorf("Expected the decrypted key, got %q", userConfig.AnthropicApiKey)
+	}
+
+	masked := Masked(userConfig)
+	if !masked.AnthropicApiKeySet || masked.AnthropicApiKey != "********cret" || masked.SentryApiKeySet {
+		t.Errorf("Expected masked keys with set flags, got %+v", masked)
+	}
+
+	// Masked values sent back by clients keep the stored secrets
+	if err := manager.UpdateConfig(&masked.UserConfig); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	userConfig, _ = manager.GetConfig()
+	if userConfig.AnthropicApiKey != "sk-ant-api03-secret" {
+		t.Errorf("Expected masked updates to keep the secrets, got %q", userConfig.AnthropicApiKey)
+	}
+}
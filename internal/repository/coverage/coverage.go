@@ -0,0 +1,320 @@
+package coverage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+	"github.com/webscopeio/ai-hackathon/internal/repository/analyzer"
+	"github.com/webscopeio/ai-hackathon/internal/repository/gen_eval_loop"
+	"github.com/webscopeio/ai-hackathon/internal/runner"
+)
+
+// maxPromptGaps is the number of gaps of each kind included in the analyzer prompt
+const maxPromptGaps = 10
+
+// testFileRegex matches the files Playwright picks up as tests by default
+var testFileRegex = regexp.MustCompile(`\.(test|spec)\.[cm]?[jt]sx?$`)
+
+// TestFiles lists the Playwright test files in a directory
+func TestFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tests directory: %w", err)
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && testFileRegex.MatchString(entry.Name()) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// Run runs the test files against baseURL with tracing enabled and returns what each test exercised
+func Run(ctx context.Context, baseURL string, testFiles []string) ([]models.TestCoverage, error) {
+	if len(testFiles) == 0 {
+		return nil, fmt.Errorf("no test files to run")
+	}
+
+	tempDir, testsDir, err := gen_eval_loop.SetupTestEnvironment(ctx, baseURL)
+	if tempDir != "" {
+		defer os.RemoveAll(tempDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range testFiles {
+		if err := copyFile(file, filepath.Join(testsDir, filepath.Base(file))); err != nil {
+			return nil, fmt.Errorf("couldn't copy test file %s: %w", file, err)
+		}
+	}
+
+	logger.For(ctx, "coverage").Debug("running tests with tracing", "files", len(testFiles), "dir", tempDir)
+	result, err := runner.FromContext(ctx).Run(ctx, runner.Spec{
+		Step:    "coverage",
+		Phase:   runner.PhaseTest,
+		Command: []string{"pnpm", "exec", "playwright", "test", "--trace", "on", "--reporter", "line"},
+		Dir:     tempDir,
+		Egress:  netguard.FromContext(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run tests: %w", err)
+	}
+	if !result.Passed() {
+		// Failing tests still leave traces of what they exercised
+		logger.For(ctx, "coverage").Debug("some tests failed", "error", result.Err(), "output", string(result.Output))
+	}
+
+	return ParseTraces(filepath.Join(tempDir, "test-results"))
+}
+
+// Build matches the test coverage against the sitemap, significant flows and Sentry-affected paths
+// Targets are compared by path on the host of baseURL, flows after normalizing dynamic segments
+func Build(baseURL string, tests []models.TestCoverage, sitemap *models.Sitemap, flows []models.UmamiSignificantFlow, sentryPaths []models.SentryAffectedPath) *models.CoverageReport {
+	base, _ := url.Parse(baseURL)
+
+	// Paths visited by each test, in order
+	visited := make([][]string, len(tests))
+	for i, test := range tests {
+		for _, u := range test.URLs {
+			if path, ok := sitePath(base, u); ok {
+				visited[i] = append(visited[i], path)
+			}
+		}
+	}
+
+	coveredBy := func(match func(i int) bool) []string {
+		names := []string{}
+		for i, test := range tests {
+			if match(i) {
+				names = append(names, test.Test)
+			}
+		}
+		return names
+	}
+
+	visitsPath := func(path string) func(i int) bool {
+		return func(i int) bool {
+			for _, p := range visited[i] {
+				if p == path {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	report := &models.CoverageReport{
+		BaseURL:     baseURL,
+		GeneratedAt: time.Now(),
+		Tests:       tests,
+		Pages:       []models.CoverageTarget{},
+		Flows:       []models.CoverageTarget{},
+		SentryPaths: []models.CoverageTarget{},
+	}
+
+	if sitemap != nil {
+		seen := map[string]bool{}
+		for _, entry := range sitemap.URLs {
+			path, ok := sitePath(base, entry.Loc)
+			if !ok || seen[path] {
+				continue
+			}
+			seen[path] = true
+			report.Pages = append(report.Pages, target(models.CoverageKindPage, entry.Loc, 0, coveredBy(visitsPath(path))))
+		}
+	}
+
+	for _, flow := range flows {
+		report.Flows = append(report.Flows, target(models.CoverageKindFlow, strings.Join(flow.Path, " -> "), flow.Frequency, coveredBy(func(i int) bool {
+			return followsFlow(visited[i], tests[i].Selectors, flow.Path)
+		})))
+	}
+
+	for _, affected := range sentryPaths {
+		path, ok := sitePath(base, affected.Path)
+		if !ok {
+			continue
+		}
+		report.SentryPaths = append(report.SentryPaths, target(models.CoverageKindSentry, affected.Path, affected.Count, coveredBy(visitsPath(path))))
+	}
+
+	report.Gaps = gaps(report)
+	return report
+}
+
+// GapsPrompt asks the analyzer to generate criteria for the most important coverage gaps
+func GapsPrompt(report *models.CoverageReport) string {
+	if len(report.Gaps) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n\nIMPORTANT: The existing tests don't cover the following. Generate the criteria for these gaps instead of the already covered functionality.\n")
+
+	count := map[string]int{}
+	for _, gap := range report.Gaps {
+		if count[gap.Kind] >= maxPromptGaps {
+			continue
+		}
+		count[gap.Kind]++
+
+		switch gap.Kind {
+		case models.CoverageKindPage:
+			builder.WriteString(fmt.Sprintf("- Page never visited: %s\n", gap.Target))
+		case models.CoverageKindFlow:
+			builder.WriteString(fmt.Sprintf("- User flow followed by %d sessions: %s\n", gap.Weight, gap.Target))
+		case models.CoverageKindSentry:
+			builder.WriteString(fmt.Sprintf("- Page with %d Sentry errors: %s\n", gap.Weight, gap.Target))
+		}
+	}
+
+	return builder.String()
+}
+
+// Load reads a coverage report from a JSON file
+func Load(path string) (*models.CoverageReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read coverage report: %w", err)
+	}
+
+	var report models.CoverageReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse coverage report: %w", err)
+	}
+
+	return &report, nil
+}
+
+// Save writes a coverage report to a JSON file
+func Save(path string, report *models.CoverageReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create coverage directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode coverage report: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write coverage report: %w", err)
+	}
+
+	return nil
+}
+
+// followsFlow reports whether a test visits the flow pages in order and interacts with its events
+// Custom events are matched by name against the selectors, e.g. [data-umami-event="signup"]
+func followsFlow(visited []string, selectors []string, flow []string) bool {
+	next := 0
+	for _, step := range flow {
+		if event, ok := strings.CutPrefix(step, "event:"); ok {
+			if !usesEvent(selectors, event) {
+				return false
+			}
+			continue
+		}
+
+		found := false
+		for next < len(visited) {
+			page := visited[next]
+			next++
+			if analyzer.NormalizeFlowPath(page) == step {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func usesEvent(selectors []string, event string) bool {
+	event = strings.ToLower(event)
+	for _, selector := range selectors {
+		if strings.Contains(strings.ToLower(selector), event) {
+			return true
+		}
+	}
+	return false
+}
+
+// sitePath returns the normalized path of a URL on the site, or false for other hosts
+func sitePath(base *url.URL, rawURL string) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	if parsed.Host != "" && base != nil && base.Host != "" && !strings.EqualFold(parsed.Host, base.Host) {
+		return "", false
+	}
+
+	path := strings.TrimSuffix(parsed.Path, "/")
+	if path == "" {
+		return "/", true
+	}
+	return path, true
+}
+
+func target(kind, name string, weight int, coveredBy []string) models.CoverageTarget {
+	return models.CoverageTarget{
+		Kind:      kind,
+		Target:    name,
+		Weight:    weight,
+		Covered:   len(coveredBy) > 0,
+		CoveredBy: coveredBy,
+	}
+}
+
+// gaps returns the uncovered targets, the most frequent flows and errors first
+func gaps(report *models.CoverageReport) []models.CoverageTarget {
+	result := []models.CoverageTarget{}
+	for _, targets := range [][]models.CoverageTarget{report.SentryPaths, report.Flows, report.Pages} {
+		start := len(result)
+		for _, t := range targets {
+			if !t.Covered {
+				result = append(result, t)
+			}
+		}
+		group := result[start:]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].Weight > group[j].Weight
+		})
+	}
+	return result
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
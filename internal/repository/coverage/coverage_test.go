@@ -0,0 +1,136 @@
+package coverage
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func writeTrace(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// The entries are written in a fixed order, the .network file goes before the .trace file
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	archive := zip.NewWriter(file)
+	for _, name := range names {
+		writer, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.Write([]byte(files[name]))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTraces(t *testing.T) {
+	dir := t.TempDir()
+	writeTrace(t, filepath.Join(dir, "signup-chromium", "trace.zip"), map[string]string{
+		"trace.trace": `{"type":"context-options","title":"signup.spec.ts:3 › signs up"}
+{"type":"before","method":"goto","startTime":100,"params":{"url":"https://example.com/"}}
+{"type":"frame-snapshot","snapshot":{"frameUrl":"https://example.com/","timestamp":150}}
+{"type":"before","method":"click","startTime":200,"params":{"selector":"[data-umami-event=\"signup-click\"]"}}
+{"type":"before","method":"waitForSelector","startTime":300,"params":{"selector":"h1"}}
+not json
+{"type":"frame-snapshot","snapshot":{"frameUrl":"https://example.com/signup","timestamp":350}}
+`,
+		"trace.network": `{"type":"resource-snapshot","snapshot":{"_monotonicTime":120,"request":{"url":"https://example.com/"},"response":{"content":{"mimeType":"text/html"}}}}
+{"type":"resource-snapshot","snapshot":{"_monotonicTime":250,"request":{"url":"https://example.com/signup"},"response":{"content":{"mimeType":"text/html"}}}}
+{"type":"resource-snapshot","snapshot":{"_monotonicTime":400,"request":{"url":"https://example.com/signup/done"},"response":{"content":{"mimeType":"text/html; charset=utf-8"}}}}
+{"type":"resource-snapshot","snapshot":{"_monotonicTime":410,"request":{"url":"https://example.com/app.js"},"response":{"content":{"mimeType":"application/javascript"}}}}
+`,
+	})
+
+	tests, err := ParseTraces(dir)
+	if err != nil {
+		t.Fatalf("Failed to parse traces: %v", err)
+	}
+	if len(tests) != 1 {
+		t.Fatalf("Expected 1 test, got %d", len(tests))
+	}
+
+	test := tests[0]
+	if test.Test != "signup.spec.ts:3 › signs up" {
+		t.Errorf("Expected the test title from the trace, got %q", test.Test)
+	}
+
+	expectedURLs := []string{"https://example.com/", "https://example.com/signup", "https://example.com/signup/done"}
+	if !reflect.DeepEqual(test.URLs, expectedURLs) {
+		t.Errorf("Expected %v, got %v", expectedURLs, test.URLs)
+	}
+	if !reflect.DeepEqual(test.Selectors, []string{`[data-umami-event="signup-click"]`}) {
+		t.Errorf("Expected only the clicked selector, got %v", test.Selectors)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []models.TestCoverage{{
+		Test:      "signup",
+		URLs:      []string{"https://example.com/", "https://cdn.example.net/pricing", "https://example.com/products/42/", "https://example.com/signup"},
+		Selectors: []string{`[data-umami-event="signup-click"]`},
+	}}
+	sitemap := &models.Sitemap{URLs: []models.URL{
+		{Loc: "https://example.com/"},
+		{Loc: "https://example.com/pricing"},
+	}}
+	flows := []models.UmamiSignificantFlow{
+		{Path: []string{"/", "/products/:id", "event:signup-click", "/signup"}, Frequency: 5},
+		{Path: []string{"/signup", "/"}, Frequency: 9},
+	}
+	sentryPaths := []models.SentryAffectedPath{
+		{Path: "https://example.com/checkout", Count: 3},
+		{Path: "https://example.com/signup", Count: 7},
+	}
+
+	report := Build("https://example.com/", tests, sitemap, flows, sentryPaths)
+
+	if !report.Pages[0].Covered || report.Pages[1].Covered {
+		t.Errorf("Expected only / to be covered, pages on other hosts don't count: %+v", report.Pages)
+	}
+	if !report.Flows[0].Covered || report.Flows[1].Covered {
+		t.Errorf("Expected only the ordered flow to be covered: %+v", report.Flows)
+	}
+	if report.SentryPaths[0].Covered || !report.SentryPaths[1].Covered {
+		t.Errorf("Expected only /signup errors to be covered: %+v", report.SentryPaths)
+	}
+
+	var gapTargets []string
+	for _, gap := range report.Gaps {
+		gapTargets = append(gapTargets, gap.Target)
+	}
+	expectedGaps := []string{"https://example.com/checkout", "/signup -> /", "https://example.com/pricing"}
+	if !reflect.DeepEqual(gapTargets, expectedGaps) {
+		t.Errorf("Expected gaps %v, got %v", expectedGaps, gapTargets)
+	}
+
+	prompt := GapsPrompt(report)
+	if !strings.Contains(prompt, "User flow followed by 9 sessions: /signup -> /") {
+		t.Errorf("Expected the flow gap in the prompt, got %q", prompt)
+	}
+
+	htmlPath := filepath.Join(t.TempDir(), "coverage.html")
+	if err := WriteHTML(htmlPath, report); err != nil {
+		t.Fatalf("Failed to write HTML report: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+var reportTemplate = template.Must(template.New("coverage").Funcs(template.FuncMap{
+	"covered": func(targets []models.CoverageTarget) int {
+		count := 0
+		for _, t := range targets {
+			if t.Covered {
+				count++
+			}
+		}
+		return count
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Test coverage for {{.BaseURL}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #1f2933; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+th, td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid #e4e7eb; vertical-align: top; }
+.gap { background: #fdecea; }
+.ok { background: #e8f5e9; }
+small { color: #616e7c; }
+</style>
+</head>
+<body>
+<h1>Test coverage for {{.BaseURL}}</h1>
+<p><small>Generated {{.GeneratedAt.Format "2006-01-02 15:04"}} from {{len .Tests}} traced tests</small></p>
+<ul>
+<li>Pages: {{covered .Pages}} / {{len .Pages}} covered</li>
+<li>Significant flows: {{covered .Flows}} / {{len .Flows}} covered</li>
+<li>Sentry-affected paths: {{covered .SentryPaths}} / {{len .SentryPaths}} covered</li>
+</ul>
+
+<h2>Gaps</h2>
+{{if .Gaps}}
+<table>
+<tr><th>Kind</th><th>Target</th><th>Weight</th></tr>
+{{range .Gaps}}<tr class="gap"><td>{{.Kind}}</td><td>{{.Target}}</td><td>{{if .Weight}}{{.Weight}}{{end}}</td></tr>
+{{end}}
+</table>
+{{else}}
+<p>No gaps, every known page, flow and error path is exercised.</p>
+{{end}}
+
+{{define "targets"}}
+<table>
+<tr><th>Target</th><th>Weight</th><th>Covered by</th></tr>
+{{range .}}<tr class="{{if .Covered}}ok{{else}}gap{{end}}"><td>{{.Target}}</td><td>{{if .Weight}}{{.Weight}}{{end}}</td><td>{{range .CoveredBy}}{{.}}<br>{{end}}</td></tr>
+{{end}}
+</table>
+{{end}}
+
+<h2>Pages</h2>
+{{template "targets" .Pages}}
+<h2>Significant flows</h2>
+{{template "targets" .Flows}}
+<h2>Sentry-affected paths</h2>
+{{template "targets" .SentryPaths}}
+
+<h2>Tests</h2>
+<table>
+<tr><th>Test</th><th>Visited URLs</th><th>Interacted elements</th></tr>
+{{range .Tests}}<tr><td>{{.Test}}</td><td>{{range .URLs}}{{.}}<br>{{end}}</td><td>{{range .Selectors}}<code>{{.}}</code><br>{{end}}</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML renders a coverage report as a standalone HTML page
+func WriteHTML(path string, report *models.CoverageReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create coverage directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create coverage report: %w", err)
+	}
+	defer file.Close()
+
+	if err := reportTemplate.Execute(file, report); err != nil {
+		return fmt.Errorf("failed to render coverage report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package coverage
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+// interactionMethods are the Playwright actions counted as interacting with an element
+var interactionMethods = map[string]bool{
+	"click": true, "dblclick": true, "tap": true, "fill": true, "type": true, "press": true,
+	"check": true, "uncheck": true, "setChecked": true, "selectOption": true, "hover": true,
+	"setInputFiles": true, "dragAndDrop": true, "focus": true,
+}
+
+// traceEvent is the subset of a Playwright trace entry the coverage needs
+// Actions, frame snapshots and network entries carry their monotonic time in different fields
+type traceEvent struct {
+	Type      string  `json:"type"`
+	Title     string  `json:"title"`
+	Method    string  `json:"method"`
+	StartTime float64 `json:"startTime"`
+	Params    struct {
+		URL      string `json:"url"`
+		Selector string `json:"selector"`
+	} `json:"params"`
+	Snapshot struct {
+		FrameURL      string  `json:"frameUrl"`
+		Timestamp     float64 `json:"timestamp"`
+		MonotonicTime float64 `json:"_monotonicTime"`
+		Request       struct {
+			URL string `json:"url"`
+		} `json:"request"`
+		Response struct {
+			Content struct {
+				MimeType string `json:"mimeType"`
+			} `json:"content"`
+		} `json:"response"`
+	} `json:"snapshot"`
+}
+
+// ParseTraces parses every trace.zip below the test results directory
+// Playwright writes one directory per test, so the directory name identifies the test
+func ParseTraces(resultsDir string) ([]models.TestCoverage, error) {
+	var tests []models.TestCoverage
+
+	err := filepath.WalkDir(resultsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "trace.zip" {
+			return nil
+		}
+
+		test, err := ParseTrace(path)
+		if err != nil {
+			logger.Default().Debug("skipping unreadable trace", "subsystem", "coverage", "path", path, "error", err)
+			return nil
+		}
+		if test.Test == "" {
+			test.Test = filepath.Base(filepath.Dir(path))
+		}
+		tests = append(tests, *test)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test results: %w", err)
+	}
+
+	return tests, nil
+}
+
+// visit is a URL seen in the trace at a monotonic time in milliseconds
+type visit struct {
+	time float64
+	url  string
+}
+
+// ParseTrace collects the visited URLs and the interacted selectors of a Playwright trace
+// URLs come from page.goto calls, frame snapshots and HTML document responses, in the order visited.
+// The actions and the network entries are in separate files, their URLs are merged by time.
+func ParseTrace(path string) (*models.TestCoverage, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer archive.Close()
+
+	test := &models.TestCoverage{URLs: []string{}, Selectors: []string{}}
+	seenSelectors := map[string]bool{}
+	var visits []visit
+
+	addVisit := func(time float64, u string) {
+		if u == "" || strings.HasPrefix(u, "about:") || strings.HasPrefix(u, "data:") {
+			return
+		}
+		visits = append(visits, visit{time: time, url: u})
+	}
+
+	for _, file := range archive.File {
+		if !strings.HasSuffix(file.Name, ".trace") && !strings.HasSuffix(file.Name, ".network") {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in trace: %w", file.Name, err)
+		}
+
+		err = readTraceEvents(reader, func(event traceEvent) {
+			switch event.Type {
+			case "context-options":
+				if event.Title != "" {
+					test.Test = event.Title
+				}
+			case "before":
+				if event.Method == "goto" {
+					addVisit(event.StartTime, event.Params.URL)
+				}
+				if interactionMethods[event.Method] && event.Params.Selector != "" && !seenSelectors[event.Params.Selector] {
+					seenSelectors[event.Params.Selector] = true
+					test.Selectors = append(test.Selectors, event.Params.Selector)
+				}
+			case "frame-snapshot":
+				addVisit(event.Snapshot.Timestamp, event.Snapshot.FrameURL)
+			case "resource-snapshot":
+				if strings.HasPrefix(event.Snapshot.Response.Content.MimeType, "text/html") {
+					addVisit(event.Snapshot.MonotonicTime, event.Snapshot.Request.URL)
+				}
+			}
+		})
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s in trace: %w", file.Name, err)
+		}
+	}
+
+	sort.SliceStable(visits, func(i, j int) bool { return visits[i].time < visits[j].time })
+	for _, v := range visits {
+		// Consecutive entries for the same page are a single visit
+		if len(test.URLs) > 0 && test.URLs[len(test.URLs)-1] == v.url {
+			continue
+		}
+		test.URLs = append(test.URLs, v.url)
+	}
+
+	return test, nil
+}
+
+// readTraceEvents decodes a JSON-lines trace file, skipping lines it can't parse
+func readTraceEvents(reader io.Reader, handle func(traceEvent)) error {
+	scanner := bufio.NewScanner(reader)
+	// Snapshots embed whole DOM trees, so lines can be large
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var event traceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		handle(event)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,57 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/promptguard"
+)
+
+const (
+	// axeModule runs axe-core accessibility checks in Playwright tests, it is only allowed by opt-in
+	axeModule  = "@axe-core/playwright"
+	axeVersion = "^4.10.0"
+)
+
+type axeKey struct{}
+
+// WithAxe returns a context whose generated tests may use @axe-core/playwright
+func WithAxe(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, axeKey{}, enabled)
+}
+
+func axeFrom(ctx context.Context) bool {
+	enabled, _ := ctx.Value(axeKey{}).(bool)
+	return enabled
+}
+
+// allowedModules are the modules generated tests may import besides @playwright/test
+func allowedModules(ctx context.Context) []string {
+	if axeFrom(ctx) {
+		return []string{axeModule}
+	}
+	return nil
+}
+
+// dependencyRules tells the generator which dependencies it may use and how to write accessibility tests with them
+func dependencyRules(ctx context.Context) string {
+	if !axeFrom(ctx) {
+		return "- Do not add any other dependencies, only @playwright/test is allowed.\n"
+	}
+	return `- Do not add any other dependencies, only @playwright/test and @axe-core/playwright are allowed.
+- For accessibility criteria, check the page with @axe-core/playwright: import AxeBuilder from '@axe-core/playwright', run await new AxeBuilder({ page }).withTags(['wcag2a', 'wcag2aa']).analyze() and expect the violations to be empty. Use include() to limit the check to the elements of the criterion and exclude() for third-party widgets.
+`
+}
+
+// accessibilityContext lists the violations of the analyzer's accessibility audit for the generator
+func accessibilityContext(audits []models.PageA11yAudit) string {
+	if len(audits) == 0 {
+		return ""
+	}
+	violations, err := json.Marshal(audits)
+	if err != nil {
+		return ""
+	}
+	return "\nACCESSIBILITY VIOLATIONS: " + promptguard.Wrap("accessibility audit", string(violations))
+}
@@ -0,0 +1,38 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestAxeOptIn(t *testing.T) {
+	ctx := context.Background()
+	if modules := allowedModules(ctx); len(modules) != 0 || strings.Contains(dependencyRules(ctx), axeModule) {
+		t.Errorf("Expected only @playwright/test without the opt-in, got %v", modules)
+	}
+
+	ctx = WithAxe(ctx, true)
+	if modules := allowedModules(ctx); len(modules) != 1 || modules[0] != axeModule {
+		t.Errorf("Expected %s to be allowed, got %v", axeModule, modules)
+	}
+	if rules := dependencyRules(ctx); !strings.Contains(rules, "new AxeBuilder({ page })") {
+		t.Errorf("Expected the generator to be told how to use axe, got %s", rules)
+	}
+}
+
+func TestAccessibilityContext(t *testing.T) {
+	if prompt := accessibilityContext(nil); prompt != "" {
+		t.Errorf("Expected no prompt without audits, got %q", prompt)
+	}
+
+	prompt := accessibilityContext([]models.PageA11yAudit{{
+		URL:        "https://shop.example.com/",
+		Violations: []models.A11yViolation{{Rule: models.A11yMissingLabel, Impact: models.A11yImpactCritical, Selector: "#search", Message: "input field has no label"}},
+	}})
+	if !strings.Contains(prompt, "ACCESSIBILITY VIOLATIONS") || !strings.Contains(prompt, `"selector":"#search"`) {
+		t.Errorf("Expected the violations in the prompt, got %s", prompt)
+	}
+}
@@ -0,0 +1,124 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+const (
+	// maxArtifactBytes limits the artifacts kept per test run, files beyond it are dropped
+	maxArtifactBytes = 200 << 20
+	// maxScreenshotBytes is the largest screenshot sent to the evaluator
+	maxScreenshotBytes = 5 << 20
+)
+
+type evaluatorScreenshotKey struct{}
+
+// WithEvaluatorScreenshot returns a context whose evaluators also see the last failure screenshot
+func WithEvaluatorScreenshot(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, evaluatorScreenshotKey{}, enabled)
+}
+
+func evaluatorScreenshotFrom(ctx context.Context) bool {
+	enabled, _ := ctx.Value(evaluatorScreenshotKey{}).(bool)
+	return enabled
+}
+
+// collectArtifacts moves the test-results of a run out of the way of the next run into a new directory of artifactsDir
+// Returns the directory and its artifacts, paths are relative and slash separated
+func collectArtifacts(resultsDir, artifactsDir string) (string, []models.Artifact, error) {
+	if _, err := os.Stat(resultsDir); os.IsNotExist(err) {
+		return "", nil, nil
+	}
+
+	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
+		return "", nil, fmt.Errorf("couldn't create artifacts directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(artifactsDir, "iteration-")
+	if err != nil {
+		return "", nil, fmt.Errorf("couldn't create artifacts directory: %w", err)
+	}
+	// The rename needs an empty target
+	os.Remove(dir)
+	if err := os.Rename(resultsDir, dir); err != nil {
+		return "", nil, fmt.Errorf("couldn't collect test results: %w", err)
+	}
+
+	var artifacts []models.Artifact
+	var total int64
+	err = filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if total+info.Size() > maxArtifactBytes {
+			return os.Remove(file)
+		}
+		total += info.Size()
+
+		relative, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, models.Artifact{
+			Path: filepath.ToSlash(relative),
+			Kind: artifactKind(relative),
+			Size: info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return dir, artifacts, fmt.Errorf("couldn't list test results: %w", err)
+	}
+	return dir, artifacts, nil
+}
+
+func artifactKind(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".zip":
+		return models.ArtifactTrace
+	case ".png", ".jpg", ".jpeg":
+		return models.ArtifactScreenshot
+	case ".webm":
+		return models.ArtifactVideo
+	default:
+		return models.ArtifactOther
+	}
+}
+
+// failureScreenshot returns the screenshot Playwright took at a failure as an image block
+// Every failed test has its own directory, the last one in path order is picked
+func failureScreenshot(iteration models.GenEvalIteration) (anthropic.ContentBlockParamUnion, bool) {
+	var last *models.Artifact
+	for i, artifact := range iteration.Artifacts {
+		if artifact.Kind == models.ArtifactScreenshot && strings.HasPrefix(path.Base(artifact.Path), "test-failed") &&
+			artifact.Size <= maxScreenshotBytes && (last == nil || artifact.Path > last.Path) {
+			last = &iteration.Artifacts[i]
+		}
+	}
+	if last == nil {
+		return anthropic.ContentBlockParamUnion{}, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(iteration.ArtifactDir, filepath.FromSlash(last.Path)))
+	if err != nil {
+		return anthropic.ContentBlockParamUnion{}, false
+	}
+	mediaType := "image/png"
+	if ext := strings.ToLower(path.Ext(last.Path)); ext == ".jpg" || ext == ".jpeg" {
+		mediaType = "image/jpeg"
+	}
+	return anthropic.NewImageBlockBase64(mediaType, base64.StdEncoding.EncodeToString(data)), true
+}
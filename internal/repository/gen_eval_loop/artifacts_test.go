@@ -0,0 +1,55 @@
+package gen_eval_loop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestCollectArtifacts(t *testing.T) {
+	tempDir := t.TempDir()
+	resultsDir := filepath.Join(tempDir, "test-results")
+	// Playwright's layout: a directory per failed test named after its file, describe blocks, title and project
+	files := map[string]string{
+		"test-1-cart-shows-the-cart-chromium/test-failed-1.png":             "screenshot",
+		"test-1-cart-shows-the-cart-chromium/trace.zip":                     "trace",
+		"test-1-cart-shows-the-cart-chromium/video.webm":                    "video",
+		"test-1-cart-checkout-submits-the-order-chromium/test-failed-1.png": "screenshot",
+		"test-1-cart-checkout-submits-the-order-chromium/error-context.md":  "page snapshot",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(resultsDir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(resultsDir, name), []byte(content), 0644)
+	}
+
+	dir, artifacts, err := collectArtifacts(resultsDir, filepath.Join(tempDir, "artifacts"))
+	if err != nil {
+		t.Fatalf("collectArtifacts failed: %v", err)
+	}
+	if _, err := os.Stat(resultsDir); !os.IsNotExist(err) {
+		t.Errorf("Expected test-results to be moved for the next run, got %v", err)
+	}
+
+	kinds := map[string]string{}
+	for _, artifact := range artifacts {
+		kinds[artifact.Path] = artifact.Kind
+	}
+	if len(artifacts) != 5 || kinds["test-1-cart-shows-the-cart-chromium/trace.zip"] != models.ArtifactTrace ||
+		kinds["test-1-cart-shows-the-cart-chromium/video.webm"] != models.ArtifactVideo ||
+		kinds["test-1-cart-checkout-submits-the-order-chromium/error-context.md"] != models.ArtifactOther {
+		t.Errorf("Expected the artifacts with their kinds, got %+v", artifacts)
+	}
+
+	if _, ok := failureScreenshot(models.GenEvalIteration{ArtifactDir: dir, Artifacts: artifacts}); !ok {
+		t.Error("Expected a failure screenshot")
+	}
+	if _, ok := failureScreenshot(models.GenEvalIteration{ArtifactDir: dir}); ok {
+		t.Error("Expected no screenshot without artifacts")
+	}
+
+	if dir, artifacts, err := collectArtifacts(resultsDir, filepath.Join(tempDir, "artifacts")); dir != "" || artifacts != nil || err != nil {
+		t.Errorf("Expected nothing to collect without test-results, got %q, %v and %v", dir, artifacts, err)
+	}
+}
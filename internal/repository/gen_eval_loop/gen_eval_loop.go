@@ -6,67 +6,125 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/webscopeio/ai-hackathon/internal/llm"
 	"github.com/webscopeio/ai-hackathon/internal/logger"
 	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+	"github.com/webscopeio/ai-hackathon/internal/promptguard"
+	"github.com/webscopeio/ai-hackathon/internal/runner"
+	"github.com/webscopeio/ai-hackathon/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func GenEvalLoop(ctx context.Context, client *llm.Client, analyzerReturn *models.AnalyzerReturn, index int, noOfLoops int) (string, error) {
-	tempDir, testsDir, err := SetupTestEnvironment(ctx)
+// GenEvalLoop generates a test file and improves it with evaluator feedback until it's accepted
+// The result holds the final file and every generated version with its test output
+// Log records of the loop carry the index as the criterion ID unless the context already has one
+// Every iteration runs in its own span
+// With a stabilization in the context, accepted files are run repeatedly and flaky ones are regenerated
+// The tests run against the analyzed target on the projects of the matrix in the context
+func GenEvalLoop(ctx context.Context, client *llm.Client, analyzerReturn *models.AnalyzerReturn, index int, noOfLoops int) (*models.GenEvalResult, error) {
+	if logger.Correlation(ctx, logger.CriterionIDKey) == "" {
+		ctx = logger.WithCorrelation(ctx, logger.CriterionIDKey, strconv.Itoa(index))
+	}
+	log := logger.For(ctx, "gen_eval")
+
+	tempDir, testsDir, err := SetupTestEnvironment(ctx, targetURL(analyzerReturn))
 	if err != nil {
-		return "", fmt.Errorf("SetupTestEnvironment failed: %w", err)
+		return nil, fmt.Errorf("SetupTestEnvironment failed: %w", err)
 	}
 
+	// Generated code may only reach the analyzed site and the allowed domains of the project
+	pages := sitePages(analyzerReturn)
+	scope := scopeFor(ctx, pages)
+
+	// and so may the browser running it
+	egress := egressFor(ctx, pages)
+
+	result := &models.GenEvalResult{}
 	generatorMessages := []anthropic.MessageParam{}
 	feedback := ""
-	filename := ""
-	testFileContent := []byte{}
+	testFileContent := ""
 	loopCount := 0
 
+	defer func() { telemetry.ObserveGenEval(len(result.Iterations), result.Accepted) }()
+
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("gen-eval loop canceled: %w", err)
+		}
 		if loopCount > noOfLoops {
-			return filename, nil
+			// A file that never passed the safety scan must not be used
+			if n := len(result.Iterations); n > 0 && len(result.Iterations[n-1].Findings) > 0 {
+				os.Remove(result.Filename)
+				return nil, fmt.Errorf("generated test failed the safety scan: %s", strings.Join(result.Iterations[n-1].Findings, "; "))
+			}
+			return result, nil
 		}
-		filename, generatorMessages, err = generateTestFile(ctx, client, analyzerReturn, generatorMessages, feedback, string(testFileContent), testsDir, index)
+
+		iterationCtx, span := telemetry.StartSpan(ctx, "gen_eval.iteration",
+			attribute.Int("criterion.index", index),
+			attribute.Int("iteration", loopCount),
+		)
+		result.Filename, generatorMessages, err = generateTestFile(iterationCtx, client, analyzerReturn, generatorMessages, feedback, testFileContent, testsDir, index)
 		if err != nil {
-			return "", fmt.Errorf("GenerateTestFile failed: %w", err)
+			telemetry.EndSpan(span, err)
+			return nil, fmt.Errorf("GenerateTestFile failed: %w", err)
 		}
-		logger.Debug("Filename: %s", filename)
+		log.Debug("generated test file", "file", result.Filename, "iteration", loopCount)
 
-		var passed bool
-		feedback, passed, err = evaluateTestFile(ctx, client, filename, tempDir)
-		logger.Debug("EVALUATOR feedback: %s", feedback)
+		iteration, err := evaluateTestFile(iterationCtx, client, result.Filename, tempDir, scope, egress)
 		if err != nil {
-			return "", fmt.Errorf("EvaluateTestFile failed: %w", err)
+			telemetry.EndSpan(span, err)
+			return nil, fmt.Errorf("EvaluateTestFile failed: %w", err)
 		}
 
-		if passed {
-			logger.Debug("[GEN_EVAL_LOOP] Evaluator accepted the test file.")
-			break
+		// An accepted file may still be flaky, its flaky tests go back to the generator
+		if stability := stabilityFrom(ctx); iteration.Accepted && stability.enabled() {
+			iteration.Stability, err = stabilizeTestFile(iterationCtx, result.Filename, tempDir, egress, stability)
+			if err != nil {
+				telemetry.EndSpan(span, err)
+				return nil, fmt.Errorf("stabilizeTestFile failed: %w", err)
+			}
+			span.SetAttributes(attribute.Float64("stability.score", iteration.Stability.Score))
+			if !iteration.Stability.Stable {
+				log.Info("accepted test file is flaky", "file", result.Filename, "score", iteration.Stability.Score)
+				iteration.Accepted = false
+				iteration.Feedback = stabilityFeedback(iteration.Stability)
+			}
 		}
 
-		testFileContent, err = os.ReadFile(filename)
-		if err != nil {
-			return "", fmt.Errorf("ReadTestFile failed: %w", err)
+		span.SetAttributes(
+			attribute.Bool("tests_passed", iteration.TestsPassed),
+			attribute.Bool("accepted", iteration.Accepted),
+		)
+		telemetry.EndSpan(span, nil)
+		result.Iterations = append(result.Iterations, iteration)
+
+		if iteration.Accepted {
+			log.Info("evaluator accepted the test file", "file", result.Filename, "iterations", len(result.Iterations))
+			result.Accepted = true
+			break
 		}
 
-		feedback = `FEEDBACK: ` + feedback
+		testFileContent = iteration.Content
+		feedback = `FEEDBACK: ` + iteration.Feedback
 		loopCount++
 	}
 
-	return filename, nil
+	return result, nil
 }
 
 // Tests generates test files based on a URL using the LLM client
 // It also stores the generated test files in a temporary directory
 func generateTestFile(ctx context.Context, client *llm.Client, analyzerReturn *models.AnalyzerReturn, prevMessages []anthropic.MessageParam, feedback string, testFileContent string, testsDir string, index int) (string, []anthropic.MessageParam, error) {
-
-	logger.Debug("Starting generateTestFile")
+	ctx = telemetry.WithPhase(ctx, "generator")
+	log := logger.For(ctx, "generator")
 
 	if analyzerReturn == nil {
 		return "", prevMessages, fmt.Errorf("analyzerReturn is nil")
@@ -74,13 +132,19 @@ func generateTestFile(ctx context.Context, client *llm.Client, analyzerReturn *m
 
 	var builder strings.Builder
 
-	builder.WriteString("INPUTS: \n")
+	builder.WriteString(promptguard.Instructions)
+	builder.WriteString("\n\nINPUTS: \n")
 	builder.WriteString("TECHNICAL SPECIFICATION: ")
 	builder.WriteString(analyzerReturn.TechSpec)
 	builder.WriteString("\nCONTENT MAP (SEPARATED BY 2 NEWLINES): ")
-	for url, content := range analyzerReturn.ContentMap {
+	wrapped, flagged := promptguard.WrapAll(analyzerReturn.ContentMap)
+	if len(flagged) > 0 {
+		log.Warn("instruction-like text in the content map", "urls", flagged)
+	}
+	for url, content := range wrapped {
 		builder.WriteString(fmt.Sprintf("%s: %s\n\n", url, content))
 	}
+	builder.WriteString(accessibilityContext(analyzerReturn.Accessibility))
 	builder.WriteString("\nTEST CRITERIA: ")
 	builder.WriteString(analyzerReturn.Criteria)
 	builder.WriteString("\n---END PAGE---\n\n")
@@ -93,8 +157,7 @@ the test you need to write. Focus on the provided criteria and tech spec. Your o
 
 Important points:
 - Focus on the provided criteria
-- Do not add any other dependencies, only @playwright/test is allowed.
-- The test file should be around 100 lines of code, the closer the better.
+` + dependencyRules(ctx) + signInRules(ctx) + `- The test file should be around 100 lines of code, the closer the better.
 - Write consise test cases that won't fail instead of complex cases.
 
 Format the tests following Playwright best practices with clear test descriptions and organized test suites.`
@@ -120,18 +183,15 @@ Invalid formatting will cause errors in processing your response.
 TEST FILE CURRENT CONTENT:
 
 ` + testFileContent
-		fmt.Println("\n[GENERATOR] Calling Generator with feedback.")
+		log.Info("calling generator with feedback")
 	} else {
-		fmt.Println("\n[GENERATOR] Calling Generator with base prompt.")
+		log.Info("calling generator with base prompt")
 	}
 
 	// INFO: for a structured response the client requires tools, ref: https://docs.anthropic.com/en/docs/build-with-claude/tool-use/overview
 	tool, toolChoice := llm.GenerateTool[models.GenerateTestReturn]("get_generate_test_file_return", "Generate structured Playwright e2e test suite based on provided inputs. Return organized TypeScript code with proper test organization, assertions, and comments.")
 
-	logger.Debug("GENERATOR Sending request to LLM with context length: %d characters", len(context))
-	logger.Debug("GENERATOR feedback: %s", feedback)
-	logger.Debug("GENERATOR Prompt:\n %s", basePrompt)
-	logger.Debug("GENERATOR Previous messages:\n %v", prevMessages)
+	log.Debug("sending request to LLM", "context_length", len(context), "feedback", feedback, "prompt", basePrompt, "previous_messages", len(prevMessages))
 	rawResponse, err := client.GetStructuredCompletion(
 		ctx,
 		context,
@@ -141,28 +201,25 @@ TEST FILE CURRENT CONTENT:
 		prevMessages,
 	)
 	if err != nil {
-		logger.Debug("LLM request failed: %v", err)
+		log.Error("LLM request failed", "error", err)
 		return "", prevMessages, fmt.Errorf("couldn't process request: %w", err)
 	}
-	logger.Debug("Received response from LLM with length: %d characters", len(rawResponse))
-	logger.Debug("GENERATOR Response:\n %s", string(rawResponse))
+	log.Debug("received response from LLM", "length", len(rawResponse), "response", string(rawResponse))
 
 	newMessages := append(prevMessages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(string(rawResponse))))
 
-	logger.Debug("Unmarshalling LLM response")
 	var response models.GenerateTestReturn
 	if err := json.Unmarshal(rawResponse, &response); err != nil {
-		logger.Debug("Primary unmarshal failed, trying fallback: %v", err)
+		log.Debug("primary unmarshal failed, trying fallback", "error", err)
 		var interlayer struct {
 			FileName     string   `json:"filename"`
 			Content      string   `json:"content"`
 			Dependencies []string `json:"dependencies"`
 		}
 		if err := json.Unmarshal(rawResponse, &interlayer); err != nil {
-			logger.Debug("Interlayer unmarshal failed: %v", err)
+			log.Debug("interlayer unmarshal failed", "error", err)
 			return "", newMessages, fmt.Errorf("couldn't process response: %w", err)
 		}
-		logger.Debug("Interlayer unmarshal successful")
 
 		response = models.GenerateTestReturn{
 			FileName:     interlayer.FileName,
@@ -171,14 +228,11 @@ TEST FILE CURRENT CONTENT:
 		}
 	}
 
-	logger.Debug("Validating response")
 	if err := response.Validate(); err != nil {
-		logger.Debug("Response validation failed: %v", err)
+		log.Debug("response validation failed", "error", err)
 		return "", newMessages, fmt.Errorf("validation failed: %w", err)
 	}
-	logger.Debug("Response validation successful")
 
-	logger.Debug("Write the test file to a temporary file")
 	filePath := filepath.Join(testsDir, fmt.Sprintf("test-%d-%s", index, response.FileName))
 
 	// Write the test file
@@ -187,46 +241,145 @@ TEST FILE CURRENT CONTENT:
 	}
 
 	// TODO: we need to do somethign with the dependencies
-	logger.Debug("Dependencies:")
-	for _, dep := range response.Dependencies {
-		logger.Debug("  - %s\n", dep)
+	log.Info("generated test file", "file", filePath, "dependencies", response.Dependencies)
+	return filePath, newMessages, nil
+}
+
+type allowedDomainsKey struct{}
+
+// WithAllowedDomains returns a context whose generated tests may also reach the domains, like auth providers and CDNs of the project
+func WithAllowedDomains(ctx context.Context, domains []string) context.Context {
+	return context.WithValue(ctx, allowedDomainsKey{}, domains)
+}
+
+func allowedDomainsFrom(ctx context.Context) []string {
+	domains, _ := ctx.Value(allowedDomainsKey{}).([]string)
+	return domains
+}
+
+// sitePages returns the analyzed pages together with the target URL
+func sitePages(analyzerReturn *models.AnalyzerReturn) []string {
+	pages := make([]string, 0, len(analyzerReturn.ContentMap)+1)
+	for page := range analyzerReturn.ContentMap {
+		pages = append(pages, page)
+	}
+	if target := targetURL(analyzerReturn); target != "" {
+		pages = append(pages, target)
 	}
+	sort.Strings(pages)
+	return pages
+}
 
-	fmt.Printf("[GENERATOR] GenerateTest successfuly generated test file: %s\n", filePath)
-	return filePath, newMessages, nil
+// scopeFor returns the hosts of pages and the allowed domains of the context, like the scope of the analyzer
+func scopeFor(ctx context.Context, pages []string) *promptguard.Scope {
+	return promptguard.NewScope(pages, allowedDomainsFrom(ctx))
 }
 
-func evaluateTestFile(ctx context.Context, client *llm.Client, filename string, tempDir string) (string, bool, error) {
+// egressFor restricts the network policy of the context to the hosts of pages and the allowed domains,
+// without any nothing is reachable
+func egressFor(ctx context.Context, pages []string) *netguard.Policy {
+	hosts := make([]string, 0, len(pages))
+	for _, page := range pages {
+		if u, err := netguard.Normalize(page); err == nil {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	hosts = append(hosts, allowedDomainsFrom(ctx)...)
+	return netguard.FromContext(ctx).Restrict(hosts)
+}
+
+// evaluateTestFile runs the test file in the sandbox and lets the evaluator judge it
+// Files failing the safety scan aren't run, the findings are fed back to the generator
+// The browser may only reach the hosts of egress
+// The tests pass when they pass on every project of the matrix in the context, or on its first one without RequireAll
+func evaluateTestFile(ctx context.Context, client *llm.Client, filename string, tempDir string, scope *promptguard.Scope, egress *netguard.Policy) (models.GenEvalIteration, error) {
+	ctx = telemetry.WithPhase(ctx, "evaluator")
+	log := logger.For(ctx, "evaluator")
+	matrix := matrixFrom(ctx)
+
 	// List the provided test file
 	content, err := os.ReadFile(filename)
 	if err != nil {
-		return "", false, fmt.Errorf("couldn't read test file: %w", err)
+		return models.GenEvalIteration{}, fmt.Errorf("couldn't read test file: %w", err)
 	}
 
-	// Run pnpm test
-	testCmd := exec.Command("pnpm", "test", filename)
-	testCmd.Dir = tempDir
-	logger.Debug("Running tests in %s...", tempDir)
-	output, err := testCmd.CombinedOutput()
+	if findings := promptguard.ScanTestCode(string(content), scope, allowedModules(ctx)...); len(findings) > 0 {
+		log.Warn("generated test failed the safety scan", "file", filename, "findings", findings)
+		return models.GenEvalIteration{
+			Content:  string(content),
+			Output:   "Not run, the safety scan found: " + strings.Join(findings, "; "),
+			Feedback: "The test file was not run because it " + strings.Join(findings, ", it ") + ". Only use the allowed dependencies and only visit pages of the website under test.",
+			Findings: findings,
+		}, nil
+	}
+
+	// Run pnpm test, the config also writes a JSON report with the results per project
+	reportPath := filepath.Join(tempDir, reportFile)
+	os.Remove(reportPath)
+	log.Debug("running tests", "dir", tempDir)
+	run, err := runner.FromContext(ctx).Run(ctx, runner.Spec{
+		Step:    "test",
+		Phase:   runner.PhaseTest,
+		Command: []string{"pnpm", "test", filename},
+		Dir:     tempDir,
+		Egress:  egress,
+	})
+	if err != nil {
+		return models.GenEvalIteration{}, fmt.Errorf("couldn't run tests: %w", err)
+	}
+	// A canceled job doesn't need the evaluator
+	if err := ctx.Err(); err != nil {
+		return models.GenEvalIteration{}, fmt.Errorf("tests canceled: %w", err)
+	}
+	output := string(run.Output)
+	if run.Failure != nil {
+		output += fmt.Sprintf("\nSANDBOX STOPPED THE TESTS (%s): %s", run.Failure.Reason, run.Failure.Detail)
+	}
+	iteration := models.GenEvalIteration{
+		Content:     string(content),
+		TestsPassed: run.Passed(),
+		Output:      output,
+		DurationMs:  run.Duration.Milliseconds(),
+		Failure:     run.Failure,
+	}
+	// The next run clears test-results, so its traces, screenshots and videos are moved aside for the run record
+	iteration.ArtifactDir, iteration.Artifacts, err = collectArtifacts(filepath.Join(tempDir, "test-results"), filepath.Join(tempDir, "artifacts"))
 	if err != nil {
-		logger.Debug("❌ Tests failed!\n")
-		logger.Debug("Error executing pnpm test: %v\n", err)
-		logger.Debug("Test output: %s\n", output)
+		log.Warn("couldn't collect the test artifacts", "error", err)
+	}
+	if data, err := os.ReadFile(reportPath); err != nil {
+		log.Debug("no JSON report, the exit code decides", "error", err)
+	} else if iteration.Projects, err = projectResults(data, matrix); err != nil {
+		log.Warn("couldn't parse the JSON report", "error", err)
+	} else if run.Failure == nil && !matrix.RequireAll {
+		// The exit code covers every project, without RequireAll only the primary one counts
+		iteration.TestsPassed = primaryPassed(iteration.Projects)
+	}
+	if len(run.Blocked) > 0 {
+		log.Warn("tests requested hosts outside the target", "hosts", run.Blocked)
+	}
+	if !iteration.TestsPassed {
+		log.Debug("tests failed", "error", run.Err(), "output", output, "projects", iteration.Projects)
 		// but we don't want to return, we want to continue the loop
 	} else {
-		logger.Debug("✅ Tests passed successfully!\n")
+		log.Debug("tests passed")
 	}
 
 	// Analyze the test output
 	var builder strings.Builder
 
-	builder.WriteString("INPUTS: \n")
+	builder.WriteString(promptguard.Instructions)
+	builder.WriteString("\n\nINPUTS: \n")
 	builder.WriteString("\nTEST FILE NAME: ")
 	builder.WriteString(filename)
 	builder.WriteString("\nTEST FILE CONTENTS: ")
 	builder.WriteString(string(content))
 	builder.WriteString("\nTEST OUTPUT: ")
-	builder.WriteString(string(output))
+	builder.WriteString(promptguard.Wrap("test output", string(output)))
+	if len(iteration.Projects) > 1 {
+		builder.WriteString("\nRESULTS PER PROJECT:\n")
+		builder.WriteString(promptguard.Wrap("project results", projectSummary(iteration.Projects, matrix)))
+	}
 	builder.WriteString("\n---END PAGE---\n\n")
 
 	context := builder.String()
@@ -234,10 +387,12 @@ func evaluateTestFile(ctx context.Context, client *llm.Client, filename string,
 	basePrompt := `You are a test engineer, your task is to evaluate the test file and provide feedback on the test file.
 Your feedback should be concise and to the point. You should provide feedback on the following:
 - Focus mainly on fixing the failing tests.
-- The only allowed dependency is @playwright/test, no other dependencies are allowed.
-- Whether the test file is covering the provided criteria
+` + dependencyRules(ctx) + `- Whether the test file is covering the provided criteria
 - The length of the test file should be around 100 lines of code, the closer the better.
 - Whether the test scope is too broad. If the test file is more than 100 lines of code, it is too broad, so suggest what tests to remove (prioritize removing the tests that are failing)
+- If the tests fail on some browser or device projects only, suggest fixes that work on all of them, e.g. for the navigation of small mobile viewports. Projects that are only reported don't need to pass.
+- If a screenshot of the last failure is attached, use what the page looked like to explain the failure.
+- If the sandbox stopped the tests, suggest how to stay within its limits: shorter waits for a timeout, fewer pages for memory or CPU, less logging for output, and only pages of the website for blocked hosts.
 
 
 IMPORTANT: When providing the feedback, ensure proper JSON formatting:
@@ -257,67 +412,78 @@ Invalid formatting will cause errors in processing your response.
 	// INFO: for a structured response the client requires tools, ref: https://docs.anthropic.com/en/docs/build-with-claude/tool-use/overview
 	tool, toolChoice := llm.GenerateTool[models.EvaluationReturn]("get_generate_feedback_return", "")
 
-	fmt.Printf("[EVALUATOR] Calling Evaluator with context length: %d characters\n", len(context))
-	fmt.Println("[EVALUATOR] and test result + file content")
+	messages := []anthropic.MessageParam{}
+	if !iteration.TestsPassed && evaluatorScreenshotFrom(ctx) {
+		if screenshot, ok := failureScreenshot(iteration); ok {
+			messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock("Screenshot of the page at the last test failure:"), screenshot))
+		}
+	}
+
+	log.Info("calling evaluator with the test result and file content", "context_length", len(context), "screenshot", len(messages) > 0)
 	rawResponse, err := client.GetStructuredCompletion(
 		ctx,
 		context,
 		basePrompt,
 		tool,
 		toolChoice,
-		[]anthropic.MessageParam{},
+		messages,
 	)
 	if err != nil {
-		return "", false, fmt.Errorf("couldn't process request: %w", err)
+		return iteration, fmt.Errorf("couldn't process request: %w", err)
 	}
 
 	var response models.EvaluationReturn
 	if err := json.Unmarshal(rawResponse, &response); err != nil {
-		return "", false, fmt.Errorf("couldn't unmarshal response: %w", err)
+		return iteration, fmt.Errorf("couldn't unmarshal response: %w", err)
 	}
 
 	if response.Passed {
-		fmt.Printf("[EVALUATOR] Evaluator accepted the test file ✅\n")
-		return "", true, nil
+		log.Info("evaluator accepted the test file")
+		iteration.Accepted = true
+		return iteration, nil
 	}
 
-	fmt.Printf("[EVALUATOR] Evaluator rejected the test file ❌\n")
-	fmt.Printf("[EVALUATOR] With the following feedback:\n\n %s\n", response.Feedback)
+	log.Info("evaluator rejected the test file", "feedback", response.Feedback)
 
-	return response.Feedback, false, nil
+	iteration.Feedback = response.Feedback
+	return iteration, nil
 }
 
 // SetupTestEnvironment creates a temporary directory and copies the config files to it
+// The Playwright config is generated for baseURL and the projects of the matrix in the context
 // returns the temp directory and the tests directory (which is just a subdirectory /tests in the temp directory)
-func SetupTestEnvironment(ctx context.Context) (string, string, error) {
+func SetupTestEnvironment(ctx context.Context, baseURL string) (string, string, error) {
+	log := logger.For(ctx, "gen_eval")
+
+	matrix := matrixFrom(ctx)
+	if err := matrix.Validate(); err != nil {
+		return "", "", err
+	}
+
 	// Create a temporary directory to store the test files
 	tempDir, err := os.MkdirTemp("", "playwright-tests-")
 	if err != nil {
-		logger.Debug("Error creating temporary directory: %v\n", err)
 		return "", "", fmt.Errorf("couldn't create temporary directory: %w", err)
 	}
 
-	logger.Debug("Created temporary directory at: %s", tempDir)
+	log.Debug("created temporary directory", "dir", tempDir)
 
 	// Create a tests directory within the temporary directory
 	testsDir := filepath.Join(tempDir, "tests")
 	if err := os.MkdirAll(testsDir, 0755); err != nil {
-		logger.Debug("Error creating tests directory: %v\n", err)
 		return tempDir, "", fmt.Errorf("couldn't create tests directory: %w", err)
 	}
-	logger.Debug("Created tests directory at: %s", testsDir)
 
 	// Get the absolute path to the src directory
 	currentDir, err := os.Getwd()
 	if err != nil {
-		logger.Debug("Error getting current directory: %v\n", err)
 		return tempDir, testsDir, fmt.Errorf("couldn't get current directory: %w", err)
 	}
 
 	templatePath := filepath.Join(currentDir, "internal/repository/gen_eval_loop/nodeTemplate")
 
 	// Files to copy
-	filesToCopy := []string{"tsconfig.json", "pnpm-lock.yaml", "package.json", "playwright.config.ts"}
+	filesToCopy := []string{"tsconfig.json", "pnpm-lock.yaml", "package.json", "throttle.ts"}
 
 	// Copy each file from template to temp directory
 	for _, file := range filesToCopy {
@@ -326,49 +492,59 @@ func SetupTestEnvironment(ctx context.Context) (string, string, error) {
 
 		src, err := os.Open(srcFile)
 		if err != nil {
-			logger.Debug("Error opening source file %s: %v\n", file, err)
 			return tempDir, testsDir, fmt.Errorf("couldn't open source file %s: %w", file, err)
 		}
 		defer src.Close()
 
 		dst, err := os.Create(dstFile)
 		if err != nil {
-			logger.Debug("Error creating destination file %s: %v\n", file, err)
 			return tempDir, testsDir, fmt.Errorf("couldn't create destination file %s: %w", file, err)
 		}
 		defer dst.Close()
 
 		if _, err = io.Copy(dst, src); err != nil {
-			logger.Debug("Error copying file %s: %v\n", file, err)
 			return tempDir, testsDir, fmt.Errorf("couldn't copy file %s: %w", file, err)
 		}
 	}
 
-	// Run pnpm install
-	installCmd := exec.Command("pnpm", "i")
-	installCmd.Dir = tempDir
-	logger.Debug("Running pnpm install in %s...", tempDir)
-	output, err := installCmd.CombinedOutput()
+	if err := writePlaywrightConfig(tempDir, baseURL, matrix); err != nil {
+		return tempDir, testsDir, err
+	}
+
+	// Run pnpm install, the registry and the browser downloads are outside the target so egress isn't limited
+	sandbox := runner.FromContext(ctx)
+	log.Debug("running pnpm install", "dir", tempDir)
+	install, err := sandbox.Run(ctx, runner.Spec{Step: "install", Phase: runner.PhaseInstall, Command: []string{"pnpm", "i"}, Dir: tempDir})
 	if err != nil {
-		logger.Debug("❌ Installation failed!\n")
-		logger.Debug("Error executing pnpm install: %v\n", err)
-		logger.Debug("Installation output: %s\n", output)
 		return tempDir, testsDir, fmt.Errorf("couldn't execute pnpm install: %w", err)
 	}
-	logger.Debug("✅ Installation completed successfully!\n")
+	if err := install.Err(); err != nil {
+		log.Error("pnpm install failed", "error", err, "output", string(install.Output))
+		return tempDir, testsDir, fmt.Errorf("couldn't execute pnpm install: %w", err)
+	}
 
-	// Install browsers
-	playwrightCmd := exec.Command("npx", "playwright", "install")
-	playwrightCmd.Dir = tempDir
-	logger.Debug("Running npx playwright install in %s...", tempDir)
-	output, err = playwrightCmd.CombinedOutput()
+	if axeFrom(ctx) {
+		log.Debug("adding the accessibility test dependency", "dir", tempDir, "module", axeModule)
+		install, err = sandbox.Run(ctx, runner.Spec{Step: "install_axe", Phase: runner.PhaseInstall, Command: []string{"pnpm", "add", "-D", axeModule + "@" + axeVersion}, Dir: tempDir})
+		if err != nil {
+			return tempDir, testsDir, fmt.Errorf("couldn't add %s: %w", axeModule, err)
+		}
+		if err := install.Err(); err != nil {
+			log.Error("adding the accessibility test dependency failed", "error", err, "output", string(install.Output))
+			return tempDir, testsDir, fmt.Errorf("couldn't add %s: %w", axeModule, err)
+		}
+	}
+
+	// Install the browsers of the matrix
+	log.Debug("running playwright install", "dir", tempDir, "browsers", matrix.browsers())
+	install, err = sandbox.Run(ctx, runner.Spec{Step: "install_browsers", Phase: runner.PhaseInstall, Command: append([]string{"npx", "playwright", "install"}, matrix.browsers()...), Dir: tempDir})
 	if err != nil {
-		logger.Debug("❌ Installation failed!\n")
-		logger.Debug("Error executing npx playwright install: %v\n", err)
-		logger.Debug("Installation output: %s\n", output)
 		return tempDir, testsDir, fmt.Errorf("couldn't install playwright: %w", err)
 	}
-	logger.Debug("✅ Installation completed successfully!\n")
+	if err := install.Err(); err != nil {
+		log.Error("playwright install failed", "error", err, "output", string(install.Output))
+		return tempDir, testsDir, fmt.Errorf("couldn't install playwright: %w", err)
+	}
 
 	return tempDir, testsDir, nil
 }
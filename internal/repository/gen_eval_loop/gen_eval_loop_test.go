@@ -0,0 +1,44 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+)
+
+func TestTargetScope(t *testing.T) {
+	cfg := &config.Config{AllowedDomains: []string{"shop.example.com", "auth.example.org"}}
+	ctx := netguard.NewContext(WithConfig(context.Background(), cfg), netguard.ForConfig(cfg))
+
+	pages := sitePages(&models.AnalyzerReturn{
+		ContentMap: map[string]string{"https://shop.example.com/cart": ""},
+		BaseURL:    "https://shop.example.com",
+	})
+	scope := scopeFor(ctx, pages)
+	egress := egressFor(ctx, pages)
+
+	// The project's domains are reachable like in the analyzer, everything else is blocked without resolving it
+	for host, allowed := range map[string]bool{
+		"shop.example.com": true,
+		"auth.example.org": true,
+		"cdn.example.net":  false,
+		"attacker.example": false,
+	} {
+		if scope.Contains("https://"+host+"/") != allowed {
+			t.Errorf("%s: expected the scope to allow it: %v", host, allowed)
+		}
+		err := egress.Check(ctx, &url.URL{Scheme: "https", Host: host})
+		if errors.Is(err, netguard.ErrBlocked) == allowed {
+			t.Errorf("%s: expected the egress to allow it: %v, got %v", host, allowed, err)
+		}
+	}
+
+	if err := egressFor(context.Background(), nil).Check(ctx, &url.URL{Scheme: "https", Host: "shop.example.com"}); !errors.Is(err, netguard.ErrBlocked) {
+		t.Errorf("Expected no egress without pages, got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+import { test as base } from "@playwright/test";
+
+export * from "@playwright/test";
+
+// Slows down the CPU of Chromium pages by TESTBUDDY_CPU_THROTTLE during the stability runs
+export const test = base.extend<{ cpuThrottle: void }>({
+  cpuThrottle: [
+    async ({ page, browserName }, use) => {
+      const rate = Number(process.env.TESTBUDDY_CPU_THROTTLE ?? 1);
+      if (browserName === "chromium" && rate > 1) {
+        const session = await page.context().newCDPSession(page);
+        await session.send("Emulation.setCPUThrottlingRate", { rate });
+      }
+      await use();
+    },
+    { auto: true },
+  ],
+});
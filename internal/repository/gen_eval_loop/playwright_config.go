@@ -0,0 +1,232 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+const (
+	// reportFile is the JSON report the generated config writes next to the list output
+	reportFile = "report.json"
+	// storageStateFile is the copy of the signed-in session in the test environment
+	storageStateFile = "storage-state.json"
+)
+
+// projectDevices maps the selectable projects to their Playwright device descriptors
+var projectDevices = map[string]string{
+	"chromium": "Desktop Chrome",
+	"firefox":  "Desktop Firefox",
+	"webkit":   "Desktop Safari",
+	"pixel":    "Pixel 7",
+	"iphone":   "iPhone 14",
+}
+
+// projectBrowsers maps the selectable projects to the browser Playwright installs for them
+var projectBrowsers = map[string]string{
+	"chromium": "chromium",
+	"firefox":  "firefox",
+	"webkit":   "webkit",
+	"pixel":    "chromium",
+	"iphone":   "webkit",
+}
+
+// DefaultProjects are run when no project is selected
+var DefaultProjects = []string{"chromium"}
+
+// Matrix is the set of Playwright projects and the browser context settings generated tests run with
+type Matrix struct {
+	// Projects are names of projectDevices, the first one is the primary project
+	Projects    []string
+	Locale      string
+	TimezoneID  string
+	ColorScheme string
+	// StorageState is a Playwright storage state file, every project starts signed in with its session
+	StorageState string
+	// RequireAll only lets tests pass when they pass on every project, otherwise the primary project decides
+	RequireAll bool
+}
+
+// MatrixForConfig returns the matrix configured in cfg
+func MatrixForConfig(cfg *config.Config) Matrix {
+	return Matrix{
+		Projects:     cfg.TestProjects,
+		Locale:       cfg.TestLocale,
+		TimezoneID:   cfg.TestTimezone,
+		ColorScheme:  cfg.TestColorScheme,
+		StorageState: cfg.StorageState,
+		RequireAll:   cfg.RequireAllProjects,
+	}
+}
+
+// Validate reports unknown projects and color schemes
+func (m Matrix) Validate() error {
+	for _, project := range m.Projects {
+		if _, ok := projectDevices[project]; !ok {
+			return fmt.Errorf("unknown project %q, use one of %s", project, strings.Join(SupportedProjects(), ", "))
+		}
+	}
+	switch m.ColorScheme {
+	case "", "light", "dark", "no-preference":
+	default:
+		return fmt.Errorf("unknown color scheme %q, use light, dark or no-preference", m.ColorScheme)
+	}
+	return nil
+}
+
+// SupportedProjects returns the names of the selectable projects
+func SupportedProjects() []string {
+	projects := make([]string, 0, len(projectDevices))
+	for project := range projectDevices {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+func (m Matrix) projects() []string {
+	if len(m.Projects) == 0 {
+		return DefaultProjects
+	}
+	return m.Projects
+}
+
+// required returns the projects tests have to pass on, every project with RequireAll and the primary one otherwise
+func (m Matrix) required() []string {
+	projects := m.projects()
+	if m.RequireAll {
+		return projects
+	}
+	return projects[:1]
+}
+
+// browsers returns the browsers the projects need, in the order of the projects
+func (m Matrix) browsers() []string {
+	var browsers []string
+	for _, project := range m.projects() {
+		if browser := projectBrowsers[project]; !slices.Contains(browsers, browser) {
+			browsers = append(browsers, browser)
+		}
+	}
+	return browsers
+}
+
+type matrixKey struct{}
+
+// WithMatrix returns a context whose test environments run the projects of matrix
+func WithMatrix(ctx context.Context, matrix Matrix) context.Context {
+	return context.WithValue(ctx, matrixKey{}, matrix)
+}
+
+func matrixFrom(ctx context.Context) Matrix {
+	matrix, _ := ctx.Value(matrixKey{}).(Matrix)
+	return matrix
+}
+
+// WithConfig returns a context carrying the stabilization, the project matrix, the evaluator screenshots and the allowed domains of cfg
+func WithConfig(ctx context.Context, cfg *config.Config) context.Context {
+	ctx = WithStability(ctx, StabilityForConfig(cfg))
+	ctx = WithAllowedDomains(ctx, cfg.AllowedDomains)
+	ctx = WithEvaluatorScreenshot(ctx, cfg.EvaluatorScreenshots)
+	ctx = WithAxe(ctx, cfg.AxeTests)
+	return WithMatrix(ctx, MatrixForConfig(cfg))
+}
+
+// targetURL returns the base URL of the analyzed target, the origin of its first page when it isn't set
+func targetURL(analyzerReturn *models.AnalyzerReturn) string {
+	if analyzerReturn.BaseURL != "" {
+		return analyzerReturn.BaseURL
+	}
+	pages := make([]string, 0, len(analyzerReturn.ContentMap))
+	for page := range analyzerReturn.ContentMap {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	for _, page := range pages {
+		if u, err := url.Parse(page); err == nil && u.Scheme != "" && u.Host != "" {
+			return u.Scheme + "://" + u.Host
+		}
+	}
+	return ""
+}
+
+// signInRules tells the generator that the tests start signed in when the matrix has a storage state
+func signInRules(ctx context.Context) string {
+	if matrixFrom(ctx).StorageState == "" {
+		return ""
+	}
+	return "- The browser starts signed in to the website, don't sign in or out in the tests.\n"
+}
+
+// writePlaywrightConfig writes the playwright.config.ts of a test environment
+// The list reporter feeds the evaluator, the JSON report the results per project
+// The storage state of the matrix is copied into dir, so the sandbox can read it
+func writePlaywrightConfig(dir, baseURL string, matrix Matrix) error {
+	var use strings.Builder
+	writeOption := func(name, value string) {
+		if value == "" {
+			return
+		}
+		quoted, _ := json.Marshal(value)
+		fmt.Fprintf(&use, "    %s: %s,\n", name, quoted)
+	}
+	writeOption("baseURL", baseURL)
+	writeOption("locale", matrix.Locale)
+	writeOption("timezoneId", matrix.TimezoneID)
+	writeOption("colorScheme", matrix.ColorScheme)
+	if matrix.StorageState != "" {
+		state, err := os.ReadFile(matrix.StorageState)
+		if err != nil {
+			return fmt.Errorf("couldn't read the storage state: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, storageStateFile), state, 0600); err != nil {
+			return fmt.Errorf("couldn't copy the storage state: %w", err)
+		}
+		writeOption("storageState", storageStateFile)
+	}
+
+	var projects strings.Builder
+	for _, project := range matrix.projects() {
+		name, _ := json.Marshal(project)
+		device, _ := json.Marshal(projectDevices[project])
+		fmt.Fprintf(&projects, "    {\n      name: %s,\n      use: { ...devices[%s] },\n    },\n", name, device)
+	}
+
+	content := fmt.Sprintf(`import { defineConfig, devices } from "@playwright/test";
+
+// Generated for every test run, edits are overwritten
+
+// The sandbox routes the browser through its egress proxy, which only lets it reach the target
+const proxy = process.env.TESTBUDDY_PROXY
+  ? { server: process.env.TESTBUDDY_PROXY }
+  : undefined;
+
+export default defineConfig({
+  testDir: "./tests",
+  fullyParallel: true,
+  reporter: [["list"], ["json", { outputFile: %q }]],
+  use: {
+%s    trace: "retain-on-failure",
+    screenshot: "only-on-failure",
+    video: "retain-on-failure",
+    proxy,
+  },
+  projects: [
+%s  ],
+});
+`, reportFile, use.String(), projects.String())
+
+	if err := os.WriteFile(filepath.Join(dir, "playwright.config.ts"), []byte(content), 0644); err != nil {
+		return fmt.Errorf("couldn't write playwright config: %w", err)
+	}
+	return nil
+}
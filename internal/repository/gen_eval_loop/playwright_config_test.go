@@ -0,0 +1,119 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestWritePlaywrightConfig(t *testing.T) {
+	dir := t.TempDir()
+	matrix := Matrix{Projects: []string{"chromium", "iphone"}, Locale: "de-DE", ColorScheme: "dark"}
+	if err := writePlaywrightConfig(dir, "https://shop.example.com", matrix); err != nil {
+		t.Fatalf("writePlaywrightConfig failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "playwright.config.ts"))
+	for _, expected := range []string{
+		`baseURL: "https://shop.example.com",`,
+		`locale: "de-DE",`,
+		`colorScheme: "dark",`,
+		`name: "iphone",`,
+		`use: { ...devices["iPhone 14"] },`,
+		`outputFile: "report.json"`,
+		`trace: "retain-on-failure",`,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected the config to contain %s, got:\n%s", expected, content)
+		}
+	}
+	if strings.Contains(string(content), "timezoneId") {
+		t.Errorf("Expected no timezone when it isn't set, got:\n%s", content)
+	}
+
+	if browsers := matrix.browsers(); len(browsers) != 2 || browsers[1] != "webkit" {
+		t.Errorf("Expected chromium and webkit to be installed, got %v", browsers)
+	}
+	if err := (Matrix{Projects: []string{"edge"}}).Validate(); err == nil {
+		t.Error("Expected an unknown project to be rejected")
+	}
+}
+
+func TestStorageState(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "auth.json")
+	os.WriteFile(state, []byte(`{"cookies":[],"origins":[]}`), 0600)
+
+	dir := t.TempDir()
+	matrix := Matrix{StorageState: state}
+	if err := writePlaywrightConfig(dir, "https://shop.example.com", matrix); err != nil {
+		t.Fatalf("writePlaywrightConfig failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(dir, "playwright.config.ts"))
+	if !strings.Contains(string(content), `storageState: "storage-state.json",`) {
+		t.Errorf("Expected the config to start signed in, got:\n%s", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, storageStateFile)); err != nil {
+		t.Errorf("Expected the storage state to be copied into the test environment: %v", err)
+	}
+	if rules := signInRules(WithMatrix(context.Background(), matrix)); rules == "" {
+		t.Error("Expected the generator to be told that the tests start signed in")
+	}
+
+	if err := writePlaywrightConfig(dir, "", Matrix{StorageState: filepath.Join(dir, "missing.json")}); err == nil {
+		t.Error("Expected a missing storage state to fail")
+	}
+}
+
+func TestProjectResults(t *testing.T) {
+	report := `{
+	  "suites": [{
+	    "title": "test-1-menu.spec.ts",
+	    "specs": [{
+	      "title": "opens the menu",
+	      "tests": [
+	        {"projectName": "chromium", "results": [{"status": "passed"}]},
+	        {"projectName": "iphone", "results": [
+	          {"status": "failed", "errors": [{"message": "first attempt"}]},
+	          {"status": "failed", "errors": [{"message": "locator.click: element is not visible"}]}
+	        ]}
+	      ]
+	    }]
+	  }]
+	}`
+
+	for _, tc := range []struct {
+		name       string
+		requireAll bool
+		summary    string
+	}{
+		{name: "primary decides", requireAll: false, summary: "(reported only)"},
+		{name: "all required", requireAll: true, summary: "(must pass)"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			matrix := Matrix{Projects: []string{"chromium", "iphone"}, RequireAll: tc.requireAll}
+			results, err := projectResults([]byte(report), matrix)
+			if err != nil {
+				t.Fatalf("projectResults failed: %v", err)
+			}
+
+			expected := []models.ProjectResult{
+				{Project: "chromium", Passed: 1},
+				{Project: "iphone", Failed: 1, Failures: []string{"opens the menu: locator.click: element is not visible"}},
+			}
+			if len(results) != 2 || results[0].Passed != 1 || results[1].Failed != 1 || results[1].Failures[0] != expected[1].Failures[0] {
+				t.Fatalf("Expected %+v, got %+v", expected, results)
+			}
+			if !primaryPassed(results) {
+				t.Error("Expected the primary project to pass")
+			}
+			if summary := projectSummary(results, matrix); !strings.Contains(summary, "- iphone: 0 passed, 1 failed, 0 skipped "+tc.summary) {
+				t.Errorf("Expected the iphone project marked %s, got:\n%s", tc.summary, summary)
+			}
+		})
+	}
+}
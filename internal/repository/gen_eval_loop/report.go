@@ -0,0 +1,148 @@
+package gen_eval_loop
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+// Playwright JSON report, only the fields the stability and project results need
+type jsonReport struct {
+	Suites []jsonSuite `json:"suites"`
+}
+
+type jsonSuite struct {
+	Title  string      `json:"title"`
+	Suites []jsonSuite `json:"suites"`
+	Specs  []jsonSpec  `json:"specs"`
+}
+
+type jsonSpec struct {
+	Title string     `json:"title"`
+	Tests []jsonTest `json:"tests"`
+}
+
+type jsonTest struct {
+	ProjectName string       `json:"projectName"`
+	Results     []jsonResult `json:"results"`
+}
+
+type jsonResult struct {
+	Status   string      `json:"status"`
+	Duration float64     `json:"duration"`
+	Errors   []jsonError `json:"errors"`
+}
+
+type jsonError struct {
+	Message string `json:"message"`
+}
+
+// forEachTest parses a JSON report and calls handle for every test of every project
+// The title joins the describe blocks and the test title, the file suite is left out
+func forEachTest(data []byte, handle func(title string, test jsonTest)) error {
+	var report jsonReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return err
+	}
+
+	var walk func(suite jsonSuite, path []string)
+	walk = func(suite jsonSuite, path []string) {
+		for _, spec := range suite.Specs {
+			title := strings.Join(append(append([]string{}, path...), spec.Title), " › ")
+			for _, test := range spec.Tests {
+				handle(title, test)
+			}
+		}
+		for _, child := range suite.Suites {
+			walk(child, append(path, child.Title))
+		}
+	}
+	for _, file := range report.Suites {
+		walk(file, nil)
+	}
+	return nil
+}
+
+// failureMessage returns the first error of a failed result without colors, cut to maxFailureLength
+func failureMessage(result jsonResult) string {
+	message := result.Status
+	if len(result.Errors) > 0 {
+		message = ansiEscape.ReplaceAllString(result.Errors[0].Message, "")
+	}
+	if len(message) > maxFailureLength {
+		message = message[:maxFailureLength] + "..."
+	}
+	return message
+}
+
+// projectResults counts the final result of every test per project, the projects of the matrix come first in their order
+// Retried tests count with their last attempt
+func projectResults(data []byte, matrix Matrix) ([]models.ProjectResult, error) {
+	var results []models.ProjectResult
+	index := map[string]int{}
+	get := func(project string) *models.ProjectResult {
+		i, ok := index[project]
+		if !ok {
+			i = len(results)
+			index[project] = i
+			results = append(results, models.ProjectResult{Project: project})
+		}
+		return &results[i]
+	}
+	for _, project := range matrix.projects() {
+		get(project)
+	}
+
+	err := forEachTest(data, func(title string, test jsonTest) {
+		if len(test.Results) == 0 {
+			return
+		}
+		project := get(test.ProjectName)
+		last := test.Results[len(test.Results)-1]
+		switch last.Status {
+		case "passed":
+			project.Passed++
+		case "skipped":
+			project.Skipped++
+		default:
+			project.Failed++
+			if len(project.Failures) < maxFailures {
+				project.Failures = append(project.Failures, fmt.Sprintf("%s: %s", title, failureMessage(last)))
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// primaryPassed reports whether the tests passed on the primary project, the first of the results
+func primaryPassed(results []models.ProjectResult) bool {
+	return len(results) > 0 && results[0].Failed == 0 && results[0].Passed > 0
+}
+
+// projectSummary describes the results per project for the evaluator
+func projectSummary(results []models.ProjectResult, matrix Matrix) string {
+	var builder strings.Builder
+	for i, result := range results {
+		fmt.Fprintf(&builder, "- %s: %d passed, %d failed, %d skipped", result.Project, result.Passed, result.Failed, result.Skipped)
+		switch {
+		case i == 0 && !matrix.RequireAll:
+			builder.WriteString(" (primary project, it must pass)")
+		case matrix.RequireAll:
+			builder.WriteString(" (must pass)")
+		default:
+			builder.WriteString(" (reported only)")
+		}
+		builder.WriteString("\n")
+		for _, failure := range result.Failures {
+			builder.WriteString("  ")
+			builder.WriteString(failure)
+			builder.WriteString("\n")
+		}
+	}
+	return builder.String()
+}
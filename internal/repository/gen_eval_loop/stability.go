@@ -0,0 +1,307 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+	"github.com/webscopeio/ai-hackathon/internal/runner"
+)
+
+const (
+	// maxFailures is the number of distinct failure messages kept per test
+	maxFailures = 3
+	// maxFailureLength cuts long failure messages, the diff is at their start
+	maxFailureLength = 1500
+)
+
+var (
+	ansiEscape       = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	playwrightImport = regexp.MustCompile(`from\s+['"]@playwright/test['"]`)
+)
+
+// Stability configures the stabilization phase of GenEvalLoop
+// A file the evaluator accepted is run Runs times, tests passing less than Threshold of the runs
+// go back to the generator with their failures
+type Stability struct {
+	Runs      int
+	Threshold float64
+	// CPUThrottle slows down the CPU of Chromium by its factor, 0 or 1 doesn't throttle
+	CPUThrottle int
+	// SeparateProcesses runs every repetition in its own process instead of one process with --repeat-each
+	SeparateProcesses bool
+}
+
+// StabilityForConfig returns the stabilization configured in cfg
+func StabilityForConfig(cfg *config.Config) Stability {
+	return Stability{
+		Runs:              cfg.StabilityRuns,
+		Threshold:         cfg.StabilityThreshold,
+		CPUThrottle:       cfg.StabilityCPUThrottle,
+		SeparateProcesses: cfg.StabilityMode == "processes",
+	}
+}
+
+func (s Stability) enabled() bool {
+	return s.Runs > 1
+}
+
+type stabilityKey struct{}
+
+// WithStability returns a context whose gen-eval loops stabilize accepted files
+func WithStability(ctx context.Context, stability Stability) context.Context {
+	return context.WithValue(ctx, stabilityKey{}, stability)
+}
+
+func stabilityFrom(ctx context.Context) Stability {
+	stability, _ := ctx.Value(stabilityKey{}).(Stability)
+	return stability
+}
+
+// stabilizeTestFile runs an accepted test file repeatedly and reports the pass rate and timing of each test
+// Only the projects tests have to pass on are run, projects that are only reported can't make a file flaky
+func stabilizeTestFile(ctx context.Context, filename, tempDir string, egress *netguard.Policy, stability Stability) (*models.StabilityReport, error) {
+	log := logger.For(ctx, "stability")
+
+	target := filename
+	if stability.CPUThrottle > 1 {
+		throttled, err := throttledCopy(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(throttled)
+		target = throttled
+	}
+
+	repeats, processes := stability.Runs, 1
+	if stability.SeparateProcesses {
+		repeats, processes = 1, stability.Runs
+	}
+
+	projects := matrixFrom(ctx).required()
+	command := []string{"pnpm", "exec", "playwright", "test", target, "--repeat-each", strconv.Itoa(repeats), "--retries", "0", "--reporter", "json"}
+	for _, project := range projects {
+		command = append(command, "--project", project)
+	}
+
+	reportPath := filepath.Join(tempDir, "stability-report.json")
+	runs := &testRuns{projects: projects, byTitle: map[string]*testRun{}}
+	for i := 0; i < processes; i++ {
+		os.Remove(reportPath)
+		log.Debug("running the accepted test file repeatedly", "file", filename, "repeats", repeats, "process", i+1, "of", processes)
+		run, err := runner.FromContext(ctx).Run(ctx, runner.Spec{
+			Step:    "stability",
+			Phase:   runner.PhaseTest,
+			Command: command,
+			Dir:     tempDir,
+			Env: []string{
+				"PLAYWRIGHT_JSON_OUTPUT_NAME=" + reportPath,
+				"TESTBUDDY_CPU_THROTTLE=" + strconv.Itoa(stability.CPUThrottle),
+			},
+			Egress: egress,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't run the stability runs: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("stability runs canceled: %w", err)
+		}
+
+		data, err := os.ReadFile(reportPath)
+		if err != nil {
+			// Without a report all the runs of the process count as failed
+			reason := "the test run didn't produce a report"
+			if run.Failure != nil {
+				reason = fmt.Sprintf("the sandbox stopped the test run (%s): %s", run.Failure.Reason, run.Failure.Detail)
+			}
+			runs.fail("all tests", repeats, reason)
+			continue
+		}
+		if err := runs.add(data); err != nil {
+			return nil, fmt.Errorf("couldn't parse the stability report: %w", err)
+		}
+	}
+
+	report := runs.report(stability)
+	log.Info("stability runs finished", "file", filename, "runs", stability.Runs, "score", report.Score, "stable", report.Stable)
+	return report, nil
+}
+
+// throttledCopy copies a test file with its Playwright import replaced by the throttling fixture of the template
+func throttledCopy(filename string) (string, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read test file: %w", err)
+	}
+
+	throttled := strings.TrimSuffix(filename, ".spec.ts") + ".throttled.spec.ts"
+	content = playwrightImport.ReplaceAll(content, []byte(`from '../throttle'`))
+	if err := os.WriteFile(throttled, content, 0644); err != nil {
+		return "", fmt.Errorf("couldn't write the throttled test file: %w", err)
+	}
+	return throttled, nil
+}
+
+// stabilityFeedback tells the generator which tests are flaky and how they failed
+func stabilityFeedback(report *models.StabilityReport) string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "The test file passed once, but some tests are flaky over %d runs", report.Runs)
+	if report.CPUThrottle > 1 {
+		fmt.Fprintf(&builder, " with a %dx slower CPU", report.CPUThrottle)
+	}
+	builder.WriteString(":\n")
+	for _, test := range report.Tests {
+		if test.PassRate >= report.Threshold {
+			continue
+		}
+		fmt.Fprintf(&builder, "- %q passed %d of %d runs, taking %.0f ms on average with a standard deviation of %.0f ms.", test.Title, test.Passed, test.Runs, test.MeanMs, test.StdDevMs)
+		if len(test.Failures) > 0 {
+			builder.WriteString(" Failures:\n")
+			for _, failure := range test.Failures {
+				builder.WriteString(failure)
+				builder.WriteString("\n")
+			}
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString("Make these tests deterministic: wait for elements and states with web-first assertions instead of fixed timeouts, and don't depend on animations or the order of network responses.")
+	return builder.String()
+}
+
+// testRuns collects the results of the tests over the repeated runs
+// With projects, results of other projects are left out and, with more than one, a test is counted per project
+type testRuns struct {
+	projects []string
+	titles   []string
+	byTitle  map[string]*testRun
+}
+
+type testRun struct {
+	passed    int
+	durations []float64
+	failures  []string
+}
+
+func (r *testRuns) get(title string) *testRun {
+	run, ok := r.byTitle[title]
+	if !ok {
+		run = &testRun{}
+		r.byTitle[title] = run
+		r.titles = append(r.titles, title)
+	}
+	return run
+}
+
+// add collects the results of a JSON report
+func (r *testRuns) add(data []byte) error {
+	return forEachTest(data, func(title string, test jsonTest) {
+		if len(r.projects) > 0 && !slices.Contains(r.projects, test.ProjectName) {
+			return
+		}
+		if len(r.projects) > 1 {
+			title = fmt.Sprintf("[%s] %s", test.ProjectName, title)
+		}
+		for _, result := range test.Results {
+			r.addResult(title, result)
+		}
+	})
+}
+
+func (r *testRuns) addResult(title string, result jsonResult) {
+	if result.Status == "skipped" {
+		return
+	}
+
+	run := r.get(title)
+	run.durations = append(run.durations, result.Duration)
+	if result.Status == "passed" {
+		run.passed++
+		return
+	}
+
+	message := failureMessage(result)
+	for _, failure := range run.failures {
+		if failure == message {
+			return
+		}
+	}
+	if len(run.failures) < maxFailures {
+		run.failures = append(run.failures, message)
+	}
+}
+
+// fail records runs that produced no results
+func (r *testRuns) fail(title string, runs int, reason string) {
+	run := r.get(title)
+	for i := 0; i < runs; i++ {
+		run.durations = append(run.durations, 0)
+	}
+	if len(run.failures) < maxFailures {
+		run.failures = append(run.failures, reason)
+	}
+}
+
+func (r *testRuns) report(stability Stability) *models.StabilityReport {
+	report := &models.StabilityReport{
+		Runs:        stability.Runs,
+		CPUThrottle: stability.CPUThrottle,
+		Threshold:   stability.Threshold,
+		Stable:      len(r.titles) > 0,
+		Tests:       []models.TestStability{},
+	}
+
+	total, passed := 0, 0
+	for _, title := range r.titles {
+		run := r.byTitle[title]
+		mean, stdDev := meanStdDev(run.durations)
+		test := models.TestStability{
+			Title:    title,
+			Runs:     len(run.durations),
+			Passed:   run.passed,
+			PassRate: float64(run.passed) / float64(len(run.durations)),
+			MeanMs:   mean,
+			StdDevMs: stdDev,
+			Failures: run.failures,
+		}
+		report.Tests = append(report.Tests, test)
+
+		total += test.Runs
+		passed += test.Passed
+		if test.PassRate < stability.Threshold {
+			report.Stable = false
+		}
+	}
+	if total > 0 {
+		report.Score = float64(passed) / float64(total)
+	}
+	return report
+}
+
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)))
+}
@@ -0,0 +1,109 @@
+package gen_eval_loop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const stabilityReport = `{
+  "suites": [{
+    "title": "test-1-cart.spec.ts",
+    "specs": [{
+      "title": "shows the cart",
+      "tests": [{"projectName": "chromium", "results": [
+        {"status": "passed", "duration": 1000},
+        {"status": "passed", "duration": 1200}
+      ]}]
+    }],
+    "suites": [{
+      "title": "checkout",
+      "specs": [{
+        "title": "submits the order",
+        "tests": [{"projectName": "chromium", "results": [
+          {"status": "passed", "duration": 2000},
+          {"status": "failed", "duration": 5000, "errors": [{"message": "\u001b[31mExpected: \"Thank you\"\nReceived: \"Loading\"\u001b[39m"}]}
+        ]}]
+      }]
+    }]
+  }]
+}`
+
+func TestStabilityReport(t *testing.T) {
+	runs := &testRuns{byTitle: map[string]*testRun{}}
+	if err := runs.add([]byte(stabilityReport)); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	report := runs.report(Stability{Runs: 2, Threshold: 1})
+
+	if len(report.Tests) != 2 || report.Stable || report.Score != 0.75 {
+		t.Fatalf("Expected two tests, one flaky, with a score of 0.75, got %+v", report)
+	}
+
+	cart := report.Tests[0]
+	if cart.Title != "shows the cart" || cart.PassRate != 1 || cart.MeanMs != 1100 || cart.StdDevMs != 100 {
+		t.Errorf("Expected the timing of the stable test, got %+v", cart)
+	}
+
+	checkout := report.Tests[1]
+	if checkout.Title != "checkout › submits the order" || checkout.PassRate != 0.5 {
+		t.Errorf("Expected the flaky test with its describe title, got %+v", checkout)
+	}
+	if len(checkout.Failures) != 1 || strings.Contains(checkout.Failures[0], "\x1b") {
+		t.Errorf("Expected the failure without colors, got %q", checkout.Failures)
+	}
+
+	feedback := stabilityFeedback(report)
+	if !strings.Contains(feedback, "checkout › submits the order") || !strings.Contains(feedback, `Received: "Loading"`) || strings.Contains(feedback, "shows the cart") {
+		t.Errorf("Expected feedback on the flaky test only, got:\n%s", feedback)
+	}
+}
+
+func TestStabilityReportProjects(t *testing.T) {
+	report := `{
+	  "suites": [{
+	    "title": "test-1-menu.spec.ts",
+	    "specs": [{
+	      "title": "opens the menu",
+	      "tests": [
+	        {"projectName": "chromium", "results": [{"status": "passed", "duration": 1000}]},
+	        {"projectName": "webkit", "results": [{"status": "passed", "duration": 1000}]},
+	        {"projectName": "iphone", "results": [{"status": "failed", "duration": 1000, "errors": [{"message": "not visible"}]}]}
+	      ]
+	    }]
+	  }]
+	}`
+
+	primary := &testRuns{projects: Matrix{Projects: []string{"chromium", "iphone"}}.required(), byTitle: map[string]*testRun{}}
+	if err := primary.add([]byte(report)); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if stability := primary.report(Stability{Runs: 1, Threshold: 1}); !stability.Stable || len(stability.Tests) != 1 || stability.Tests[0].Title != "opens the menu" {
+		t.Errorf("Expected failures of report-only projects to be left out, got %+v", stability)
+	}
+
+	all := &testRuns{projects: Matrix{Projects: []string{"chromium", "webkit", "iphone"}, RequireAll: true}.required(), byTitle: map[string]*testRun{}}
+	if err := all.add([]byte(report)); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	stability := all.report(Stability{Runs: 1, Threshold: 1})
+	if stability.Stable || len(stability.Tests) != 3 || stability.Tests[2].Title != "[iphone] opens the menu" || stability.Tests[2].PassRate != 0 {
+		t.Errorf("Expected every required project counted on its own, got %+v", stability)
+	}
+}
+
+func TestThrottledCopy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test-1-cart.spec.ts")
+	os.WriteFile(filename, []byte(`import { test, expect } from "@playwright/test";`), 0644)
+
+	throttled, err := throttledCopy(filename)
+	if err != nil {
+		t.Fatalf("throttledCopy failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(throttled)
+	if !strings.HasSuffix(throttled, "test-1-cart.throttled.spec.ts") || string(content) != `import { test, expect } from '../throttle';` {
+		t.Errorf("Expected the import of the throttling fixture, got %s: %s", throttled, content)
+	}
+}
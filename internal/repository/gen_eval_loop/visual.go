@@ -0,0 +1,286 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/promptguard"
+	"github.com/webscopeio/ai-hackathon/internal/runner"
+	"github.com/webscopeio/ai-hackathon/internal/telemetry"
+)
+
+const (
+	// visualSpecFile is the spec of the visual tests, Playwright keeps its baselines in visualSpecFile-snapshots
+	visualSpecFile = "visual.spec.ts"
+	// maxVisualPages and maxVisualComponents keep the baselines few enough to review
+	maxVisualPages      = 5
+	maxVisualComponents = 3
+	// maxSelectorLength rejects selectors that are page content rather than selectors
+	maxSelectorLength = 200
+)
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// GenerateVisualTests writes toHaveScreenshot tests for the key pages of the analysis together with their baselines to outDir
+// An LLM pass picks the pages, their key components and the dynamic regions to mask. The baselines are captured in the sandbox
+// and checked by a second run, tests failing against their own baselines need more masks.
+func GenerateVisualTests(ctx context.Context, client *llm.Client, analyzerReturn *models.AnalyzerReturn, outDir string) (result *models.VisualTestReturn, err error) {
+	ctx, span := telemetry.StartSpan(telemetry.WithPhase(ctx, "visual"), "gen_eval.visual")
+	defer func() { telemetry.EndSpan(span, err) }()
+	log := logger.For(ctx, "visual")
+
+	pages := sitePages(analyzerReturn)
+
+	plan, err := planVisualTests(ctx, client, analyzerReturn, scopeFor(ctx, pages))
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Pages) == 0 {
+		return nil, fmt.Errorf("no pages to compare visually")
+	}
+
+	tempDir, testsDir, err := SetupTestEnvironment(ctx, targetURL(analyzerReturn))
+	if tempDir != "" {
+		defer os.RemoveAll(tempDir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SetupTestEnvironment failed: %w", err)
+	}
+
+	specPath := filepath.Join(testsDir, visualSpecFile)
+	if err := os.WriteFile(specPath, []byte(visualSpec(plan)), 0644); err != nil {
+		return nil, fmt.Errorf("couldn't write the visual tests: %w", err)
+	}
+
+	sandbox := runner.FromContext(ctx)
+	egress := egressFor(ctx, pages)
+
+	log.Info("capturing visual baselines", "pages", len(plan.Pages))
+	baseline, err := sandbox.Run(ctx, runner.Spec{
+		Step:    "visual_baseline",
+		Phase:   runner.PhaseTest,
+		Command: []string{"pnpm", "exec", "playwright", "test", specPath, "--update-snapshots"},
+		Dir:     tempDir,
+		Egress:  egress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't capture the visual baselines: %w", err)
+	}
+	if err := baseline.Err(); err != nil {
+		log.Error("capturing the visual baselines failed", "error", err, "output", string(baseline.Output))
+		return nil, fmt.Errorf("couldn't capture the visual baselines: %w", err)
+	}
+
+	// Regions the masks missed make the tests fail against their own baselines
+	reportPath := filepath.Join(tempDir, reportFile)
+	os.Remove(reportPath)
+	verify, err := sandbox.Run(ctx, runner.Spec{
+		Step:    "visual_verify",
+		Phase:   runner.PhaseTest,
+		Command: []string{"pnpm", "exec", "playwright", "test", specPath},
+		Dir:     tempDir,
+		Egress:  egress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't verify the visual baselines: %w", err)
+	}
+
+	result = &models.VisualTestReturn{Plan: *plan, Stable: verify.Passed()}
+	if data, err := os.ReadFile(reportPath); err == nil {
+		if projects, err := projectResults(data, matrixFrom(ctx)); err == nil {
+			for _, project := range projects {
+				for _, failure := range project.Failures {
+					result.Failures = append(result.Failures, project.Project+": "+failure)
+				}
+			}
+		}
+	}
+	if !result.Stable {
+		log.Warn("visual tests fail against their own baselines", "error", verify.Err(), "failures", len(result.Failures))
+	}
+
+	result.SpecFile, result.Baselines, err = copyVisualTests(testsDir, outDir)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("wrote visual tests", "file", result.SpecFile, "baselines", len(result.Baselines), "stable", result.Stable)
+	return result, nil
+}
+
+// planVisualTests asks the LLM for the key pages, components and dynamic regions of the analyzed pages
+func planVisualTests(ctx context.Context, client *llm.Client, analyzerReturn *models.AnalyzerReturn, scope *promptguard.Scope) (*models.VisualPlan, error) {
+	log := logger.For(ctx, "visual")
+
+	var builder strings.Builder
+	builder.WriteString(promptguard.Instructions)
+	builder.WriteString("\n\nINPUTS: \n")
+	builder.WriteString("TECHNICAL SPECIFICATION: ")
+	builder.WriteString(analyzerReturn.TechSpec)
+	builder.WriteString("\nCONTENT MAP (SEPARATED BY 2 NEWLINES): ")
+	wrapped, flagged := promptguard.WrapAll(analyzerReturn.ContentMap)
+	if len(flagged) > 0 {
+		log.Warn("instruction-like text in the content map", "urls", flagged)
+	}
+	for url, content := range wrapped {
+		builder.WriteString(fmt.Sprintf("%s: %s\n\n", url, content))
+	}
+	builder.WriteString("\n---END PAGE---\n\n")
+
+	prompt := fmt.Sprintf(`You are a test engineer planning visual regression tests with Playwright toHaveScreenshot. From the content map, pick the key pages users see most, at most %d.
+For every page:
+- Pick at most %d key components worth comparing on their own, like the header, the navigation, a hero section or a pricing table, with stable CSS selectors. Prefer ids, data attributes and semantic elements over generated class names.
+- List CSS selectors of every region whose pixels change between visits, so they are masked: timestamps and dates, counters, carousels and sliders, ads, embedded videos and iframes, avatars, random, recommended or personalized content.
+Only use URLs of the content map and selectors of elements in its HTML.`, maxVisualPages, maxVisualComponents)
+
+	tool, toolChoice := llm.GenerateTool[models.VisualPlan]("get_visual_plan", "Plan the pages, components and masked regions of visual regression tests")
+
+	log.Info("calling LLM for the visual test plan")
+	rawResponse, err := client.GetStructuredCompletion(ctx, builder.String(), prompt, tool, toolChoice, []anthropic.MessageParam{})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't process request: %w", err)
+	}
+
+	var plan models.VisualPlan
+	if err := json.Unmarshal(rawResponse, &plan); err != nil {
+		return nil, fmt.Errorf("couldn't unmarshal response: %w", err)
+	}
+	return sanitizeVisualPlan(ctx, &plan, scope), nil
+}
+
+// sanitizeVisualPlan drops pages outside the analyzed site and selectors that can't be selectors,
+// names become unique slugs usable as file names
+func sanitizeVisualPlan(ctx context.Context, plan *models.VisualPlan, scope *promptguard.Scope) *models.VisualPlan {
+	log := logger.For(ctx, "visual")
+
+	validSelector := func(selector string) bool {
+		return strings.TrimSpace(selector) != "" && len(selector) <= maxSelectorLength
+	}
+
+	sanitized := &models.VisualPlan{Pages: []models.VisualPage{}}
+	used := map[string]bool{}
+	for _, page := range plan.Pages {
+		if len(sanitized.Pages) == maxVisualPages {
+			break
+		}
+		if !scope.Contains(page.URL) {
+			log.Warn("dropping visual test of a page outside the website under test", "url", page.URL)
+			continue
+		}
+
+		name := uniqueSlug(page.Name, "page", used)
+		clean := models.VisualPage{URL: page.URL, Name: name, Components: []models.VisualComponent{}, Masks: []string{}}
+		for _, component := range page.Components {
+			if len(clean.Components) < maxVisualComponents && validSelector(component.Selector) {
+				component.Name = uniqueSlug(name+"-"+component.Name, name+"-component", used)
+				clean.Components = append(clean.Components, component)
+			}
+		}
+		for _, mask := range page.Masks {
+			if validSelector(mask) {
+				clean.Masks = append(clean.Masks, mask)
+			}
+		}
+		sanitized.Pages = append(sanitized.Pages, clean)
+	}
+	return sanitized
+}
+
+func uniqueSlug(name, fallback string, used map[string]bool) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		slug = fallback
+	}
+	unique := slug
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", slug, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// visualSpec renders the spec of the plan, every value is a JSON encoded string literal
+func visualSpec(plan *models.VisualPlan) string {
+	quote := func(value string) string {
+		quoted, _ := json.Marshal(value)
+		return string(quoted)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`import { test, expect } from '@playwright/test';
+
+// Visual regression tests, the baselines are in the visual.spec.ts-snapshots directory next to this file
+// Update them after intended changes with: npx playwright test visual.spec.ts --update-snapshots
+
+test.describe('visual', () => {
+`)
+	for _, page := range plan.Pages {
+		fmt.Fprintf(&builder, "  test(%s, async ({ page }) => {\n", quote(page.Name))
+		fmt.Fprintf(&builder, "    await page.goto(%s);\n", quote(page.URL))
+		builder.WriteString("    await page.waitForLoadState('networkidle');\n")
+		builder.WriteString("    const mask = [")
+		for i, selector := range page.Masks {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+			fmt.Fprintf(&builder, "page.locator(%s)", quote(selector))
+		}
+		builder.WriteString("];\n")
+		fmt.Fprintf(&builder, "    await expect(page).toHaveScreenshot(%s, { fullPage: true, mask, animations: 'disabled' });\n", quote(page.Name+".png"))
+		for _, component := range page.Components {
+			fmt.Fprintf(&builder, "    await expect(page.locator(%s).first()).toHaveScreenshot(%s, { mask, animations: 'disabled' });\n", quote(component.Selector), quote(component.Name+".png"))
+		}
+		builder.WriteString("  });\n\n")
+	}
+	builder.WriteString("});\n")
+	return builder.String()
+}
+
+// copyVisualTests copies the spec and its baselines to outDir, the baselines are returned relative to outDir
+func copyVisualTests(testsDir, outDir string) (string, []string, error) {
+	specFile := filepath.Join(outDir, visualSpecFile)
+	if err := copyFile(filepath.Join(testsDir, visualSpecFile), specFile); err != nil {
+		return "", nil, err
+	}
+
+	snapshotsDir := visualSpecFile + "-snapshots"
+	entries, err := os.ReadDir(filepath.Join(testsDir, snapshotsDir))
+	if err != nil {
+		return specFile, nil, fmt.Errorf("couldn't read the visual baselines: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(outDir, snapshotsDir), 0755); err != nil {
+		return specFile, nil, fmt.Errorf("couldn't create the baselines directory: %w", err)
+	}
+
+	var baselines []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		baseline := filepath.Join(snapshotsDir, entry.Name())
+		if err := copyFile(filepath.Join(testsDir, baseline), filepath.Join(outDir, baseline)); err != nil {
+			return specFile, baselines, err
+		}
+		baselines = append(baselines, baseline)
+	}
+	return specFile, baselines, nil
+}
+
+func copyFile(src, dst string) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("couldn't read %s: %w", filepath.Base(src), err)
+	}
+	if err := os.WriteFile(dst, content, 0644); err != nil {
+		return fmt.Errorf("couldn't write %s: %w", filepath.Base(dst), err)
+	}
+	return nil
+}
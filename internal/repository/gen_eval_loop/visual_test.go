@@ -0,0 +1,78 @@
+package gen_eval_loop
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/promptguard"
+)
+
+func TestSanitizeVisualPlan(t *testing.T) {
+	scope := promptguard.NewScope([]string{"https://shop.example.com/", "https://shop.example.com/cart"}, nil)
+	plan := &models.VisualPlan{Pages: []models.VisualPage{
+		{URL: "https://shop.example.com/", Name: "Home Page", Components: []models.VisualComponent{
+			{Name: "Header", Selector: "header"},
+			{Name: "Empty", Selector: " "},
+			{Name: "Header", Selector: "#hero"},
+		}, Masks: []string{".timestamp", strings.Repeat("x", maxSelectorLength+1)}},
+		{URL: "https://evil.example.org/", Name: "elsewhere"},
+		{URL: "https://shop.example.com/cart", Name: "home page!"},
+	}}
+
+	sanitized := sanitizeVisualPlan(context.Background(), plan, scope)
+	if len(sanitized.Pages) != 2 {
+		t.Fatalf("Expected the page outside the website to be dropped, got %+v", sanitized.Pages)
+	}
+	home, cart := sanitized.Pages[0], sanitized.Pages[1]
+	if home.Name != "home-page" || cart.Name != "home-page-2" {
+		t.Errorf("Expected unique slugs, got %q and %q", home.Name, cart.Name)
+	}
+	if len(home.Components) != 2 || home.Components[0].Name != "home-page-header" || home.Components[1].Name != "home-page-header-2" {
+		t.Errorf("Expected the empty selector dropped and unique component names, got %+v", home.Components)
+	}
+	if len(home.Masks) != 1 || home.Masks[0] != ".timestamp" {
+		t.Errorf("Expected the overlong mask dropped, got %v", home.Masks)
+	}
+}
+
+func TestVisualSpec(t *testing.T) {
+	spec := visualSpec(&models.VisualPlan{Pages: []models.VisualPage{{
+		URL:        "https://shop.example.com/",
+		Name:       "home",
+		Components: []models.VisualComponent{{Name: "home-nav", Selector: `nav[aria-label="Main"]`}},
+		Masks:      []string{".ad", "time"},
+	}}})
+
+	for _, expected := range []string{
+		`await page.goto("https://shop.example.com/");`,
+		`const mask = [page.locator(".ad"), page.locator("time")];`,
+		`await expect(page).toHaveScreenshot("home.png", { fullPage: true, mask, animations: 'disabled' });`,
+		`await expect(page.locator("nav[aria-label=\"Main\"]").first()).toHaveScreenshot("home-nav.png"`,
+	} {
+		if !strings.Contains(spec, expected) {
+			t.Errorf("Expected the spec to contain %s, got:\n%s", expected, spec)
+		}
+	}
+}
+
+func TestCopyVisualTests(t *testing.T) {
+	testsDir, outDir := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(testsDir, visualSpecFile), []byte("spec"), 0644)
+	os.MkdirAll(filepath.Join(testsDir, visualSpecFile+"-snapshots"), 0755)
+	os.WriteFile(filepath.Join(testsDir, visualSpecFile+"-snapshots", "home-chromium-linux.png"), []byte("png"), 0644)
+
+	specFile, baselines, err := copyVisualTests(testsDir, outDir)
+	if err != nil {
+		t.Fatalf("copyVisualTests failed: %v", err)
+	}
+	if specFile != filepath.Join(outDir, visualSpecFile) || len(baselines) != 1 {
+		t.Fatalf("Expected the spec and one baseline, got %s and %v", specFile, baselines)
+	}
+	if content, err := os.ReadFile(filepath.Join(outDir, baselines[0])); err != nil || string(content) != "png" {
+		t.Errorf("Expected the baseline next to the spec, got %q and %v", content, err)
+	}
+}
@@ -0,0 +1,262 @@
+package reproducer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/promptguard"
+	"github.com/webscopeio/ai-hackathon/internal/repository/analyzer"
+	"github.com/webscopeio/ai-hackathon/internal/repository/gen_eval_loop"
+	"github.com/webscopeio/ai-hackathon/internal/sentry"
+)
+
+const (
+	// maxSteps is the number of most recent breadcrumbs turned into test steps
+	maxSteps = 20
+	// maxFrames is the number of stack frames included in the criterion
+	maxFrames = 5
+	// maxPages is the number of visited pages whose content is fetched for the generator
+	maxPages = 5
+)
+
+// Reproduce generates a Playwright regression test for a Sentry issue of the website at baseURL
+// The returned filename points to the generated test file
+func Reproduce(ctx context.Context, cfg *config.Config, client *llm.Client, baseURL, orgSlug, issueID string, noOfLoops int) (*models.GeneratedTestReturn, error) {
+	sentryClient := sentry.New(cfg)
+
+	issue, err := sentryClient.Issue(ctx, orgSlug, issueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Sentry issue: %w", err)
+	}
+
+	event, err := sentryClient.LatestEvent(ctx, orgSlug, issueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest event: %w", err)
+	}
+
+	log := logger.For(ctx, "reproducer")
+	criterion, pages := BuildCriterion(issue, event)
+	log.Debug("built criterion", "issue", issue.ShortID, "criterion", criterion.String())
+
+	// Anyone with the public DSN can send events, so their URLs are only fetched on the website under test
+	pages, dropped := PagesInScope(pages, promptguard.NewScope([]string{baseURL}, cfg.AllowedDomains))
+	if len(dropped) > 0 {
+		log.Warn("skipping event pages outside the website under test", "pages", dropped)
+	}
+
+	contentMap := map[string]string{}
+	if len(pages) > 0 {
+		result, err := analyzer.GetContent(ctx, pages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get content: %w", err)
+		}
+		contentMap = result.Contents
+	}
+
+	// Numeric issue IDs keep the generated file names unique per issue
+	index, err := strconv.Atoi(issue.ID)
+	if err != nil {
+		index = 1
+	}
+
+	techSpec := fmt.Sprintf("Regression test for the Sentry issue %s (%s) seen %s times by %d users. The test must reproduce the user actions that led to the error.",
+		issue.ShortID, issue.Title, issue.Count, issue.UserCount)
+
+	result, err := gen_eval_loop.GenEvalLoop(ctx, client, &models.AnalyzerReturn{
+		TechSpec:   techSpec,
+		ContentMap: contentMap,
+		Criteria:   criterion.String(),
+		BaseURL:    baseURL,
+	}, index, noOfLoops)
+	if err != nil {
+		return nil, err
+	}
+
+	test, err := os.ReadFile(result.Filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated test: %w", err)
+	}
+
+	return &models.GeneratedTestReturn{
+		TechSpec:  techSpec,
+		Criterion: criterion,
+		Pages:     pages,
+		Filename:  result.Filename,
+		Test:      string(test),
+		Result:    result,
+	}, nil
+}
+
+// BuildCriterion turns the latest event of an issue into a test criterion
+// It also returns the pages visited in the event, starting with the page of the error
+func BuildCriterion(issue *models.SentryIssue, event *models.SentryEvent) (models.TestCriterion, []string) {
+	pageURL := sentry.EventTag(event, "url")
+	base, _ := url.Parse(pageURL)
+
+	breadcrumbs := sentry.Breadcrumbs(event)
+	if len(breadcrumbs) > maxSteps {
+		breadcrumbs = breadcrumbs[len(breadcrumbs)-maxSteps:]
+	}
+
+	steps := []string{}
+	visited := []string{}
+	for _, crumb := range breadcrumbs {
+		step := describeBreadcrumb(base, crumb)
+		if step == "" {
+			continue
+		}
+		steps = append(steps, fmt.Sprintf("%d) %s", len(steps)+1, step))
+		if crumb.Category == "navigation" {
+			visited = append(visited, resolve(base, stringData(crumb, "to")))
+		}
+	}
+
+	// The page where the error happened goes first, followed by the visited pages
+	pages := []string{}
+	seen := map[string]bool{}
+	for _, page := range append([]string{pageURL}, visited...) {
+		if page == "" || seen[page] || len(pages) >= maxPages {
+			continue
+		}
+		seen[page] = true
+		pages = append(pages, page)
+	}
+
+	if len(steps) == 0 && pageURL != "" {
+		steps = append(steps, fmt.Sprintf("1) Open %s", pageURL))
+	}
+
+	var scenario strings.Builder
+	scenario.WriteString(fmt.Sprintf("Reproduce the Sentry error %q", issue.Title))
+	if pageURL != "" {
+		scenario.WriteString(fmt.Sprintf(" that happens on %s", pageURL))
+	}
+	scenario.WriteString(". Follow the recorded user actions: ")
+	scenario.WriteString(strings.Join(steps, "; "))
+	scenario.WriteString(".")
+
+	if trace := describeStackTrace(event); trace != "" {
+		scenario.WriteString(" The error was thrown at: ")
+		scenario.WriteString(trace)
+		scenario.WriteString(".")
+	}
+
+	errorMessage := issue.Metadata.Value
+	if errorMessage == "" {
+		errorMessage = issue.Title
+	}
+
+	expected := fmt.Sprintf("The page produces no console error and no uncaught page error whose message contains %q. "+
+		"Collect messages from page.on('console') with type 'error' and from page.on('pageerror') during the whole test, "+
+		"then assert that none of them contain that text.", errorMessage)
+
+	return models.TestCriterion{
+		Title:    fmt.Sprintf("Regression %s: %s", issue.ShortID, issue.Title),
+		Scenario: scenario.String(),
+		Expected: expected,
+	}, pages
+}
+
+// PagesInScope splits pages into those on a host of the scope and the dropped rest
+func PagesInScope(pages []string, scope *promptguard.Scope) ([]string, []string) {
+	var kept, dropped []string
+	for _, page := range pages {
+		if scope.Contains(page) {
+			kept = append(kept, page)
+		} else {
+			dropped = append(dropped, page)
+		}
+	}
+	return kept, dropped
+}
+
+// describeBreadcrumb converts a breadcrumb into a user action, or an empty string for noise
+func describeBreadcrumb(base *url.URL, crumb models.SentryBreadcrumb) string {
+	switch {
+	case crumb.Category == "navigation":
+		to := resolve(base, stringData(crumb, "to"))
+		if to == "" {
+			return ""
+		}
+		return fmt.Sprintf("Navigate to %s", to)
+	case crumb.Category == "ui.click":
+		return fmt.Sprintf("Click the element %s", crumb.Message)
+	case crumb.Category == "ui.input":
+		return fmt.Sprintf("Type into the element %s", crumb.Message)
+	case crumb.Category == "xhr" || crumb.Category == "fetch":
+		method := stringData(crumb, "method")
+		requestURL := stringData(crumb, "url")
+		if requestURL == "" {
+			return ""
+		}
+		status := stringData(crumb, "status_code")
+		if status != "" {
+			return fmt.Sprintf("The page sends %s %s (responded %s)", method, requestURL, status)
+		}
+		return fmt.Sprintf("The page sends %s %s", method, requestURL)
+	}
+	return ""
+}
+
+// describeStackTrace returns the innermost in-app frames of the raised exception
+func describeStackTrace(event *models.SentryEvent) string {
+	exceptions := sentry.Exceptions(event)
+	if len(exceptions) == 0 {
+		return ""
+	}
+
+	// Chained exceptions are listed with the raised one last
+	exception := exceptions[len(exceptions)-1]
+	frames := []string{}
+	// Sentry orders frames from the outermost to the innermost call
+	for i := len(exception.Frames) - 1; i >= 0 && len(frames) < maxFrames; i-- {
+		frame := exception.Frames[i]
+		if !frame.InApp {
+			continue
+		}
+		frames = append(frames, fmt.Sprintf("%s (%s:%d:%d)", frame.Function, frame.Filename, frame.LineNo, frame.ColNo))
+	}
+
+	trace := fmt.Sprintf("%s: %s", exception.Type, exception.Value)
+	if len(frames) > 0 {
+		trace += " in " + strings.Join(frames, " <- ")
+	}
+	return trace
+}
+
+// resolve resolves a possibly relative path against the page of the error
+func resolve(base *url.URL, target string) string {
+	if target == "" {
+		return ""
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	if base == nil || base.Host == "" {
+		if parsed.IsAbs() {
+			return parsed.String()
+		}
+		return ""
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+func stringData(crumb models.SentryBreadcrumb, key string) string {
+	value, ok := crumb.Data[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
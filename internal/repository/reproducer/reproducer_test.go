@@ -0,0 +1,72 @@
+package reproducer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/promptguard"
+)
+
+func TestBuildCriterion(t *testing.T) {
+	issue := &models.SentryIssue{
+		ID:       "42",
+		ShortID:  "DEMO-1",
+		Title:    "TypeError: Cannot read properties of undefined (reading 'price')",
+		Metadata: models.Metadata{Value: "Cannot read properties of undefined (reading 'price')"},
+	}
+	event := &models.SentryEvent{
+		Tags: []models.SentryEventTag{{Key: "url", Value: "https://example.com/checkout"}},
+		Entries: []map[string]interface{}{
+			{"type": "breadcrumbs", "data": map[string]interface{}{"values": []interface{}{
+				map[string]interface{}{"category": "navigation", "data": map[string]interface{}{"from": "/", "to": "/pricing"}},
+				map[string]interface{}{"category": "ui.click", "message": "button.buy"},
+				map[string]interface{}{"category": "fetch", "data": map[string]interface{}{"method": "POST", "url": "/api/cart", "status_code": float64(500)}},
+				map[string]interface{}{"category": "console", "message": "noise"},
+			}}},
+			{"type": "exception", "data": map[string]interface{}{"values": []interface{}{
+				map[string]interface{}{"type": "TypeError", "value": "Cannot read properties of undefined", "stacktrace": map[string]interface{}{"frames": []interface{}{
+					map[string]interface{}{"filename": "node_modules/react.js", "function": "render", "inApp": false},
+					map[string]interface{}{"filename": "app/checkout.js", "function": "total", "lineNo": float64(7), "colNo": float64(3), "inApp": true},
+				}}},
+			}}},
+		},
+	}
+
+	criterion, pages := BuildCriterion(issue, event)
+
+	if len(pages) != 2 || pages[0] != "https://example.com/checkout" || pages[1] != "https://example.com/pricing" {
+		t.Errorf("Expected the error page followed by the visited pages, got %v", pages)
+	}
+
+	for _, want := range []string{
+		"1) Navigate to https://example.com/pricing",
+		"2) Click the element button.buy",
+		"3) The page sends POST /api/cart (responded 500)",
+		"total (app/checkout.js:7:3)",
+	} {
+		if !strings.Contains(criterion.Scenario, want) {
+			t.Errorf("Expected scenario to contain %q, got %q", want, criterion.Scenario)
+		}
+	}
+	if strings.Contains(criterion.Scenario, "noise") || strings.Contains(criterion.Scenario, "react.js") {
+		t.Errorf("Expected console breadcrumbs and library frames to be skipped, got %q", criterion.Scenario)
+	}
+	if !strings.Contains(criterion.Expected, issue.Metadata.Value) {
+		t.Errorf("Expected assertion on the issue message, got %q", criterion.Expected)
+	}
+}
+
+func TestPagesInScope(t *testing.T) {
+	scope := promptguard.NewScope([]string{"https://example.com/"}, []string{"auth.example.org"})
+	kept, dropped := PagesInScope([]string{
+		"https://example.com/checkout",
+		"https://www.example.com/pricing",
+		"https://auth.example.org/login",
+		"http://169.254.169.254/latest/meta-data",
+		"https://attacker.example.net/",
+	}, scope)
+	if len(kept) != 3 || len(dropped) != 2 {
+		t.Errorf("Expected the pages of the website and the project domains to be kept, got %v and dropped %v", kept, dropped)
+	}
+}
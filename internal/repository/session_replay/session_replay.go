@@ -0,0 +1,188 @@
+package session_replay
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/analytics"
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/repository/analyzer"
+	"github.com/webscopeio/ai-hackathon/internal/repository/gen_eval_loop"
+	"github.com/webscopeio/ai-hackathon/internal/umami"
+)
+
+const (
+	// maxSteps is the number of first session steps turned into test steps
+	maxSteps = 20
+	// maxPages is the number of visited pages whose content is fetched for the generator
+	maxPages = 5
+	// defaultWindow is how far back activity is fetched when the session start is unknown
+	defaultWindow = 30 * 24 * time.Hour
+)
+
+// Replay generates a Playwright test that follows the path of a recorded Umami session
+// The returned filename points to the generated test file
+func Replay(ctx context.Context, cfg *config.Config, client *llm.Client, baseURL, sessionID string, noOfLoops int) (*models.GeneratedTestReturn, error) {
+	umamiClient := umami.New(cfg)
+
+	session, err := umamiClient.Session(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Umami session: %w", err)
+	}
+
+	endDate := time.Now()
+	startDate := endDate.Add(-defaultWindow)
+	if createdAt, err := time.Parse(time.RFC3339, session.CreatedAt); err == nil {
+		startDate = createdAt
+	}
+
+	activities, err := umamiClient.SessionActivity(ctx, *session, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session activity: %w", err)
+	}
+	if len(activities) == 0 {
+		return nil, fmt.Errorf("session %s has no recorded activity", sessionID)
+	}
+
+	criterion, pages := BuildCriterion(analytics.ConvertUmamiActivities(sessionID, activities), baseURL)
+	logger.For(ctx, "session_replay").Debug("built criterion", "session_id", sessionID, "criterion", criterion.String())
+
+	contentMap := map[string]string{}
+	if len(pages) > 0 {
+		result, err := analyzer.GetContent(ctx, pages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get content: %w", err)
+		}
+		contentMap = result.Contents
+	}
+
+	techSpec := fmt.Sprintf("End-to-end test replaying the path of a real visitor (Umami session %s, %s on %s). The test must follow the same pages and actions in the same order.",
+		sessionID, session.BrowserName, session.Device)
+
+	// Session IDs are UUIDs, the checksum keeps the generated file names unique per session
+	index := int(crc32.ChecksumIEEE([]byte(sessionID)) % 1000000)
+
+	result, err := gen_eval_loop.GenEvalLoop(ctx, client, &models.AnalyzerReturn{
+		TechSpec:   techSpec,
+		ContentMap: contentMap,
+		Criteria:   criterion.String(),
+		BaseURL:    baseURL,
+	}, index, noOfLoops)
+	if err != nil {
+		return nil, err
+	}
+
+	test, err := os.ReadFile(result.Filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated test: %w", err)
+	}
+
+	return &models.GeneratedTestReturn{
+		TechSpec:  techSpec,
+		Criterion: criterion,
+		Pages:     pages,
+		Filename:  result.Filename,
+		Test:      string(test),
+		Result:    result,
+	}, nil
+}
+
+// BuildCriterion turns the steps of a session into a test criterion
+// It also returns the distinct pages visited in the session, in order
+func BuildCriterion(session models.AnalyticsSession, baseURL string) (models.TestCriterion, []string) {
+	base, _ := url.Parse(baseURL)
+
+	sessionSteps := session.Steps
+	if len(sessionSteps) > maxSteps {
+		sessionSteps = sessionSteps[:maxSteps]
+	}
+
+	steps := []string{}
+	pages := []string{}
+	seen := map[string]bool{}
+	lastPage := ""
+	var lastTime time.Time
+
+	for _, step := range sessionSteps {
+		page := resolve(base, step.Path)
+		if page == "" {
+			continue
+		}
+
+		var action string
+		switch {
+		case step.Event != "":
+			if page != lastPage && lastPage != "" {
+				steps = append(steps, fmt.Sprintf("%d) Go to %s", len(steps)+1, page))
+			}
+			action = fmt.Sprintf("Trigger the %q action, tracked by the element with data-umami-event=%q if present", step.Event, step.Event)
+		case page == lastPage:
+			// Reloads and duplicate page views are not separate user actions
+			continue
+		case lastPage == "":
+			action = fmt.Sprintf("Open %s", page)
+		default:
+			action = fmt.Sprintf("Go to %s, using the link or button a visitor would use where possible", page)
+		}
+
+		if !lastTime.IsZero() && !step.Timestamp.IsZero() {
+			if gap := step.Timestamp.Sub(lastTime).Round(time.Second); gap >= time.Second {
+				action += fmt.Sprintf(" (%s after the previous step)", gap)
+			}
+		}
+
+		steps = append(steps, fmt.Sprintf("%d) %s", len(steps)+1, action))
+		lastPage = page
+		lastTime = step.Timestamp
+
+		if !seen[page] && len(pages) < maxPages {
+			seen[page] = true
+			pages = append(pages, page)
+		}
+	}
+
+	var scenario strings.Builder
+	scenario.WriteString("Replay the path of a real visitor through the site: ")
+	scenario.WriteString(strings.Join(steps, "; "))
+	scenario.WriteString(". The recorded timing shows how long the visitor stayed on each step, do not add fixed waits for it.")
+
+	expected := "Every page in the path loads with a successful response and visible main content, " +
+		"every action can be performed on the page it happened on, and no uncaught page error occurs during the test."
+
+	title := "Visitor path"
+	if len(pages) > 0 {
+		title = fmt.Sprintf("Visitor path from %s", pages[0])
+	}
+
+	return models.TestCriterion{
+		Title:    title,
+		Scenario: scenario.String(),
+		Expected: expected,
+	}, pages
+}
+
+// resolve resolves a session path against the base URL of the site
+func resolve(base *url.URL, path string) string {
+	if path == "" {
+		return ""
+	}
+	parsed, err := url.Parse(path)
+	if err != nil {
+		return ""
+	}
+	if base == nil || base.Host == "" {
+		if parsed.IsAbs() {
+			return parsed.String()
+		}
+		return ""
+	}
+	return base.ResolveReference(parsed).String()
+}
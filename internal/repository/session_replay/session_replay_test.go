@@ -0,0 +1,48 @@
+package session_replay
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestBuildCriterion(t *testing.T) {
+	at := func(second int) time.Time {
+		return time.Date(2025, 4, 1, 10, 0, second, 0, time.UTC)
+	}
+	session := models.AnalyticsSession{
+		ID: "session",
+		Steps: []models.AnalyticsStep{
+			{Timestamp: at(0), Path: "/"},
+			{Timestamp: at(2), Path: "/"},
+			{Timestamp: at(12), Path: "/pricing"},
+			{Timestamp: at(20), Path: "/pricing", Event: "signup-click"},
+			{Timestamp: at(25), Path: "/signup"},
+		},
+	}
+
+	criterion, pages := BuildCriterion(session, "https://example.com/app/")
+
+	expectedPages := []string{"https://example.com/", "https://example.com/pricing", "https://example.com/signup"}
+	if !reflect.DeepEqual(pages, expectedPages) {
+		t.Errorf("Expected %v, got %v", expectedPages, pages)
+	}
+
+	for _, want := range []string{
+		"1) Open https://example.com/;",
+		"2) Go to https://example.com/pricing, using the link or button a visitor would use where possible (12s after the previous step)",
+		`3) Trigger the "signup-click" action, tracked by the element with data-umami-event="signup-click" if present (8s after the previous step)`,
+		"4) Go to https://example.com/signup",
+	} {
+		if !strings.Contains(criterion.Scenario, want) {
+			t.Errorf("Expected scenario to contain %q, got %q", want, criterion.Scenario)
+		}
+	}
+
+	if strings.Contains(criterion.Scenario, "5)") {
+		t.Errorf("Expected the reload to be collapsed, got %q", criterion.Scenario)
+	}
+}
@@ -0,0 +1,337 @@
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/repository/analyzer"
+)
+
+// ErrNotFound is returned when no snapshot exists at the given path
+var ErrNotFound = errors.New("snapshot not found")
+
+// New creates an empty snapshot for a website
+func New(baseURL string, techSpec string) *models.SiteSnapshot {
+	now := time.Now()
+	return &models.SiteSnapshot{
+		BaseURL:   baseURL,
+		TechSpec:  techSpec,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Pages:     make(map[string]models.SnapshotPage),
+		Criteria:  []models.TestCriterion{},
+		Tests:     []models.SnapshotTest{},
+	}
+}
+
+// Load reads a snapshot from a JSON file
+func Load(path string) (*models.SiteSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+
+	var snap models.SiteSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot file: %w", err)
+	}
+	if snap.Pages == nil {
+		snap.Pages = make(map[string]models.SnapshotPage)
+	}
+
+	return &snap, nil
+}
+
+// Save writes a snapshot to a JSON file, creating the parent directory if needed
+func Save(path string, snap *models.SiteSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+
+	return nil
+}
+
+// HashContent returns a stable hash of the page content
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(content)))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordPages stores the content hashes of the fetched pages
+// The sitemap is optional and only used for the lastmod values
+func RecordPages(snap *models.SiteSnapshot, sitemap *models.Sitemap, contents map[string]string) {
+	lastMods := sitemapLastMods(sitemap)
+	now := time.Now()
+
+	// Sitemap pages that were not analyzed are only tracked by their lastmod
+	for pageURL, lastMod := range lastMods {
+		if _, ok := snap.Pages[pageURL]; !ok {
+			snap.Pages[pageURL] = models.SnapshotPage{URL: pageURL, LastMod: lastMod}
+		}
+	}
+
+	for pageURL, content := range contents {
+		// Pages that failed to load are not recorded so they get fetched again next time
+		if content == "" {
+			continue
+		}
+		snap.Pages[pageURL] = models.SnapshotPage{
+			URL:       pageURL,
+			LastMod:   lastMods[pageURL],
+			Hash:      HashContent(content),
+			FetchedAt: now,
+		}
+	}
+	snap.UpdatedAt = now
+}
+
+// RecordTest links a generated test file to the snapshot pages it navigates to
+func RecordTest(snap *models.SiteSnapshot, file string, criterion models.TestCriterion, content string) {
+	test := models.SnapshotTest{
+		File:      file,
+		Criterion: criterion,
+		Pages:     CoveredPages(snap, content),
+	}
+
+	for i, t := range snap.Tests {
+		if t.File == file {
+			snap.Tests[i] = test
+			return
+		}
+	}
+	snap.Tests = append(snap.Tests, test)
+}
+
+// MergeCriteria replaces existing criteria with the same title and appends the new ones
+func MergeCriteria(snap *models.SiteSnapshot, criteria []models.TestCriterion) {
+	for _, c := range criteria {
+		replaced := false
+		for i, e := range snap.Criteria {
+			if c.Title != "" && e.Title == c.Title {
+				snap.Criteria[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			snap.Criteria = append(snap.Criteria, c)
+		}
+	}
+}
+
+var gotoRegex = regexp.MustCompile("goto\\(\\s*['\"`]([^'\"`]+)['\"`]")
+
+// CoveredPages returns the snapshot pages a test navigates to via page.goto
+// If no navigation can be matched, the test is assumed to cover the base URL
+func CoveredPages(snap *models.SiteSnapshot, content string) []string {
+	base, err := url.Parse(snap.BaseURL)
+	if err != nil {
+		return []string{}
+	}
+
+	paths := make(map[string]string, len(snap.Pages))
+	for pageURL := range snap.Pages {
+		if parsed, err := url.Parse(pageURL); err == nil {
+			paths[normalizePath(parsed.Path)] = pageURL
+		}
+	}
+
+	seen := make(map[string]bool)
+	var pages []string
+	for _, match := range gotoRegex.FindAllStringSubmatch(content, -1) {
+		target, err := base.Parse(match[1])
+		if err != nil {
+			continue
+		}
+		if pageURL, ok := paths[normalizePath(target.Path)]; ok && !seen[pageURL] {
+			seen[pageURL] = true
+			pages = append(pages, pageURL)
+		}
+	}
+
+	if len(pages) == 0 {
+		if pageURL, ok := paths[normalizePath(base.Path)]; ok {
+			pages = append(pages, pageURL)
+		}
+	}
+
+	sort.Strings(pages)
+	return pages
+}
+
+// Update diffs the current website against the snapshot
+// Only pages with a new or missing sitemap lastmod are fetched again, and only
+// criteria for added or changed pages are proposed. Tests covering changed or
+// removed pages are marked as stale. The snapshot is updated in place.
+func Update(ctx context.Context, client *llm.Client, snap *models.SiteSnapshot) (*models.SnapshotUpdate, error) {
+	update := &models.SnapshotUpdate{
+		Added:       []string{},
+		Changed:     []string{},
+		Removed:     []string{},
+		Unchanged:   []string{},
+		Contents:    make(map[string]string),
+		NewCriteria: []models.TestCriterion{},
+		StaleTests:  []models.SnapshotTest{},
+	}
+
+	// Without a sitemap every known page has to be fetched again
+	sitemap, err := analyzer.GetSitemap(ctx, snap.BaseURL)
+	if err != nil {
+		logger.For(ctx, "snapshot").Debug("no sitemap, re-checking all known pages", "url", snap.BaseURL, "error", err)
+		sitemap = nil
+	}
+
+	candidates := sitemapLastMods(sitemap)
+	if sitemap == nil {
+		for pageURL, page := range snap.Pages {
+			candidates[pageURL] = page.LastMod
+		}
+	}
+
+	toFetch := []string{}
+	for pageURL, lastMod := range candidates {
+		page, known := snap.Pages[pageURL]
+		if known && lastMod != "" && page.LastMod == lastMod {
+			update.Unchanged = append(update.Unchanged, pageURL)
+			continue
+		}
+		toFetch = append(toFetch, pageURL)
+	}
+
+	if sitemap != nil {
+		for pageURL := range snap.Pages {
+			if _, ok := candidates[pageURL]; !ok {
+				update.Removed = append(update.Removed, pageURL)
+			}
+		}
+	}
+
+	logger.For(ctx, "snapshot").Debug("fetching pages for the update", "fetching", len(toFetch), "unchanged_by_lastmod", len(update.Unchanged))
+
+	if len(toFetch) > 0 {
+		result, err := analyzer.GetContent(ctx, toFetch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get content: %w", err)
+		}
+
+		classifyPages(snap, result.Contents, update)
+		RecordPages(snap, sitemap, result.Contents)
+	} else {
+		RecordPages(snap, sitemap, nil)
+	}
+
+	for _, pageURL := range update.Removed {
+		delete(snap.Pages, pageURL)
+	}
+
+	sort.Strings(update.Added)
+	sort.Strings(update.Changed)
+	sort.Strings(update.Removed)
+	sort.Strings(update.Unchanged)
+
+	update.StaleTests = markStale(snap, update.Changed, update.Removed)
+
+	criteria, err := analyzer.ProposeCriteria(ctx, client, snap.TechSpec, snap.Criteria, update.Contents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to propose criteria: %w", err)
+	}
+	update.NewCriteria = criteria
+	MergeCriteria(snap, criteria)
+
+	snap.UpdatedAt = time.Now()
+	return update, nil
+}
+
+// markStale flags the tests that cover changed or removed pages
+func markStale(snap *models.SiteSnapshot, changed []string, removed []string) []models.SnapshotTest {
+	reasons := make(map[string]string, len(changed)+len(removed))
+	for _, pageURL := range changed {
+		reasons[pageURL] = "page changed"
+	}
+	for _, pageURL := range removed {
+		reasons[pageURL] = "page removed"
+	}
+
+	stale := []models.SnapshotTest{}
+	for i, test := range snap.Tests {
+		for _, pageURL := range test.Pages {
+			reason, ok := reasons[pageURL]
+			if !ok {
+				continue
+			}
+			snap.Tests[i].Stale = true
+			snap.Tests[i].StaleReason = fmt.Sprintf("%s: %s", reason, pageURL)
+			stale = append(stale, snap.Tests[i])
+			break
+		}
+	}
+
+	return stale
+}
+
+// classifyPages sorts the fetched pages into added, changed and unchanged ones by their content hash
+// Pages known only from the sitemap have no hash, their content is new to the snapshot
+func classifyPages(snap *models.SiteSnapshot, contents map[string]string, update *models.SnapshotUpdate) {
+	for pageURL, content := range contents {
+		if content == "" {
+			continue
+		}
+		page, known := snap.Pages[pageURL]
+		switch {
+		case !known || page.Hash == "":
+			update.Added = append(update.Added, pageURL)
+			update.Contents[pageURL] = content
+		case page.Hash != HashContent(content):
+			update.Changed = append(update.Changed, pageURL)
+			update.Contents[pageURL] = content
+		default:
+			update.Unchanged = append(update.Unchanged, pageURL)
+		}
+	}
+}
+
+// sitemapLastMods maps the sitemap URLs to their lastmod values
+func sitemapLastMods(sitemap *models.Sitemap) map[string]string {
+	lastMods := make(map[string]string)
+	if sitemap == nil {
+		return lastMods
+	}
+	for _, u := range sitemap.URLs {
+		lastMods[u.Loc] = u.LastMod
+	}
+	return lastMods
+}
+
+func normalizePath(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
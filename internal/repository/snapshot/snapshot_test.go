@@ -0,0 +1,112 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestCoveredPagesAndStale(t *testing.T) {
+	snap := New("https://example.com/", "")
+	RecordPages(snap, &models.Sitemap{
+		URLs: []models.URL{
+			{Loc: "https://example.com/", LastMod: "2025-04-01"},
+			{Loc: "https://example.com/about", LastMod: "2025-04-01"},
+			{Loc: "https://example.com/contact", LastMod: "2025-04-01"},
+		},
+	}, map[string]string{
+		"https://example.com/":      "<main>Home</main>",
+		"https://example.com/about": "<main>About</main>",
+	})
+
+	if len(snap.Pages) != 3 {
+		t.Fatalf("Expected 3 pages in snapshot, got %d", len(snap.Pages))
+	}
+	if snap.Pages["https://example.com/contact"].Hash != "" {
+		t.Errorf("Expected page without content to have no hash")
+	}
+
+	RecordTest(snap, "about.spec.ts", models.TestCriterion{Title: "About"}, "await page.goto('/about');")
+	RecordTest(snap, "home.spec.ts", models.TestCriterion{Title: "Home"}, "await expect(page).toHaveTitle(/Home/);")
+
+	if got := snap.Tests[0].Pages; len(got) != 1 || got[0] != "https://example.com/about" {
+		t.Errorf("Expected about test to cover /about, got %v", got)
+	}
+	if got := snap.Tests[1].Pages; len(got) != 1 || got[0] != "https://example.com/" {
+		t.Errorf("Expected test without navigation to cover the base URL, got %v", got)
+	}
+
+	stale := markStale(snap, []string{"https://example.com/about"}, nil)
+	if len(stale) != 1 || stale[0].File != "about.spec.ts" {
+		t.Fatalf("Expected about.spec.ts to be stale, got %v", stale)
+	}
+	if snap.Tests[1].Stale {
+		t.Errorf("Expected home.spec.ts not to be stale")
+	}
+}
+
+func TestClassifyPages(t *testing.T) {
+	snap := New("https://example.com/", "")
+	RecordPages(snap, &models.Sitemap{
+		URLs: []models.URL{
+			{Loc: "https://example.com/", LastMod: "2025-04-01"},
+			{Loc: "https://example.com/contact", LastMod: "2025-04-01"},
+		},
+	}, map[string]string{
+		"https://example.com/": "<main>Home</main>",
+	})
+
+	update := &models.SnapshotUpdate{Contents: map[string]string{}}
+	classifyPages(snap, map[string]string{
+		"https://example.com/":        "<main>Home, new</main>",
+		"https://example.com/contact": "<main>Contact</main>",
+		"https://example.com/pricing": "<main>Pricing</main>",
+	}, update)
+
+	if len(update.Changed) != 1 || update.Changed[0] != "https://example.com/" {
+		t.Errorf("Expected the home page to be changed, got %v", update.Changed)
+	}
+	if len(update.Added) != 2 || len(update.Contents) != 3 {
+		t.Errorf("Expected the page known only from the sitemap and the new page to be added, got %v", update.Added)
+	}
+}
+
+func TestMergeCriteria(t *testing.T) {
+	snap := New("https://example.com/", "")
+	snap.Criteria = models.ParseCriteria("CRITERION #1:\nTITLE: Login\nSCENARIO: Old\nEXPECTED: Old\n\nCRITERION #2:\nTITLE: Search\nSCENARIO: Search\nEXPECTED: Results")
+
+	MergeCriteria(snap, []models.TestCriterion{
+		{Title: "Login", Scenario: "New", Expected: "New"},
+		{Title: "Checkout", Scenario: "Buy", Expected: "Order"},
+	})
+
+	if len(snap.Criteria) != 3 {
+		t.Fatalf("Expected 3 criteria, got %d", len(snap.Criteria))
+	}
+	if snap.Criteria[0].Scenario != "New" {
+		t.Errorf("Expected Login criterion to be replaced, got %q", snap.Criteria[0].Scenario)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "snapshot.json")
+
+	if _, err := Load(path); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	snap := New("https://example.com/", "spec")
+	RecordPages(snap, nil, map[string]string{"https://example.com/": "<main>Home</main>"})
+	if err := Save(path, snap); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	if loaded.Pages["https://example.com/"].Hash != HashContent("<main>Home</main>") {
+		t.Errorf("Expected page hash to survive a round trip")
+	}
+}
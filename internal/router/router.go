@@ -1,25 +1,72 @@
 package router
 
 import (
+	"time"
+
 	"github.com/go-chi/chi/v5"
+	"github.com/webscopeio/ai-hackathon/internal/auth"
 	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/events"
 	"github.com/webscopeio/ai-hackathon/internal/handlers"
 	"github.com/webscopeio/ai-hackathon/internal/llm"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/store"
+	"github.com/webscopeio/ai-hackathon/internal/telemetry"
 )
 
+// requestsPerMinute is the rate limit of tokens issued without their own
+const requestsPerMinute = 100
+
 func New() *chi.Mux {
 	return chi.NewRouter()
 }
 
-func RegisterRoutes(r *chi.Mux, cfg *config.Config, llm *llm.Client) {
+// RegisterRoutes registers the endpoints, all but /status require a bearer token
+// Members can run jobs and read, changing the config and projects requires an admin token
+// The output of the commands of running jobs is streamed at /runs/{id}/events, their artifacts are served at /runs/{id}/artifacts
+func RegisterRoutes(r *chi.Mux, provider *config.Provider, llm *llm.Client, repo store.Repository) {
+	hub := events.NewHub()
+
 	r.Get("/status", handlers.Status)
 
-	r.Post("/crawl", handlers.Crawl)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware(repo))
+		r.Use(auth.RateLimit(requestsPerMinute, time.Minute))
+		r.Use(events.Middleware(hub))
+
+		r.Method("GET", "/metrics", telemetry.Handler())
+
+		r.Post("/crawl", handlers.Crawl(provider))
+
+		// Configuration endpoints
+		r.Get("/config", handlers.GetConfig())
+
+		// Analyze endpoints
+		r.Post("/analyze", handlers.Analyze(provider, llm, repo))
+
+		// Test generation endpoints
+		r.Post("/from-session", handlers.FromSession(provider, llm, repo))
+
+		// Project endpoints
+		r.Get("/projects", handlers.ListProjects())
+		r.Get("/projects/{name}", handlers.GetProject())
+
+		// History endpoints
+		r.Get("/runs", handlers.ListRuns(repo))
+		r.Get("/runs/compare", handlers.CompareRuns(repo))
+		r.Get("/runs/{id}", handlers.GetRun(repo))
+		r.Get("/runs/{id}/events", handlers.RunEvents(repo, hub))
+		r.Get("/runs/{id}/artifacts/*", handlers.RunArtifact(repo))
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireRole(models.RoleAdmin))
 
-	// Configuration endpoints
-	r.Get("/config", handlers.GetConfig())
-	r.Post("/config", handlers.SaveConfig())
+			r.Post("/config", handlers.SaveConfig(provider))
 
-	// Analyze endpoints
-	r.Post("/analyze", handlers.Analyze(cfg, llm))
+			r.Post("/projects", handlers.CreateProject(provider))
+			r.Put("/projects/{name}", handlers.UpdateProject(provider))
+			r.Delete("/projects/{name}", handlers.DeleteProject(provider))
+			r.Post("/projects/{name}/use", handlers.UseProject(provider))
+		})
+	})
 }
@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+)
+
+// egressProxy is an HTTP proxy on loopback forwarding only the requests the policy allows
+// HTTPS goes through CONNECT tunnels, so the policy sees the host but not the path
+type egressProxy struct {
+	policy    *netguard.Policy
+	transport http.RoundTripper
+	listener  net.Listener
+	server    *http.Server
+
+	mu      sync.Mutex
+	blocked map[string]bool
+	// tunnels are the connections of open CONNECT tunnels, the server doesn't track hijacked connections
+	tunnels map[net.Conn]bool
+	closed  bool
+}
+
+func startEgressProxy(policy *netguard.Policy) (*egressProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't start the egress proxy: %w", err)
+	}
+
+	proxy := &egressProxy{
+		policy:    policy,
+		transport: policy.Transport(),
+		listener:  listener,
+		blocked:   map[string]bool{},
+		tunnels:   map[net.Conn]bool{},
+	}
+	proxy.server = &http.Server{Handler: proxy, ReadHeaderTimeout: 10 * time.Second}
+	go proxy.server.Serve(listener)
+	return proxy, nil
+}
+
+// URL is the address commands use the proxy at
+func (p *egressProxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// Env points the proxy variables at the proxy, TESTBUDDY_PROXY is read by the Playwright config
+func (p *egressProxy) Env() []string {
+	return []string{
+		"TESTBUDDY_PROXY=" + p.URL(),
+		"HTTP_PROXY=" + p.URL(),
+		"HTTPS_PROXY=" + p.URL(),
+		"http_proxy=" + p.URL(),
+		"https_proxy=" + p.URL(),
+		"NO_PROXY=",
+		"no_proxy=",
+	}
+}
+
+// Blocked returns the hosts the proxy refused, sorted
+func (p *egressProxy) Blocked() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hosts := make([]string, 0, len(p.blocked))
+	for host := range p.blocked {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Close stops the proxy and closes its open tunnels, so no connection outlives the run
+func (p *egressProxy) Close() error {
+	err := p.server.Close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for conn := range p.tunnels {
+		conn.Close()
+	}
+	p.tunnels = map[net.Conn]bool{}
+	return err
+}
+
+// track remembers the connections of a tunnel until untrack, it fails once the proxy is closed
+func (p *egressProxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	for _, conn := range conns {
+		p.tunnels[conn] = true
+	}
+	return true
+}
+
+func (p *egressProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		delete(p.tunnels, conn)
+	}
+}
+
+func (p *egressProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "only proxy requests are served", http.StatusBadRequest)
+		return
+	}
+	if err := p.allow(r.Context(), r.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.Header.Del("Proxy-Connection")
+	out.Header.Del("Proxy-Authorization")
+
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// tunnel connects a CONNECT request to its checked host and copies the bytes both ways
+func (p *egressProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	if err := p.allow(r.Context(), &url.URL{Scheme: "https", Host: r.Host}); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	upstream, err := p.policy.DialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunnels aren't supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if !p.track(client, upstream) {
+		client.Close()
+		upstream.Close()
+		return
+	}
+	defer p.untrack(client, upstream)
+
+	client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	go func() {
+		io.Copy(upstream, buffered)
+		upstream.Close()
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+}
+
+// allow checks u against the policy and remembers the hosts it refuses
+func (p *egressProxy) allow(ctx context.Context, u *url.URL) error {
+	err := p.policy.Check(ctx, u)
+	if errors.Is(err, netguard.ErrBlocked) {
+		p.mu.Lock()
+		p.blocked[u.Hostname()] = true
+		p.mu.Unlock()
+	}
+	return err
+}
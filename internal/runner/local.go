@@ -0,0 +1,331 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/events"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// passEnv are the variables kept from the environment of the server, everything else, API keys included, is dropped
+var passEnv = []string{"PATH", "LANG", "LC_ALL", "TZ", "NODE_EXTRA_CA_CERTS", "PLAYWRIGHT_BROWSERS_PATH"}
+
+const (
+	// watchInterval is how often the watchdog samples the processes of a command
+	watchInterval = 200 * time.Millisecond
+	// waitDelay is how long a stopped command may take to close its output
+	waitDelay = 5 * time.Second
+	// maxLineBytes is the longest line of output sent as one event
+	maxLineBytes = 16 << 10
+)
+
+// Local runs commands on this machine in a sandbox:
+//   - a scrubbed environment, with the home and temp directories inside the working directory
+//   - its own process group, killed together with all child processes when the command ends or is stopped
+//   - rlimits on the CPU time of each process and on file sizes
+//   - a watchdog on the memory and CPU time of all processes of the command together
+//   - an egress proxy allowing only the hosts of Spec.Egress
+//
+// rlimits and the watchdog need Linux, other platforms only get the timeout, output and egress limits.
+// The egress proxy is set through the proxy variables and the Playwright config,
+// a process ignoring them isn't stopped by it.
+type Local struct {
+	limits Limits
+}
+
+// NewLocal creates a local sandbox with limits
+func NewLocal(limits Limits) *Local {
+	return &Local{limits: limits}
+}
+
+func (l *Local) Run(ctx context.Context, spec Spec) (*Result, error) {
+	if len(spec.Command) == 0 {
+		return nil, fmt.Errorf("no command to run")
+	}
+	log := logger.For(ctx, "runner")
+
+	ctx, span := telemetry.StartSpan(ctx, "subprocess "+spec.Command[0],
+		attribute.String("process.command_line", strings.Join(spec.Command, " ")),
+		attribute.String("process.working_directory", spec.Dir),
+		attribute.String("playwright.step", spec.Step),
+	)
+
+	env, err := sandboxEnv(spec.Dir)
+	if err != nil {
+		telemetry.EndSpan(span, err)
+		return nil, err
+	}
+
+	var proxy *egressProxy
+	if spec.Egress != nil {
+		if proxy, err = startEgressProxy(spec.Egress); err != nil {
+			telemetry.EndSpan(span, err)
+			return nil, err
+		}
+		defer proxy.Close()
+		env = append(env, proxy.Env()...)
+	}
+	env = append(env, spec.Env...)
+
+	timeout := l.limits.timeout(spec.Phase)
+	runCtx, cancel := context.WithCancel(ctx)
+	if timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	stop := &stopper{cancel: cancel}
+	output := &limitedBuffer{limit: l.limits.OutputBytes, onOverflow: func() {
+		stop.stop(models.FailureOutputLimit, fmt.Sprintf("the output exceeded %d bytes", l.limits.OutputBytes))
+	}}
+
+	cmd := exec.CommandContext(runCtx, spec.Command[0], spec.Command[1:]...)
+	cmd.Dir = spec.Dir
+	cmd.Env = env
+	stdout := &lineWriter{ctx: ctx, step: spec.Step, stream: events.StreamStdout}
+	stderr := &lineWriter{ctx: ctx, step: spec.Step, stream: events.StreamStderr}
+	cmd.Stdout = io.MultiWriter(output, stdout)
+	cmd.Stderr = io.MultiWriter(output, stderr)
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		killTree(cmd.Process.Pid)
+		return nil
+	}
+
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		err = fmt.Errorf("couldn't start %s: %w", spec.Command[0], err)
+		telemetry.EndSpan(span, err)
+		return nil, err
+	}
+	pid := cmd.Process.Pid
+	if err := setRlimits(pid, l.limits); err != nil {
+		log.Warn("couldn't set the resource limits", "step", spec.Step, "error", err)
+	}
+
+	watchDone := make(chan struct{})
+	go l.watch(pid, stop, watchDone)
+	waitErr := cmd.Wait()
+	close(watchDone)
+	stdout.Flush()
+	stderr.Flush()
+	// Browsers outlive a test runner that crashed or was killed
+	killTree(pid)
+
+	result := &Result{
+		Output:   output.Bytes(),
+		ExitCode: cmd.ProcessState.ExitCode(),
+		Duration: time.Since(started),
+		Failure:  stop.failure(),
+	}
+	if result.Failure == nil {
+		switch {
+		case ctx.Err() != nil:
+			result.Failure = &models.RunFailure{Reason: models.FailureCanceled, Detail: ctx.Err().Error()}
+		case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+			result.Failure = &models.RunFailure{Reason: models.FailureTimeout, Detail: fmt.Sprintf("the %s phase didn't finish within %s", phaseName(spec.Phase), timeout)}
+		default:
+			result.Failure = signalFailure(cmd.ProcessState)
+		}
+	}
+	if proxy != nil {
+		result.Blocked = proxy.Blocked()
+		if result.Failure == nil && result.ExitCode != 0 && len(result.Blocked) > 0 {
+			result.Failure = &models.RunFailure{Reason: models.FailureEgressBlocked, Detail: "requests to hosts outside the target were blocked: " + strings.Join(result.Blocked, ", ")}
+		}
+	}
+
+	if result.Failure != nil {
+		log.Warn("sandbox stopped the command", "step", spec.Step, "reason", result.Failure.Reason, "detail", result.Failure.Detail)
+		span.SetAttributes(attribute.String("sandbox.failure", result.Failure.Reason))
+	} else if waitErr != nil && result.ExitCode == -1 {
+		log.Debug("command ended abnormally", "step", spec.Step, "error", waitErr)
+	}
+	span.SetAttributes(attribute.Int("process.exit_code", result.ExitCode))
+	if spec.Step != "" {
+		telemetry.ObservePlaywrightRun(spec.Step, result.Duration, result.Err())
+	}
+	telemetry.EndSpan(span, result.Err())
+	return result, nil
+}
+
+// watch stops the command when all its processes together exceed the memory or CPU limit
+func (l *Local) watch(pid int, stop *stopper, done <-chan struct{}) {
+	if l.limits.MemoryBytes == 0 && l.limits.CPUTime == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		usage, ok := sampleTree(pid)
+		if !ok {
+			return
+		}
+		if l.limits.MemoryBytes > 0 && usage.memory > l.limits.MemoryBytes {
+			stop.stop(models.FailureMemoryLimit, fmt.Sprintf("the processes used %d MiB of memory, the limit is %d MiB", usage.memory>>20, l.limits.MemoryBytes>>20))
+			return
+		}
+		if l.limits.CPUTime > 0 && usage.cpu > l.limits.CPUTime {
+			stop.stop(models.FailureCPULimit, fmt.Sprintf("the processes used %s of CPU time, the limit is %s", usage.cpu.Round(time.Millisecond), l.limits.CPUTime))
+			return
+		}
+	}
+}
+
+func phaseName(phase string) string {
+	if phase == "" {
+		return PhaseTest
+	}
+	return phase
+}
+
+// usage is the resource usage of the processes of a command
+type usage struct {
+	memory uint64
+	cpu    time.Duration
+}
+
+// sandboxEnv returns the scrubbed environment for a command in dir
+// Browser binaries are shared between runs, they're too large to download for each one.
+// The pnpm store is per run, a shared one would let a run change the packages of the next ones.
+func sandboxEnv(dir string) ([]string, error) {
+	home := filepath.Join(dir, ".sandbox", "home")
+	tmp := filepath.Join(dir, ".sandbox", "tmp")
+	store := filepath.Join(dir, ".sandbox", "pnpm-store")
+	for _, d := range []string{home, tmp, store} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return nil, fmt.Errorf("couldn't create the sandbox directories: %w", err)
+		}
+	}
+
+	env := []string{"HOME=" + home, "TMPDIR=" + tmp, "XDG_CONFIG_HOME=" + filepath.Join(home, ".config"), "npm_config_store_dir=" + store}
+	for _, key := range passEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	if cache, err := os.UserCacheDir(); err == nil {
+		if _, ok := os.LookupEnv("PLAYWRIGHT_BROWSERS_PATH"); !ok {
+			env = append(env, "PLAYWRIGHT_BROWSERS_PATH="+filepath.Join(cache, "ms-playwright"))
+		}
+	}
+	return env, nil
+}
+
+// lineWriter emits every line written to it as an event of the context
+// Lines longer than maxLineBytes are split, progress bars print without newlines
+type lineWriter struct {
+	ctx     context.Context
+	step    string
+	stream  string
+	pending []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.pending[:i])
+		w.pending = w.pending[i+1:]
+	}
+	if len(w.pending) > maxLineBytes {
+		w.emit(w.pending)
+		w.pending = nil
+	}
+	return len(p), nil
+}
+
+// Flush emits the last line when it has no newline
+func (w *lineWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.emit(w.pending)
+		w.pending = nil
+	}
+}
+
+func (w *lineWriter) emit(line []byte) {
+	events.Emit(w.ctx, models.RunEvent{
+		Step:   w.step,
+		Stream: w.stream,
+		Line:   logger.Redact(strings.TrimSuffix(string(line), "\r")),
+	})
+}
+
+// stopper records the first reason a command was stopped for and stops it
+type stopper struct {
+	mu     sync.Mutex
+	reason *models.RunFailure
+	cancel context.CancelFunc
+}
+
+func (s *stopper) stop(reason, detail string) {
+	s.mu.Lock()
+	if s.reason == nil {
+		s.reason = &models.RunFailure{Reason: reason, Detail: detail}
+	}
+	s.mu.Unlock()
+	s.cancel()
+}
+
+func (s *stopper) failure() *models.RunFailure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason
+}
+
+// limitedBuffer collects the output of a command up to limit bytes, a zero limit collects everything
+type limitedBuffer struct {
+	mu         sync.Mutex
+	buf        []byte
+	limit      int
+	overflowed bool
+	onOverflow func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	if b.limit > 0 && len(b.buf)+len(p) > b.limit {
+		b.buf = append(b.buf, p[:b.limit-len(b.buf)]...)
+		overflowed := b.overflowed
+		b.overflowed = true
+		b.mu.Unlock()
+		if !overflowed {
+			b.onOverflow()
+		}
+		return len(p), nil
+	}
+	b.buf = append(b.buf, p...)
+	b.mu.Unlock()
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf...)
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"golang.org/x/sys/unix"
+)
+
+// clockTicks is USER_HZ, the unit of the CPU times in /proc, it is 100 on every supported architecture
+const clockTicks = 100
+
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// setRlimits limits the process, the processes it starts inherit the limits
+// The CPU limit is a backstop per process, the watchdog limits all processes together
+func setRlimits(pid int, limits Limits) error {
+	if limits.CPUTime > 0 {
+		seconds := uint64(limits.CPUTime.Seconds()) + 1
+		if err := unix.Prlimit(pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: seconds, Max: seconds + 5}, nil); err != nil {
+			return err
+		}
+	}
+	if limits.FileSizeBytes > 0 {
+		if err := unix.Prlimit(pid, unix.RLIMIT_FSIZE, &unix.Rlimit{Cur: limits.FileSizeBytes, Max: limits.FileSizeBytes}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signalFailure maps the signals of exceeded rlimits to failures
+func signalFailure(state *os.ProcessState) *models.RunFailure {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return nil
+	}
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		return &models.RunFailure{Reason: models.FailureCPULimit, Detail: "a process exceeded its CPU time limit"}
+	case syscall.SIGXFSZ:
+		return &models.RunFailure{Reason: models.FailureFileSizeLimit, Detail: "a process exceeded the file size limit"}
+	}
+	return nil
+}
+
+// killTree kills the process group of pid and every descendant of pid
+// Descendants are killed one by one too, browsers start their own process groups
+func killTree(pid int) {
+	descendants := tree(pid)
+	syscall.Kill(-pid, syscall.SIGKILL)
+	for _, p := range descendants {
+		syscall.Kill(p, syscall.SIGKILL)
+	}
+}
+
+// sampleTree sums the usage of pid and its descendants
+// The CPU time includes the children the processes already waited for
+func sampleTree(pid int) (usage, bool) {
+	var total usage
+	pageSize := uint64(os.Getpagesize())
+	for _, p := range tree(pid) {
+		stat, ok := readStat(p)
+		if !ok {
+			continue
+		}
+		total.memory += stat.rss * pageSize
+		total.cpu += time.Duration(stat.cpuTicks) * time.Second / clockTicks
+	}
+	return total, true
+}
+
+// tree returns pid and its descendants, together with the processes still in its process group
+func tree(pid int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return []int{pid}
+	}
+
+	children := map[int][]int{}
+	found := []int{pid}
+	seen := map[int]bool{pid: true}
+	for _, entry := range entries {
+		p, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, ok := readStat(p)
+		if !ok {
+			continue
+		}
+		children[stat.ppid] = append(children[stat.ppid], p)
+		if stat.pgrp == pid && !seen[p] {
+			seen[p] = true
+			found = append(found, p)
+		}
+	}
+
+	for i := 0; i < len(found); i++ {
+		for _, child := range children[found[i]] {
+			if !seen[child] {
+				seen[child] = true
+				found = append(found, child)
+			}
+		}
+	}
+	return found
+}
+
+type procStat struct {
+	ppid     int
+	pgrp     int
+	cpuTicks uint64
+	rss      uint64
+}
+
+// readStat parses /proc/<pid>/stat, the fields are numbered as in proc(5)
+func readStat(pid int) (procStat, bool) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, false
+	}
+
+	// The command name in field 2 may contain spaces, the fields after it start at state (3)
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return procStat{}, false
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return procStat{}, false
+	}
+	field := func(n int) uint64 {
+		value, _ := strconv.ParseUint(fields[n-3], 10, 64)
+		return value
+	}
+
+	return procStat{
+		ppid:     int(field(4)),
+		pgrp:     int(field(5)),
+		cpuTicks: field(14) + field(15) + field(16) + field(17),
+		rss:      field(24),
+	}, true
+}
@@ -0,0 +1,32 @@
+//go:build !linux
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+// Without /proc and prlimit only the command itself is killed and there are no resource limits
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func setRlimits(pid int, limits Limits) error {
+	return nil
+}
+
+func signalFailure(state *os.ProcessState) *models.RunFailure {
+	return nil
+}
+
+func killTree(pid int) {
+	if process, err := os.FindProcess(pid); err == nil {
+		process.Kill()
+	}
+}
+
+func sampleTree(pid int) (usage, bool) {
+	return usage{}, false
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+)
+
+// Runner runs the commands of a Playwright project, the generated tests among them
+type Runner interface {
+	// Run runs the command of spec to completion or until a limit stops it
+	// The error is only set when the command couldn't be started, stopped commands are described by Result.Failure
+	Run(ctx context.Context, spec Spec) (*Result, error)
+}
+
+// Phases of the commands, each has its own timeout
+const (
+	PhaseInstall = "install"
+	PhaseTest    = "test"
+)
+
+// Spec is a command to run
+type Spec struct {
+	// Step names the command in metrics, spans and events, e.g. "test" or "install_browsers"
+	Step string
+	// Phase selects the timeout, PhaseTest when empty
+	Phase   string
+	Command []string
+	// Dir is the working directory, the sandbox keeps its home and temp directories inside it
+	Dir string
+	// Env holds extra KEY=value pairs on top of the scrubbed environment
+	Env []string
+	// Egress is the policy of the hosts the command may reach, nil leaves the network unrestricted
+	Egress *netguard.Policy
+}
+
+// Limits are the resources a command may use, zero values disable a limit
+type Limits struct {
+	// Timeout limits the commands of the test phase
+	Timeout time.Duration
+	// InstallTimeout limits the commands of the install phase, they wait on the network
+	InstallTimeout time.Duration
+	// MemoryBytes limits the resident memory of the command and all its child processes together
+	MemoryBytes uint64
+	// CPUTime limits the CPU time of the command and all its child processes together
+	CPUTime time.Duration
+	// OutputBytes limits the combined output of the command
+	OutputBytes int
+	// FileSizeBytes limits the size of every file the command writes
+	FileSizeBytes uint64
+}
+
+// DefaultLimits are used for the commands of a runner without a config
+var DefaultLimits = Limits{
+	Timeout:        10 * time.Minute,
+	InstallTimeout: 15 * time.Minute,
+	MemoryBytes:    4 << 30,
+	CPUTime:        20 * time.Minute,
+	OutputBytes:    8 << 20,
+	FileSizeBytes:  1 << 30,
+}
+
+// timeout returns the timeout of phase
+func (l Limits) timeout(phase string) time.Duration {
+	if phase == PhaseInstall {
+		return l.InstallTimeout
+	}
+	return l.Timeout
+}
+
+// Result is the outcome of a command
+type Result struct {
+	Output   []byte
+	ExitCode int
+	Duration time.Duration
+	// Failure is set when a limit or the egress policy stopped the command
+	Failure *models.RunFailure
+	// Blocked are the hosts the egress policy refused
+	Blocked []string
+}
+
+// Passed reports whether the command finished on its own and exited with 0
+func (r *Result) Passed() bool {
+	return r.Failure == nil && r.ExitCode == 0
+}
+
+// Err describes a command that didn't pass as an error, it is nil for a passed command
+func (r *Result) Err() error {
+	if r.Failure != nil {
+		return fmt.Errorf("%s: %s", r.Failure.Reason, r.Failure.Detail)
+	}
+	if r.ExitCode != 0 {
+		return fmt.Errorf("exit code %d", r.ExitCode)
+	}
+	return nil
+}
+
+// ForConfig returns the local sandbox with the limits of cfg
+func ForConfig(cfg *config.Config) Runner {
+	limits := DefaultLimits
+	if cfg.RunnerTimeout > 0 {
+		limits.Timeout = cfg.RunnerTimeout
+	}
+	if cfg.RunnerInstallTimeout > 0 {
+		limits.InstallTimeout = cfg.RunnerInstallTimeout
+	}
+	if cfg.RunnerMemoryMB > 0 {
+		limits.MemoryBytes = uint64(cfg.RunnerMemoryMB) << 20
+	}
+	if cfg.RunnerCPUSeconds > 0 {
+		limits.CPUTime = time.Duration(cfg.RunnerCPUSeconds) * time.Second
+	}
+	return NewLocal(limits)
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the runner
+func NewContext(ctx context.Context, runner Runner) context.Context {
+	return context.WithValue(ctx, contextKey{}, runner)
+}
+
+// FromContext returns the runner of the context, or the local sandbox with the default limits
+func FromContext(ctx context.Context) Runner {
+	if runner, ok := ctx.Value(contextKey{}).(Runner); ok {
+		return runner
+	}
+	return NewLocal(DefaultLimits)
+}
@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/events"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("API_KEY", "sk-secret")
+	dir := t.TempDir()
+
+	result, err := NewLocal(DefaultLimits).Run(context.Background(), Spec{Command: []string{"env"}, Dir: dir, Env: []string{"EXTRA=1"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !result.Passed() {
+		t.Fatalf("Expected the command to pass, got %v", result.Err())
+	}
+
+	env := string(result.Output)
+	if strings.Contains(env, "sk-secret") {
+		t.Errorf("Expected the API key to be scrubbed, got:\n%s", env)
+	}
+	if !strings.Contains(env, "HOME="+dir) || !strings.Contains(env, "EXTRA=1") {
+		t.Errorf("Expected the sandbox home and the extra variable, got:\n%s", env)
+	}
+	if !strings.Contains(env, "npm_config_store_dir="+dir) {
+		t.Errorf("Expected a pnpm store of the run, got:\n%s", env)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the resource limits need Linux")
+	}
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		phase   string
+		command string
+		reason  string
+	}{
+		{"timeout", Limits{Timeout: 200 * time.Millisecond}, "", "sleep 10", models.FailureTimeout},
+		{"install timeout", Limits{Timeout: time.Minute, InstallTimeout: 200 * time.Millisecond}, PhaseInstall, "sleep 10", models.FailureTimeout},
+		{"output", Limits{Timeout: 10 * time.Second, OutputBytes: 1024}, "", "yes", models.FailureOutputLimit},
+		{"cpu", Limits{Timeout: 20 * time.Second, CPUTime: time.Second}, "", "while :; do :; done", models.FailureCPULimit},
+		{"memory", Limits{Timeout: 20 * time.Second, MemoryBytes: 64 << 20}, "", `x=$(head -c 300000000 /dev/zero | tr '\0' a); sleep 10`, models.FailureMemoryLimit},
+		{"child processes", Limits{Timeout: 500 * time.Millisecond}, "", "sleep 10 & sleep 10 & wait", models.FailureTimeout},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			started := time.Now()
+			result, err := NewLocal(test.limits).Run(context.Background(), Spec{Phase: test.phase, Command: []string{"sh", "-c", test.command}, Dir: t.TempDir()})
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if result.Failure == nil || result.Failure.Reason != test.reason {
+				t.Fatalf("Expected the %s failure, got %+v", test.reason, result.Failure)
+			}
+			if elapsed := time.Since(started); elapsed > 4*time.Second {
+				t.Errorf("Expected the command to be stopped early, it took %s", elapsed)
+			}
+		})
+	}
+}
+
+type collector struct {
+	mu     sync.Mutex
+	events []models.RunEvent
+}
+
+func (c *collector) Emit(event models.RunEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+func TestStream(t *testing.T) {
+	sink := &collector{}
+	ctx := events.NewContext(context.Background(), sink)
+
+	_, err := NewLocal(DefaultLimits).Run(ctx, Spec{Step: "test", Command: []string{"sh", "-c", "echo one; sleep 0.1; echo two >&2; printf three"}, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var lines []string
+	for _, event := range sink.events {
+		lines = append(lines, event.Stream+":"+event.Line)
+	}
+	if strings.Join(lines, ",") != "stdout:one,stderr:two,stdout:three" {
+		t.Errorf("Expected every line as an event, got %v", lines)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	result, err := NewLocal(DefaultLimits).Run(ctx, Spec{Command: []string{"sh", "-c", "sleep 10 & sleep 10 & wait"}, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Failure == nil || result.Failure.Reason != models.FailureCanceled {
+		t.Errorf("Expected the canceled failure, got %+v", result.Failure)
+	}
+	if elapsed := time.Since(started); elapsed > 4*time.Second {
+		t.Errorf("Expected the process group to be killed, it took %s", elapsed)
+	}
+}
+
+func TestEgressProxy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	proxy, err := startEgressProxy(netguard.New(true, []string{"127.0.0.1"}))
+	if err != nil {
+		t.Fatalf("startEgressProxy failed: %v", err)
+	}
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	resp, err := client.Get(target.URL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the target to be reachable, got %v, %v", resp, err)
+	}
+	resp.Body.Close()
+
+	resp, err = client.Get("http://tracker.example.org/collect")
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected the outside host to be refused, got %v, %v", resp, err)
+	}
+	resp.Body.Close()
+
+	if blocked := proxy.Blocked(); len(blocked) != 1 || blocked[0] != "tracker.example.org" {
+		t.Errorf("Expected the refused host to be reported, got %v", blocked)
+	}
+}
+
+func TestEgressProxyClosesTunnels(t *testing.T) {
+	// The upstream keeps the tunnel open until it is closed
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+
+	proxy, err := startEgressProxy(netguard.New(true, []string{"127.0.0.1"}))
+	if err != nil {
+		t.Fatalf("startEgressProxy failed: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", proxy.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("CONNECT " + upstream.Addr().String() + " HTTP/1.1\r\nHost: " + upstream.Addr().String() + "\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the tunnel to be established, got %v, %v", resp, err)
+	}
+
+	proxy.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the tunnel to be closed with the proxy, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// KeyEnv holds the base64 encoded encryption key
+	KeyEnv = "TESTBUDDY_SECRET_KEY"
+	// KeyFileEnv points to a file holding the base64 encoded encryption key
+	KeyFileEnv = "TESTBUDDY_SECRET_KEY_FILE"
+
+	keySize         = 32
+	encryptedPrefix = "enc:v1:"
+	maskPrefix      = "********"
+	// visibleChars is the number of trailing characters left visible in masked values
+	visibleChars = 4
+)
+
+// Cipher encrypts secrets with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher for a 32 byte key
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("secret key must be %d bytes, got %d", keySize, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// LoadKey returns the key from TESTBUDDY_SECRET_KEY or the file in TESTBUDDY_SECRET_KEY_FILE
+// Without either, the key is read from defaultKeyFile, which is generated on first use
+func LoadKey(defaultKeyFile string) ([]byte, error) {
+	if encoded := strings.TrimSpace(os.Getenv(KeyEnv)); encoded != "" {
+		return decodeKey(encoded, KeyEnv)
+	}
+
+	if keyFile := os.Getenv(KeyFileEnv); keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		return decodeKey(string(data), keyFile)
+	}
+
+	data, err := os.ReadFile(defaultKeyFile)
+	if err == nil {
+		return decodeKey(string(data), defaultKeyFile)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(defaultKeyFile), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(defaultKeyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	return key, nil
+}
+
+func decodeKey(encoded, source string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret key from %s: %w", source, err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("secret key from %s must be %d bytes, got %d", source, keySize, len(key))
+	}
+	return key, nil
+}
+
+// Encrypt returns the encrypted form of a secret, empty and already encrypted values are returned as is
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of an encrypted secret
+// Values without the encrypted prefix were stored before encryption and are returned as is
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("encrypted secret is too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret, is the secret key right?: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether a value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Mask hides a secret, leaving the last characters of long values visible
+func Mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) < 4*visibleChars {
+		return maskPrefix
+	}
+	return maskPrefix + value[len(value)-visibleChars:]
+}
+
+// IsMasked reports whether a value was produced by Mask
+// Clients send masked values back unchanged when the secret wasn't edited
+func IsMasked(value string) bool {
+	return strings.HasPrefix(value, maskPrefix)
+}
@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	t.Setenv(KeyFileEnv, "")
+
+	keyFile := filepath.Join(t.TempDir(), "secret.key")
+	key, err := LoadKey(keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load key: %v", err)
+	}
+
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatalf("Expected the key file to be generated: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the key file to be private, got %v", info.Mode().Perm())
+	}
+
+	// The generated key is reused
+	again, err := LoadKey(keyFile)
+	if err != nil || string(again) != string(key) {
+		t.Fatalf("Expected the same key on the second load, got %v", err)
+	}
+
+	c, err := NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	encrypted, err := c.Encrypt("sk-ant-secret")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if !IsEncrypted(encrypted) || encrypted == "sk-ant-secret" {
+		t.Fatalf("Expected an encrypted value, got %q", encrypted)
+	}
+
+	decrypted, err := c.Decrypt(encrypted)
+	if err != nil || decrypted != "sk-ant-secret" {
+		t.Errorf("Expected the plaintext back, got %q (%v)", decrypted, err)
+	}
+
+	// Values stored before encryption are read as plaintext
+	if legacy, err := c.Decrypt("plain"); err != nil || legacy != "plain" {
+		t.Errorf("Expected a plaintext value to be returned as is, got %q (%v)", legacy, err)
+	}
+
+	other, _ := NewCipher(make([]byte, keySize))
+	if _, err := other.Decrypt(encrypted); err == nil {
+		t.Error("Expected decryption with another key to fail")
+	}
+}
+
+func TestMask(t *testing.T) {
+	masked := Mask("sk-ant-api03-abcdefgh")
+	if masked != "********efgh" || !IsMasked(masked) {
+		t.Errorf("Expected the last characters to stay visible, got %q", masked)
+	}
+	if Mask("short") != "********" {
+		t.Errorf("Expected short values to be fully masked, got %q", Mask("short"))
+	}
+	if Mask("") != "" || IsMasked("sk-ant") {
+		t.Error("Expected empty values to stay empty and plain values not to count as masked")
+	}
+}
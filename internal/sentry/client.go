@@ -0,0 +1,355 @@
+package sentry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+)
+
+const (
+	defaultPeriod = "14d"
+	defaultQuery  = "is:unresolved"
+	defaultLimit  = 100
+)
+
+// Client talks to the Sentry API of sentry.io or a self-hosted instance
+type Client struct {
+	baseURL    string
+	authToken  string
+	httpClient *http.Client
+}
+
+// IssuesQuery holds the filters for listing issues
+// Empty fields fall back to the last 14 days of unresolved issues
+type IssuesQuery struct {
+	Period      string
+	Query       string
+	Environment string
+	Release     string
+	// Limit is the maximum number of issues to fetch across all pages
+	Limit int
+}
+
+// New creates a Sentry client using the base URL and auth token from config
+func New(cfg *config.Config) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(cfg.SentryURL, "/"),
+		authToken:  cfg.SentryAuthToken,
+		httpClient: netguard.ForAPI(cfg).Client(),
+	}
+}
+
+// Issues lists the issues of a project, following the pagination links
+func (c *Client) Issues(ctx context.Context, orgSlug, projectSlug string, q IssuesQuery) ([]models.SentryIssue, error) {
+	logger.For(ctx, "sentry").Debug("getting issues", "org", orgSlug, "project", projectSlug)
+
+	if q.Period == "" {
+		q.Period = defaultPeriod
+	}
+	if q.Query == "" {
+		q.Query = defaultQuery
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+
+	// The release is a search term, the environment is a separate parameter
+	search := q.Query
+	if q.Release != "" {
+		search = fmt.Sprintf("%s release:%s", search, q.Release)
+	}
+
+	query := url.Values{}
+	query.Add("statsPeriod", q.Period)
+	query.Add("query", search)
+	if q.Environment != "" {
+		query.Add("environment", q.Environment)
+	}
+
+	nextURL := fmt.Sprintf("%s/projects/%s/%s/issues/?%s", c.baseURL, orgSlug, projectSlug, query.Encode())
+
+	issues := []models.SentryIssue{}
+	for nextURL != "" && len(issues) < q.Limit {
+		var page []models.SentryIssue
+		header, err := c.get(ctx, nextURL, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, page...)
+		nextURL = nextLink(header.Get("Link"))
+		if nextURL != "" && !c.onBaseHost(nextURL) {
+			return nil, fmt.Errorf("refusing to follow the next page link to %s, it isn't on the configured Sentry host", nextURL)
+		}
+	}
+
+	if len(issues) > q.Limit {
+		issues = issues[:q.Limit]
+	}
+
+	logger.For(ctx, "sentry").Debug("retrieved issues", "count", len(issues))
+	return issues, nil
+}
+
+// IssueTagDetails retrieves details for a specific tag of an issue
+func (c *Client) IssueTagDetails(ctx context.Context, orgSlug, issueID, tagKey string) (*models.SentryTagDetails, error) {
+	logger.For(ctx, "sentry").Debug("getting tag details", "org", orgSlug, "issue_id", issueID, "tag", tagKey)
+
+	apiURL := fmt.Sprintf("%s/organizations/%s/issues/%s/tags/%s/", c.baseURL, orgSlug, issueID, tagKey)
+
+	var tagDetails models.SentryTagDetails
+	if _, err := c.get(ctx, apiURL, &tagDetails); err != nil {
+		return nil, err
+	}
+
+	logger.For(ctx, "sentry").Debug("retrieved tag details", "issue_id", issueID, "tag", tagKey, "unique_values", tagDetails.UniqueValues)
+	return &tagDetails, nil
+}
+
+// Issue retrieves a single issue by its ID
+func (c *Client) Issue(ctx context.Context, orgSlug, issueID string) (*models.SentryIssue, error) {
+	logger.For(ctx, "sentry").Debug("getting issue", "org", orgSlug, "issue_id", issueID)
+
+	apiURL := fmt.Sprintf("%s/organizations/%s/issues/%s/", c.baseURL, orgSlug, issueID)
+
+	var issue models.SentryIssue
+	if _, err := c.get(ctx, apiURL, &issue); err != nil {
+		return nil, err
+	}
+
+	return &issue, nil
+}
+
+// LatestEvent retrieves the most recent event of an issue
+func (c *Client) LatestEvent(ctx context.Context, orgSlug, issueID string) (*models.SentryEvent, error) {
+	logger.For(ctx, "sentry").Debug("getting latest event", "org", orgSlug, "issue_id", issueID)
+
+	apiURL := fmt.Sprintf("%s/organizations/%s/issues/%s/events/latest/", c.baseURL, orgSlug, issueID)
+
+	var event models.SentryEvent
+	if _, err := c.get(ctx, apiURL, &event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// Breadcrumbs extracts the breadcrumbs entry of an event
+func Breadcrumbs(event *models.SentryEvent) []models.SentryBreadcrumb {
+	breadcrumbs := []models.SentryBreadcrumb{}
+	if event == nil {
+		return breadcrumbs
+	}
+
+	for _, entry := range event.Entries {
+		if entry["type"] != "breadcrumbs" {
+			continue
+		}
+
+		data, ok := entry["data"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		values, ok := data["values"].([]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, value := range values {
+			crumb, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			breadcrumb := models.SentryBreadcrumb{
+				Timestamp: stringValue(crumb["timestamp"]),
+				Type:      stringValue(crumb["type"]),
+				Category:  stringValue(crumb["category"]),
+				Level:     stringValue(crumb["level"]),
+				Message:   stringValue(crumb["message"]),
+			}
+			if crumbData, ok := crumb["data"].(map[string]interface{}); ok {
+				breadcrumb.Data = crumbData
+			}
+			breadcrumbs = append(breadcrumbs, breadcrumb)
+		}
+	}
+
+	return breadcrumbs
+}
+
+// Exceptions extracts the exceptions and their stack frames from an event
+func Exceptions(event *models.SentryEvent) []models.SentryException {
+	exceptions := []models.SentryException{}
+	if event == nil {
+		return exceptions
+	}
+
+	for _, entry := range event.Entries {
+		if entry["type"] != "exception" {
+			continue
+		}
+
+		data, ok := entry["data"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		values, ok := data["values"].([]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, value := range values {
+			raw, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			exception := models.SentryException{
+				Type:   stringValue(raw["type"]),
+				Value:  stringValue(raw["value"]),
+				Frames: []models.SentryStackFrame{},
+			}
+
+			stacktrace, _ := raw["stacktrace"].(map[string]interface{})
+			frames, _ := stacktrace["frames"].([]interface{})
+			for _, f := range frames {
+				frame, ok := f.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				lineNo, _ := frame["lineNo"].(float64)
+				colNo, _ := frame["colNo"].(float64)
+				inApp, _ := frame["inApp"].(bool)
+				exception.Frames = append(exception.Frames, models.SentryStackFrame{
+					Filename: stringValue(frame["filename"]),
+					Function: stringValue(frame["function"]),
+					LineNo:   int(lineNo),
+					ColNo:    int(colNo),
+					InApp:    inApp,
+				})
+			}
+
+			exceptions = append(exceptions, exception)
+		}
+	}
+
+	return exceptions
+}
+
+// EventTag returns the value of a tag of an event, or an empty string
+func EventTag(event *models.SentryEvent, key string) string {
+	if event == nil {
+		return ""
+	}
+	for _, tag := range event.Tags {
+		if tag.Key == key {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// get performs an authorized GET request and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, apiURL string, v any) (http.Header, error) {
+	if c.authToken == "" {
+		return nil, fmt.Errorf("sentry auth token not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.authToken))
+	req.Header.Add("Content-Type", "application/json")
+
+	logger.For(ctx, "sentry").Debug("requesting", "url", apiURL)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned non-OK status: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	return resp.Header, nil
+}
+
+// onBaseHost reports whether apiURL has the scheme and host of the configured Sentry URL,
+// only those requests may carry the auth token
+func (c *Client) onBaseHost(apiURL string) bool {
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return false
+	}
+	target, err := url.Parse(apiURL)
+	if err != nil {
+		return false
+	}
+	return target.Scheme == base.Scheme && strings.EqualFold(target.Host, base.Host)
+}
+
+// nextLink returns the URL of the next page from a Sentry Link header
+// Sentry always sends a next link, results="false" marks the last page
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+
+		target := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+		isNext := false
+		hasResults := false
+		for _, param := range parts[1:] {
+			switch strings.ReplaceAll(strings.TrimSpace(param), " ", "") {
+			case `rel="next"`:
+				isNext = true
+			case `results="true"`:
+				hasResults = true
+			}
+		}
+
+		if isNext && hasResults {
+			return target
+		}
+	}
+
+	return ""
+}
+
+func stringValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,136 @@
+package sentry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+)
+
+func TestIssuesPagination(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/0/projects/org/project/issues/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get("statsPeriod") != "24h" || query.Get("environment") != "production" || query.Get("query") != "is:unresolved release:1.0.0" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		next := fmt.Sprintf("%s/api/0/projects/org/project/issues/?%s&cursor=0:1:0", server.URL, r.URL.RawQuery)
+		if query.Get("cursor") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="previous"; results="false"; cursor="0:0:1", <%s>; rel="next"; results="true"; cursor="0:1:0"`, next, next))
+			w.Write([]byte(`[{"id": "1", "count": "10"}]`))
+			return
+		}
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="previous"; results="true"; cursor="0:0:1", <%s>; rel="next"; results="false"; cursor="0:2:0"`, next, next))
+		w.Write([]byte(`[{"id": "2", "count": "5"}]`))
+	}))
+	defer server.Close()
+
+	client := New(&config.Config{SentryURL: server.URL + "/api/0/", SentryAuthToken: "token", AllowLocalTargets: true})
+	issues, err := client.Issues(context.Background(), "org", "project", IssuesQuery{
+		Period:      "24h",
+		Environment: "production",
+		Release:     "1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("Failed to get issues: %v", err)
+	}
+
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues across 2 pages, got %d", len(issues))
+	}
+	if issues[0].ID != "1" || issues[1].ID != "2" {
+		t.Errorf("Expected issues 1 and 2, got %s and %s", issues[0].ID, issues[1].ID)
+	}
+}
+
+func TestIssuesPaginationOtherHost(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Link", `<https://attacker.example.org/issues/?cursor=0:1:0>; rel="next"; results="true"; cursor="0:1:0"`)
+		w.Write([]byte(`[{"id": "1", "count": "10"}]`))
+	}))
+	defer server.Close()
+
+	client := New(&config.Config{SentryURL: server.URL + "/api/0/", SentryAuthToken: "token", AllowLocalTargets: true})
+	if _, err := client.Issues(context.Background(), "org", "project", IssuesQuery{}); err == nil {
+		t.Error("Expected a next link to another host to be rejected")
+	}
+	if requests != 1 {
+		t.Errorf("Expected only the first page to be requested, got %d requests", requests)
+	}
+}
+
+func TestLatestEventBreadcrumbs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/organizations/org/issues/1/events/latest/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+  "eventID": "abc",
+  "tags": [{"key": "url", "value": "https://example.com/pricing"}],
+  "entries": [
+    {"type": "exception", "data": {"values": [
+      {"type": "TypeError", "value": "x is undefined", "stacktrace": {"frames": [
+        {"filename": "app/pricing.js", "function": "buy", "lineNo": 12, "colNo": 4, "inApp": true}
+      ]}}
+    ]}},
+    {"type": "breadcrumbs", "data": {"values": [
+      {"timestamp": "2025-04-01T10:00:00Z", "type": "navigation", "category": "navigation", "level": "info", "data": {"from": "/", "to": "/pricing"}},
+      {"timestamp": 1743501601.5, "type": "default", "category": "ui.click", "level": "info", "message": "button#buy"}
+    ]}}
+  ]
+}`))
+	}))
+	defer server.Close()
+
+	client := New(&config.Config{SentryURL: server.URL, SentryAuthToken: "token", AllowLocalTargets: true})
+	event, err := client.LatestEvent(context.Background(), "org", "1")
+	if err != nil {
+		t.Fatalf("Failed to get latest event: %v", err)
+	}
+
+	breadcrumbs := Breadcrumbs(event)
+	if len(breadcrumbs) != 2 {
+		t.Fatalf("Expected 2 breadcrumbs, got %d", len(breadcrumbs))
+	}
+	if breadcrumbs[0].Data["to"] != "/pricing" {
+		t.Errorf("Expected navigation to /pricing, got %v", breadcrumbs[0].Data["to"])
+	}
+	if breadcrumbs[1].Category != "ui.click" || breadcrumbs[1].Message != "button#buy" {
+		t.Errorf("Unexpected click breadcrumb: %+v", breadcrumbs[1])
+	}
+	if breadcrumbs[1].Timestamp != "1743501601.5" {
+		t.Errorf("Expected numeric timestamp to be kept, got %s", breadcrumbs[1].Timestamp)
+	}
+
+	if url := EventTag(event, "url"); url != "https://example.com/pricing" {
+		t.Errorf("Expected url tag, got %q", url)
+	}
+
+	exceptions := Exceptions(event)
+	if len(exceptions) != 1 || len(exceptions[0].Frames) != 1 {
+		t.Fatalf("Expected 1 exception with 1 frame, got %+v", exceptions)
+	}
+	if exceptions[0].Frames[0].LineNo != 12 || !exceptions[0].Frames[0].InApp {
+		t.Errorf("Unexpected stack frame: %+v", exceptions[0].Frames[0])
+	}
+}
@@ -0,0 +1,353 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+const (
+	runsFile         = "runs.json"
+	criteriaFile     = "criteria.json"
+	testVersionsFile = "test_versions.json"
+	testResultsFile  = "test_results.json"
+	tokensFile       = "tokens.json"
+	artifactsDir     = "artifacts"
+)
+
+// FileStore keeps each collection in a JSON file in the data directory
+// It needs no server and suits the single-user CLI and API. Writes are atomic,
+// but concurrent writers in separate processes may overwrite each other's changes.
+type FileStore struct {
+	dir   string
+	mutex sync.RWMutex
+}
+
+// NewFileStore opens the store in dir, by default in the user data directory
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		var err error
+		dir, err = defaultDataDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+// defaultDataDir follows the XDG base directory spec, $XDG_DATA_HOME/testbuddy or ~/.local/share/testbuddy
+func defaultDataDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "testbuddy"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get data directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "testbuddy"), nil
+}
+
+func (s *FileStore) SaveRun(ctx context.Context, run *models.Run) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	runs, err := load[models.Run](s.path(runsFile))
+	if err != nil {
+		return err
+	}
+
+	if run.ID == "" {
+		run.ID = newID()
+		if run.StartedAt.IsZero() {
+			run.StartedAt = time.Now()
+		}
+		runs = append(runs, *run)
+	} else if i := indexOf(runs, func(r models.Run) bool { return r.ID == run.ID }); i >= 0 {
+		runs[i] = *run
+	} else {
+		return ErrNotFound
+	}
+
+	return save(s.path(runsFile), runs)
+}
+
+func (s *FileStore) GetRun(ctx context.Context, id string) (*models.Run, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	runs, err := load[models.Run](s.path(runsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if i := indexOf(runs, func(r models.Run) bool { return r.ID == id }); i >= 0 {
+		return &runs[i], nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *FileStore) ListRuns(ctx context.Context, project string) ([]models.Run, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	runs, err := load[models.Run](s.path(runsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	result := filter(runs, func(r models.Run) bool { return project == "" || r.Project == project })
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartedAt.After(result[j].StartedAt)
+	})
+	return result, nil
+}
+
+func (s *FileStore) AddCriteria(ctx context.Context, criteria []models.RunCriterion) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, err := load[models.RunCriterion](s.path(criteriaFile))
+	if err != nil {
+		return err
+	}
+
+	for i := range criteria {
+		criteria[i].ID = newID()
+		stored = append(stored, criteria[i])
+	}
+
+	return save(s.path(criteriaFile), stored)
+}
+
+func (s *FileStore) ListCriteria(ctx context.Context, runID string) ([]models.RunCriterion, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	criteria, err := load[models.RunCriterion](s.path(criteriaFile))
+	if err != nil {
+		return nil, err
+	}
+	return filter(criteria, func(c models.RunCriterion) bool { return c.RunID == runID }), nil
+}
+
+func (s *FileStore) AddTestVersion(ctx context.Context, version *models.TestVersion) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	versions, err := load[models.TestVersion](s.path(testVersionsFile))
+	if err != nil {
+		return err
+	}
+
+	version.ID = newID()
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now()
+	}
+
+	return save(s.path(testVersionsFile), append(versions, *version))
+}
+
+func (s *FileStore) ListTestVersions(ctx context.Context, runID string) ([]models.TestVersion, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	versions, err := load[models.TestVersion](s.path(testVersionsFile))
+	if err != nil {
+		return nil, err
+	}
+	return filter(versions, func(v models.TestVersion) bool { return v.RunID == runID }), nil
+}
+
+func (s *FileStore) AddTestResult(ctx context.Context, result *models.TestResult) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	results, err := load[models.TestResult](s.path(testResultsFile))
+	if err != nil {
+		return err
+	}
+
+	result.ID = newID()
+	if result.CreatedAt.IsZero() {
+		result.CreatedAt = time.Now()
+	}
+
+	return save(s.path(testResultsFile), append(results, *result))
+}
+
+func (s *FileStore) ListTestResults(ctx context.Context, runID string) ([]models.TestResult, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	results, err := load[models.TestResult](s.path(testResultsFile))
+	if err != nil {
+		return nil, err
+	}
+	return filter(results, func(r models.TestResult) bool { return r.RunID == runID }), nil
+}
+
+// SaveArtifact writes the file to artifacts/{runID}/{path}, paths leaving the run directory are rejected
+func (s *FileStore) SaveArtifact(ctx context.Context, runID, path string, content io.Reader) error {
+	target, err := s.artifactPath(runID, path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create artifact %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, content); err != nil {
+		return fmt.Errorf("failed to write artifact %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+func (s *FileStore) OpenArtifact(ctx context.Context, runID, path string) (io.ReadSeekCloser, error) {
+	target, err := s.artifactPath(runID, path)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	file, err := os.Open(target)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact %s: %w", path, err)
+	}
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		file.Close()
+		return nil, ErrNotFound
+	}
+	return file, nil
+}
+
+func (s *FileStore) artifactPath(runID, path string) (string, error) {
+	if !filepath.IsLocal(runID) || !filepath.IsLocal(filepath.FromSlash(path)) {
+		return "", fmt.Errorf("invalid artifact path %q", path)
+	}
+	return filepath.Join(s.dir, artifactsDir, runID, filepath.FromSlash(path)), nil
+}
+
+func (s *FileStore) SaveToken(ctx context.Context, token *models.APIToken) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tokens, err := load[models.APIToken](s.path(tokensFile))
+	if err != nil {
+		return err
+	}
+
+	if token.ID == "" {
+		token.ID = newID()
+		if token.CreatedAt.IsZero() {
+			token.CreatedAt = time.Now()
+		}
+		tokens = append(tokens, *token)
+	} else if i := indexOf(tokens, func(t models.APIToken) bool { return t.ID == token.ID }); i >= 0 {
+		tokens[i] = *token
+	} else {
+		return ErrNotFound
+	}
+
+	return save(s.path(tokensFile), tokens)
+}
+
+func (s *FileStore) FindToken(ctx context.Context, hash string) (*models.APIToken, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tokens, err := load[models.APIToken](s.path(tokensFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if i := indexOf(tokens, func(t models.APIToken) bool { return t.Hash == hash }); i >= 0 {
+		return &tokens[i], nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *FileStore) ListTokens(ctx context.Context) ([]models.APIToken, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return load[models.APIToken](s.path(tokensFile))
+}
+
+func (s *FileStore) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// load reads a collection, a missing file is an empty collection
+func load[T any](path string) ([]T, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []T{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+
+	var records []T
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return records, nil
+}
+
+// save writes a collection to a temporary file and renames it over the old one
+func save[T any](path string, records []T) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func indexOf[T any](records []T, match func(T) bool) int {
+	for i, record := range records {
+		if match(record) {
+			return i
+		}
+	}
+	return -1
+}
+
+func filter[T any](records []T, match func(T) bool) []T {
+	result := []T{}
+	for _, record := range records {
+		if match(record) {
+			result = append(result, record)
+		}
+	}
+	return result
+}
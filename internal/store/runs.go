@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/events"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+// StartRun records the start of a run of a website for the named project of the user config
+func StartRun(ctx context.Context, repo Repository, project, siteURL, kind string) (*models.Run, error) {
+	run := &models.Run{
+		Project:   project,
+		URL:       siteURL,
+		Kind:      kind,
+		Status:    models.RunStatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := repo.SaveRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("failed to create run: %w", err)
+	}
+	return run, nil
+}
+
+// FinishRun records the end of a run, failed when runErr is set
+// The status is also sent as the last event of the run, ending its event streams
+func FinishRun(ctx context.Context, repo Repository, run *models.Run, runErr error) error {
+	run.Status = models.RunStatusFinished
+	if runErr != nil {
+		run.Status = models.RunStatusFailed
+		run.Error = runErr.Error()
+	}
+	run.FinishedAt = time.Now()
+	events.Emit(ctx, models.RunEvent{RunID: run.ID, Step: "run", Stream: events.StreamStatus, Line: run.Status})
+	return repo.SaveRun(ctx, run)
+}
+
+// RecordCriteria stores the criteria of a run in order
+func RecordCriteria(ctx context.Context, repo Repository, run *models.Run, criteria []models.TestCriterion) ([]models.RunCriterion, error) {
+	stored := make([]models.RunCriterion, len(criteria))
+	for i, criterion := range criteria {
+		stored[i] = models.RunCriterion{RunID: run.ID, Index: i, Criterion: criterion}
+	}
+
+	if err := repo.AddCriteria(ctx, stored); err != nil {
+		return nil, fmt.Errorf("failed to store criteria: %w", err)
+	}
+	return stored, nil
+}
+
+// RecordGenEval stores every test version of a gen-eval loop together with its test result
+// The artifacts of an iteration are stored under the ID of its test version
+func RecordGenEval(ctx context.Context, repo Repository, run *models.Run, criterionID, file string, result *models.GenEvalResult) error {
+	for i, iteration := range result.Iterations {
+		version := &models.TestVersion{
+			RunID:       run.ID,
+			CriterionID: criterionID,
+			File:        file,
+			Version:     i + 1,
+			Content:     iteration.Content,
+		}
+		if err := repo.AddTestVersion(ctx, version); err != nil {
+			return fmt.Errorf("failed to store test version: %w", err)
+		}
+
+		artifacts, err := recordArtifacts(ctx, repo, run, version.ID, iteration)
+		if err != nil {
+			return err
+		}
+
+		if err := repo.AddTestResult(ctx, &models.TestResult{
+			RunID:         run.ID,
+			TestVersionID: version.ID,
+			Passed:        iteration.TestsPassed,
+			Accepted:      iteration.Accepted,
+			Output:        iteration.Output,
+			Feedback:      iteration.Feedback,
+			Failure:       iteration.Failure,
+			Stability:     iteration.Stability,
+			Projects:      iteration.Projects,
+			Artifacts:     artifacts,
+			DurationMs:    iteration.DurationMs,
+		}); err != nil {
+			return fmt.Errorf("failed to store test result: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordArtifacts copies the collected artifacts of an iteration into the store
+func recordArtifacts(ctx context.Context, repo Repository, run *models.Run, versionID string, iteration models.GenEvalIteration) ([]models.Artifact, error) {
+	var stored []models.Artifact
+	for _, artifact := range iteration.Artifacts {
+		file, err := os.Open(filepath.Join(iteration.ArtifactDir, filepath.FromSlash(artifact.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact: %w", err)
+		}
+		artifact.Path = path.Join(versionID, artifact.Path)
+		err = repo.SaveArtifact(ctx, run.ID, artifact.Path, file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to store artifact: %w", err)
+		}
+		stored = append(stored, artifact)
+	}
+	return stored, nil
+}
+
+// Details loads a run together with its criteria, test versions and results
+func Details(ctx context.Context, repo Repository, runID string) (*models.RunDetails, error) {
+	run, err := repo.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	criteria, err := repo.ListCriteria(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	tests, err := repo.ListTestVersions(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := repo.ListTestResults(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RunDetails{Run: *run, Criteria: criteria, Tests: tests, Results: results}, nil
+}
+
+// Compare compares the criteria of two runs and whether the final version of each criterion's test passed
+func Compare(base, head *models.RunDetails) *models.RunComparison {
+	basePassed := finalResults(base)
+	headPassed := finalResults(head)
+
+	comparison := &models.RunComparison{
+		Base:            base.Run.ID,
+		Head:            head.Run.ID,
+		AddedCriteria:   []string{},
+		RemovedCriteria: []string{},
+		Fixed:           []string{},
+		Broken:          []string{},
+		Unchanged:       []string{},
+	}
+
+	baseTitles := map[string]bool{}
+	for _, c := range base.Criteria {
+		baseTitles[c.Criterion.Title] = true
+	}
+
+	headTitles := map[string]bool{}
+	for _, c := range head.Criteria {
+		title := c.Criterion.Title
+		headTitles[title] = true
+
+		switch {
+		case !baseTitles[title]:
+			comparison.AddedCriteria = append(comparison.AddedCriteria, title)
+		case !basePassed[title] && headPassed[title]:
+			comparison.Fixed = append(comparison.Fixed, title)
+		case basePassed[title] && !headPassed[title]:
+			comparison.Broken = append(comparison.Broken, title)
+		default:
+			comparison.Unchanged = append(comparison.Unchanged, title)
+		}
+	}
+
+	for _, c := range base.Criteria {
+		if !headTitles[c.Criterion.Title] {
+			comparison.RemovedCriteria = append(comparison.RemovedCriteria, c.Criterion.Title)
+		}
+	}
+
+	return comparison
+}
+
+// finalResults maps criterion titles to whether the last test version of the criterion passed
+func finalResults(details *models.RunDetails) map[string]bool {
+	titles := map[string]string{}
+	for _, c := range details.Criteria {
+		titles[c.ID] = c.Criterion.Title
+	}
+
+	passed := map[string]bool{}
+	for _, result := range details.Results {
+		for _, version := range details.Tests {
+			if version.ID == result.TestVersionID {
+				// Results are stored in order, so the last one per criterion wins
+				passed[titles[version.CriterionID]] = result.Passed
+			}
+		}
+	}
+	return passed
+}
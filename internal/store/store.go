@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+// ErrNotFound is returned when a record doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// Repository persists runs, everything generated in the runs, their artifacts and API tokens
+// Runs refer to the projects of the user config by name, the store doesn't keep projects of its own.
+// Save methods create the record when its ID is empty and replace it otherwise.
+// List methods return runs newest first and the other records in insertion order.
+type Repository interface {
+	SaveRun(ctx context.Context, run *models.Run) error
+	GetRun(ctx context.Context, id string) (*models.Run, error)
+	// ListRuns returns the runs of a project, or of all projects when project is empty
+	ListRuns(ctx context.Context, project string) ([]models.Run, error)
+
+	AddCriteria(ctx context.Context, criteria []models.RunCriterion) error
+	ListCriteria(ctx context.Context, runID string) ([]models.RunCriterion, error)
+
+	AddTestVersion(ctx context.Context, version *models.TestVersion) error
+	ListTestVersions(ctx context.Context, runID string) ([]models.TestVersion, error)
+
+	AddTestResult(ctx context.Context, result *models.TestResult) error
+	ListTestResults(ctx context.Context, runID string) ([]models.TestResult, error)
+
+	// SaveArtifact stores a file produced in a run under a relative path, OpenArtifact reads it back
+	SaveArtifact(ctx context.Context, runID, path string, content io.Reader) error
+	OpenArtifact(ctx context.Context, runID, path string) (io.ReadSeekCloser, error)
+
+	SaveToken(ctx context.Context, token *models.APIToken) error
+	// FindToken returns the token with the hash, revoked tokens included
+	FindToken(ctx context.Context, hash string) (*models.APIToken, error)
+	ListTokens(ctx context.Context) ([]models.APIToken, error)
+}
+
+// New opens the store in the data directory from config
+func New(cfg *config.Config) (Repository, error) {
+	return NewFileStore(cfg.DataDir)
+}
+
+func newID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestFileStoreRuns(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	record := func(results map[string]bool) *models.RunDetails {
+		run, err := StartRun(ctx, repo, "shop", "https://example.com/", models.RunKindGenerate)
+		if err != nil {
+			t.Fatalf("Failed to start run: %v", err)
+		}
+
+		criteria := []models.TestCriterion{}
+		for _, title := range []string{"Login", "Search", "Checkout"} {
+			if _, ok := results[title]; ok {
+				criteria = append(criteria, models.TestCriterion{Title: title})
+			}
+		}
+		stored, err := RecordCriteria(ctx, repo, run, criteria)
+		if err != nil {
+			t.Fatalf("Failed to record criteria: %v", err)
+		}
+
+		for _, criterion := range stored {
+			// Every test fails first and is fixed by the evaluator feedback when it passes
+			err := RecordGenEval(ctx, repo, run, criterion.ID, "test.spec.ts", &models.GenEvalResult{
+				Iterations: []models.GenEvalIteration{
+					{Content: "v1", TestsPassed: false},
+					{Content: "v2", TestsPassed: results[criterion.Criterion.Title], Accepted: true},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to record gen-eval: %v", err)
+			}
+		}
+
+		if err := FinishRun(ctx, repo, run, nil); err != nil {
+			t.Fatalf("Failed to finish run: %v", err)
+		}
+
+		details, err := Details(ctx, repo, run.ID)
+		if err != nil {
+			t.Fatalf("Failed to load run: %v", err)
+		}
+		return details
+	}
+
+	base := record(map[string]bool{"Login": true, "Search": false, "Checkout": true})
+	head := record(map[string]bool{"Login": true, "Search": true})
+
+	if len(head.Tests) != 4 || len(head.Results) != 4 || head.Tests[1].Version != 2 {
+		t.Errorf("Expected 2 versions with results per criterion, got %+v", head.Tests)
+	}
+	if head.Run.Status != models.RunStatusFinished || head.Run.FinishedAt.IsZero() {
+		t.Errorf("Expected a finished run, got %+v", head.Run)
+	}
+
+	if _, err := StartRun(ctx, repo, "blog", "https://blog.example.com/", models.RunKindAnalyze); err != nil {
+		t.Fatalf("Failed to start run: %v", err)
+	}
+
+	runs, _ := repo.ListRuns(ctx, "shop")
+	if len(runs) != 2 || runs[0].ID != head.Run.ID || runs[0].URL != "https://example.com/" {
+		t.Errorf("Expected the 2 runs of the project newest first, got %+v", runs)
+	}
+	if all, _ := repo.ListRuns(ctx, ""); len(all) != 3 {
+		t.Errorf("Expected the runs of all projects, got %d", len(all))
+	}
+
+	comparison := Compare(base, head)
+	if !reflect.DeepEqual(comparison.Fixed, []string{"Search"}) ||
+		!reflect.DeepEqual(comparison.Unchanged, []string{"Login"}) ||
+		!reflect.DeepEqual(comparison.RemovedCriteria, []string{"Checkout"}) ||
+		len(comparison.Broken) != 0 || len(comparison.AddedCriteria) != 0 {
+		t.Errorf("Unexpected comparison: %+v", comparison)
+	}
+
+	if _, err := repo.GetRun(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileStoreArtifacts(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	run, err := StartRun(ctx, repo, "shop", "https://example.com/", models.RunKindGenerate)
+	if err != nil {
+		t.Fatalf("Failed to start run: %v", err)
+	}
+
+	collected := t.TempDir()
+	os.MkdirAll(filepath.Join(collected, "test-cart-chromium"), 0755)
+	os.WriteFile(filepath.Join(collected, "test-cart-chromium", "test-failed-1.png"), []byte("png"), 0644)
+
+	err = RecordGenEval(ctx, repo, run, "", "test.spec.ts", &models.GenEvalResult{
+		Iterations: []models.GenEvalIteration{{
+			Content:     "v1",
+			ArtifactDir: collected,
+			Artifacts:   []models.Artifact{{Path: "test-cart-chromium/test-failed-1.png", Kind: models.ArtifactScreenshot, Size: 3}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to record gen-eval: %v", err)
+	}
+
+	details, _ := Details(ctx, repo, run.ID)
+	artifacts := details.Results[0].Artifacts
+	if len(artifacts) != 1 || artifacts[0].Path != details.Tests[0].ID+"/test-cart-chromium/test-failed-1.png" {
+		t.Fatalf("Expected the artifact under its test version, got %+v", artifacts)
+	}
+
+	file, err := repo.OpenArtifact(ctx, run.ID, artifacts[0].Path)
+	if err != nil {
+		t.Fatalf("Failed to open artifact: %v", err)
+	}
+	content, _ := io.ReadAll(file)
+	file.Close()
+	if string(content) != "png" {
+		t.Errorf("Expected the stored content, got %q", content)
+	}
+
+	for _, path := range []string{"../runs.json", "/etc/passwd", details.Tests[0].ID} {
+		if _, err := repo.OpenArtifact(ctx, run.ID, path); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %s, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "testbuddy"
+
+// Registry holds the metrics served by Handler
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"method", "route"})
+
+	llmDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_call_duration_seconds",
+		Help:      "LLM call latency by model and phase.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"model", "phase", "outcome"})
+
+	llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "LLM tokens by model, phase and direction (input or output).",
+	}, []string{"model", "phase", "direction"})
+
+	toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tool_calls_total",
+		Help:      "Analyzer tool calls by tool.",
+	}, []string{"tool"})
+
+	toolErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tool_call_errors_total",
+		Help:      "Failed analyzer tool calls by tool.",
+	}, []string{"tool"})
+
+	genEvalIterations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "gen_eval_iterations",
+		Help:      "Generator and evaluator iterations per criterion by outcome.",
+		Buckets:   []float64{1, 2, 3, 4, 5, 8, 10},
+	}, []string{"outcome"})
+
+	playwrightDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "playwright_run_duration_seconds",
+		Help:      "Playwright subprocess duration by step and outcome.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"step", "outcome"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		llmDuration,
+		llmTokens,
+		toolCalls,
+		toolErrors,
+		genEvalIterations,
+		playwrightDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records the count and latency of every request
+// Requests are labeled with the chi route pattern, so path parameters don't create new series
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+type phaseKey struct{}
+
+// WithPhase returns a context whose LLM calls are labeled with phase
+func WithPhase(ctx context.Context, phase string) context.Context {
+	return context.WithValue(ctx, phaseKey{}, phase)
+}
+
+// Phase returns the phase of the context, or "unknown"
+func Phase(ctx context.Context) string {
+	if phase, ok := ctx.Value(phaseKey{}).(string); ok && phase != "" {
+		return phase
+	}
+	return "unknown"
+}
+
+// ObserveLLMCall records the latency and token usage of an LLM call
+func ObserveLLMCall(ctx context.Context, model string, duration time.Duration, inputTokens, outputTokens int64, err error) {
+	phase := Phase(ctx)
+	llmDuration.WithLabelValues(model, phase, outcome(err)).Observe(duration.Seconds())
+	llmTokens.WithLabelValues(model, phase, "input").Add(float64(inputTokens))
+	llmTokens.WithLabelValues(model, phase, "output").Add(float64(outputTokens))
+}
+
+// ObserveToolCall counts a tool call and its failure
+func ObserveToolCall(tool string, err error) {
+	toolCalls.WithLabelValues(tool).Inc()
+	if err != nil {
+		toolErrors.WithLabelValues(tool).Inc()
+	}
+}
+
+// ObserveGenEval records how many iterations a criterion took and whether its test was accepted
+func ObserveGenEval(iterations int, accepted bool) {
+	result := "rejected"
+	if accepted {
+		result = "accepted"
+	}
+	genEvalIterations.WithLabelValues(result).Observe(float64(iterations))
+}
+
+// ObservePlaywrightRun records the duration and outcome of a Playwright step
+func ObservePlaywrightRun(step string, duration time.Duration, err error) {
+	playwrightDuration.WithLabelValues(step, outcome(err)).Observe(duration.Seconds())
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	shutdown := SetupWithExporter(exporter)
+	defer shutdown(context.Background())
+
+	ctx, parent := StartSpan(context.Background(), "analyzer.Analyze")
+	_, child := StartSpan(ctx, "tool sitemap_tool")
+	EndSpan(child, errors.New("sitemap not found"))
+	EndSpan(parent, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "tool sitemap_tool" || spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Errorf("Expected the tool span to be a child of the analyze span, got %+v", spans[0])
+	}
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("Expected the failed tool span to have an error status, got %v", spans[0].Status)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/runs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Method("GET", "/metrics", Handler())
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/runs/42", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/runs/43", nil))
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/runs/{id}", "404")); got != 2 {
+		t.Errorf("Expected 2 requests on the route pattern, got %v", got)
+	}
+
+	ctx := WithPhase(context.Background(), "generator")
+	ObserveLLMCall(ctx, "claude-test", time.Second, 100, 20, nil)
+	if got := testutil.ToFloat64(llmTokens.WithLabelValues("claude-test", "generator", "input")); got != 100 {
+		t.Errorf("Expected 100 input tokens, got %v", got)
+	}
+
+	ObserveToolCall("get_sentry_tool", nil)
+	ObserveToolCall("get_sentry_tool", errors.New("unauthorized"))
+	if got := testutil.ToFloat64(toolErrors.WithLabelValues("get_sentry_tool")); got != 1 {
+		t.Errorf("Expected 1 tool error, got %v", got)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, name := range []string{"testbuddy_http_requests_total", "testbuddy_llm_call_duration_seconds", "testbuddy_tool_calls_total"} {
+		if !strings.Contains(body, name) {
+			t.Errorf("Expected %s in the metrics output", name)
+		}
+	}
+}
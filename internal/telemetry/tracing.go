@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName    = "testbuddy"
+	instrumentName = "github.com/webscopeio/ai-hackathon"
+)
+
+// Shutdown flushes and stops the span exporter
+type Shutdown func(context.Context) error
+
+// Setup exports spans over OTLP/HTTP to endpoint, e.g. "localhost:4318" or "https://collector:4318"
+// Tracing is off with an empty endpoint, spans are then dropped by the global no-op provider
+func Setup(ctx context.Context, endpoint string) (Shutdown, error) {
+	if strings.TrimSpace(endpoint) == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var options []otlptracehttp.Option
+	if strings.Contains(endpoint, "://") {
+		options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+	} else {
+		options = append(options, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(newResource()),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// SetupWithExporter records spans synchronously to exporter, tests use it with an in-memory exporter
+func SetupWithExporter(exporter sdktrace.SpanExporter) Shutdown {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(newResource()),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown
+}
+
+func newResource() *resource.Resource {
+	return resource.NewSchemaless(semconv.ServiceName(serviceName))
+}
+
+// StartSpan starts a span that is a child of the span in ctx
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan marks the span as failed when err is set and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,292 @@
+package umami
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/logger"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+	"github.com/webscopeio/ai-hackathon/internal/netguard"
+)
+
+const (
+	pageSize    = 100
+	concurrency = 8
+	// sessionSettleTime is how long after the end of its month a session may still receive activity,
+	// Umami salts the session IDs per month in the time zone of its server
+	sessionSettleTime = 24 * time.Hour
+)
+
+// Client talks to the Umami API of a single website
+type Client struct {
+	baseURL     string
+	apiKey      string
+	websiteID   string
+	cacheDir    string
+	concurrency int
+	httpClient  *http.Client
+}
+
+// New creates an Umami client using the settings from config
+// Session activity is cached in UmamiCacheDir, or in the user cache directory if unset
+func New(cfg *config.Config) *Client {
+	cacheDir := cfg.UmamiCacheDir
+	if cacheDir == "" {
+		if userCacheDir, err := os.UserCacheDir(); err == nil {
+			cacheDir = filepath.Join(userCacheDir, "testbuddy", "umami")
+		}
+	}
+
+	return &Client{
+		baseURL:     strings.TrimSuffix(cfg.UmamiURL, "/"),
+		apiKey:      cfg.UmamiAPIKey,
+		websiteID:   cfg.UmamiWebsiteId,
+		cacheDir:    cacheDir,
+		concurrency: concurrency,
+		httpClient:  netguard.ForAPI(cfg).Client(),
+	}
+}
+
+// Sessions retrieves all sessions in the date range, iterating over the result pages
+func (c *Client) Sessions(ctx context.Context, startDate, endDate time.Time) ([]models.UmamiSession, error) {
+	logger.For(ctx, "umami").Debug("getting sessions", "website_id", c.websiteID)
+
+	if c.websiteID == "" {
+		return nil, fmt.Errorf("Umami website ID not configured")
+	}
+
+	apiURL := fmt.Sprintf("%s/websites/%s/sessions", c.baseURL, c.websiteID)
+
+	sessions := []models.UmamiSession{}
+	for page := 1; ; page++ {
+		query := windowQuery(startDate, endDate)
+		query.Add("page", strconv.Itoa(page))
+		query.Add("pageSize", strconv.Itoa(pageSize))
+
+		var response models.UmamiSessionsResponse
+		if err := c.get(ctx, apiURL, query, &response); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, response.Data...)
+
+		if len(response.Data) == 0 || len(sessions) >= response.Count || len(response.Data) < pageSize {
+			break
+		}
+	}
+
+	logger.For(ctx, "umami").Debug("retrieved sessions", "count", len(sessions))
+	return sessions, nil
+}
+
+// Session retrieves a single session by ID
+func (c *Client) Session(ctx context.Context, sessionID string) (*models.UmamiSession, error) {
+	logger.For(ctx, "umami").Debug("getting session", "session_id", sessionID)
+
+	if c.websiteID == "" {
+		return nil, fmt.Errorf("Umami website ID not configured")
+	}
+
+	apiURL := fmt.Sprintf("%s/websites/%s/sessions/%s", c.baseURL, c.websiteID, url.PathEscape(sessionID))
+
+	var session models.UmamiSession
+	if err := c.get(ctx, apiURL, url.Values{}, &session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// SessionActivity retrieves the activity of a session in the date range
+// The activity of sessions whose month has ended is cached on disk by session ID. The cache holds all of a session's activity,
+// so it is only written and read for date ranges covering the whole month of the session, narrower ranges go to the API.
+func (c *Client) SessionActivity(ctx context.Context, session models.UmamiSession, startDate, endDate time.Time) ([]models.UmamiSessionActivity, error) {
+	cacheable := isFinished(session) && coversSession(session, startDate, endDate)
+	if cacheable {
+		if activities, ok := c.readCache(session.ID); ok {
+			return activities, nil
+		}
+	}
+
+	logger.For(ctx, "umami").Debug("getting session activity", "session_id", session.ID)
+
+	apiURL := fmt.Sprintf("%s/websites/%s/sessions/%s/activity", c.baseURL, c.websiteID, url.PathEscape(session.ID))
+
+	// The activity endpoint returns an array directly
+	var activities []models.UmamiSessionActivity
+	if err := c.get(ctx, apiURL, windowQuery(startDate, endDate), &activities); err != nil {
+		return nil, err
+	}
+
+	logger.For(ctx, "umami").Debug("retrieved session activity", "session_id", session.ID, "count", len(activities))
+
+	if cacheable {
+		c.writeCache(ctx, session.ID, activities)
+	}
+
+	return activities, nil
+}
+
+// SessionActivities retrieves the activity of many sessions concurrently
+// Sessions whose activity couldn't be retrieved are left out of the result
+func (c *Client) SessionActivities(ctx context.Context, sessions []models.UmamiSession, startDate, endDate time.Time) map[string][]models.UmamiSessionActivity {
+	result := make(map[string][]models.UmamiSessionActivity, len(sessions))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	semaphore := make(chan struct{}, c.concurrency)
+	for _, session := range sessions {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return result
+		case semaphore <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(session models.UmamiSession) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			activities, err := c.SessionActivity(ctx, session, startDate, endDate)
+			if err != nil {
+				logger.For(ctx, "umami").Warn("failed to get session activity", "session_id", session.ID, "error", err)
+				return
+			}
+
+			mutex.Lock()
+			result[session.ID] = activities
+			mutex.Unlock()
+		}(session)
+	}
+
+	wg.Wait()
+	return result
+}
+
+// get performs an authorized GET request and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, apiURL string, query url.Values, v any) error {
+	if c.apiKey == "" {
+		return fmt.Errorf("Umami API key not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	// Add API key header as per Umami Cloud API documentation
+	req.Header.Add("x-umami-api-key", c.apiKey)
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned non-OK status: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) cachePath(sessionID string) string {
+	return filepath.Join(c.cacheDir, c.websiteID, url.PathEscape(sessionID)+".json")
+}
+
+func (c *Client) readCache(sessionID string) ([]models.UmamiSessionActivity, bool) {
+	if c.cacheDir == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.cachePath(sessionID))
+	if err != nil {
+		return nil, false
+	}
+
+	var activities []models.UmamiSessionActivity
+	if err := json.Unmarshal(data, &activities); err != nil {
+		return nil, false
+	}
+
+	return activities, true
+}
+
+func (c *Client) writeCache(ctx context.Context, sessionID string, activities []models.UmamiSessionActivity) {
+	if c.cacheDir == "" {
+		return
+	}
+
+	path := c.cachePath(sessionID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		logger.For(ctx, "umami").Warn("failed to create cache directory", "error", err)
+		return
+	}
+
+	data, err := json.Marshal(activities)
+	if err != nil {
+		return
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		logger.For(ctx, "umami").Warn("failed to write cache", "session_id", sessionID, "error", err)
+	}
+}
+
+// sessionEnd returns when a session stops receiving activity
+// Session IDs are hashes of the visitor and a monthly salt, so a returning visitor keeps the session until the month ends
+func sessionEnd(createdAt time.Time) time.Time {
+	createdAt = createdAt.UTC()
+	monthEnd := time.Date(createdAt.Year(), createdAt.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	return monthEnd.Add(sessionSettleTime)
+}
+
+// isFinished reports whether the month of a session has ended, so it can't receive new activity
+func isFinished(session models.UmamiSession) bool {
+	createdAt, err := time.Parse(time.RFC3339, session.CreatedAt)
+	if err != nil {
+		return false
+	}
+	return time.Now().After(sessionEnd(createdAt))
+}
+
+// coversSession reports whether the date range includes the whole time a session can receive activity
+func coversSession(session models.UmamiSession, startDate, endDate time.Time) bool {
+	createdAt, err := time.Parse(time.RFC3339, session.CreatedAt)
+	if err != nil {
+		return false
+	}
+	return !createdAt.Before(startDate) && !sessionEnd(createdAt).After(endDate)
+}
+
+// windowQuery returns the startAt and endAt parameters in milliseconds
+func windowQuery(startDate, endDate time.Time) url.Values {
+	query := url.Values{}
+	query.Add("startAt", strconv.FormatInt(startDate.UnixMilli(), 10))
+	query.Add("endAt", strconv.FormatInt(endDate.UnixMilli(), 10))
+	return query
+}
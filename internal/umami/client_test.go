@@ -0,0 +1,141 @@
+package umami
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/webscopeio/ai-hackathon/internal/config"
+	"github.com/webscopeio/ai-hackathon/internal/models"
+)
+
+func TestSessionsAndActivities(t *testing.T) {
+	const totalSessions = 150
+
+	// The sessions are from a month that has ended, so their activity is cached
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -90)
+	createdAt := endDate.AddDate(0, 0, -60).Format(time.RFC3339)
+
+	var inFlight, maxInFlight, activityRequests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-umami-api-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get("startAt") != strconv.FormatInt(startDate.UnixMilli(), 10) || query.Get("endAt") != strconv.FormatInt(endDate.UnixMilli(), 10) {
+			t.Errorf("Expected the same date window for every request, got %s", r.URL.RawQuery)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.HasSuffix(r.URL.Path, "/activity") {
+			current := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				observed := atomic.LoadInt32(&maxInFlight)
+				if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+					break
+				}
+			}
+			atomic.AddInt32(&activityRequests, 1)
+			time.Sleep(5 * time.Millisecond)
+
+			json.NewEncoder(w).Encode([]models.UmamiSessionActivity{{URLPath: "/"}})
+			return
+		}
+
+		page, _ := strconv.Atoi(query.Get("page"))
+		size, _ := strconv.Atoi(query.Get("pageSize"))
+		response := models.UmamiSessionsResponse{Count: totalSessions, Page: page, PageSize: size}
+		for i := (page - 1) * size; i < page*size && i < totalSessions; i++ {
+			response.Data = append(response.Data, models.UmamiSession{ID: fmt.Sprintf("session-%d", i), CreatedAt: createdAt})
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := New(&config.Config{
+		UmamiURL:          server.URL,
+		UmamiAPIKey:       "key",
+		UmamiWebsiteId:    "website",
+		UmamiCacheDir:     t.TempDir(),
+		AllowLocalTargets: true,
+	})
+
+	sessions, err := client.Sessions(context.Background(), startDate, endDate)
+	if err != nil {
+		t.Fatalf("Failed to get sessions: %v", err)
+	}
+	if len(sessions) != totalSessions {
+		t.Fatalf("Expected %d sessions across pages, got %d", totalSessions, len(sessions))
+	}
+
+	activities := client.SessionActivities(context.Background(), sessions, startDate, endDate)
+	if len(activities) != totalSessions {
+		t.Fatalf("Expected activities for %d sessions, got %d", totalSessions, len(activities))
+	}
+	if maxInFlight > concurrency {
+		t.Errorf("Expected at most %d concurrent requests, got %d", concurrency, maxInFlight)
+	}
+
+	// The second run should be served from the cache
+	client.SessionActivities(context.Background(), sessions, startDate, endDate)
+	if activityRequests != totalSessions {
+		t.Errorf("Expected %d activity requests with caching, got %d", totalSessions, activityRequests)
+	}
+}
+
+func TestSessionActivityWindow(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.UmamiSessionActivity{{URLPath: "/"}})
+	}))
+	defer server.Close()
+
+	client := New(&config.Config{
+		UmamiURL:          server.URL,
+		UmamiAPIKey:       "key",
+		UmamiWebsiteId:    "website",
+		UmamiCacheDir:     t.TempDir(),
+		AllowLocalTargets: true,
+	})
+
+	endDate := time.Now()
+	createdAt := endDate.AddDate(0, 0, -60)
+	session := models.UmamiSession{ID: "a/b", CreatedAt: createdAt.Format(time.RFC3339)}
+
+	// A window starting after the session was created or ending before its month doesn't hold all of its activity
+	client.SessionActivity(context.Background(), session, createdAt.Add(time.Hour), endDate)
+	client.SessionActivity(context.Background(), session, createdAt.Add(-time.Hour), createdAt.Add(time.Hour))
+	client.SessionActivity(context.Background(), session, createdAt.Add(-time.Hour), endDate)
+	client.SessionActivity(context.Background(), session, createdAt.Add(-time.Hour), endDate)
+
+	if len(paths) != 3 {
+		t.Errorf("Expected the narrow windows not to be cached and the covering one to be, got %d requests", len(paths))
+	}
+	if len(paths) > 0 && !strings.Contains(paths[0], "/sessions/a%2Fb/activity") {
+		t.Errorf("Expected the session ID to be escaped, got %s", paths[0])
+	}
+
+	// The session ID of a visitor can receive activity until the end of the month
+	paths = nil
+	current := models.UmamiSession{ID: "current", CreatedAt: endDate.Add(-time.Hour).Format(time.RFC3339)}
+	client.SessionActivity(context.Background(), current, endDate.Add(-2*time.Hour), endDate)
+	client.SessionActivity(context.Background(), current, endDate.Add(-2*time.Hour), endDate)
+	if len(paths) != 2 {
+		t.Errorf("Expected the activity of a session of this month not to be cached, got %d requests", len(paths))
+	}
+}